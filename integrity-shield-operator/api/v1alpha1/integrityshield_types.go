@@ -45,6 +45,7 @@ const (
 	DefaultResourceSignatureCRDName           = "resourcesignatures.apis.integrityshield.io"
 	DefaultResourceSigningProfileCRDName      = "resourcesigningprofiles.apis.integrityshield.io"
 	DefaultHelmReleaseMetadataCRDName         = "helmreleasemetadatas.apis.integrityshield.io"
+	DefaultSignatureRevocationCRDName         = "signaturerevocations.apis.integrityshield.io"
 	DefaultSignerConfigCRName                 = "signer-config"
 	DefaultIShieldAdminClusterRoleName        = "ishield-admin-clusterrole"
 	DefaultIShieldAdminClusterRoleBindingName = "ishield-admin-clusterrolebinding"
@@ -253,6 +254,10 @@ func (self *IntegrityShield) GetHelmReleaseMetadataCRDName() string {
 	return DefaultHelmReleaseMetadataCRDName
 }
 
+func (self *IntegrityShield) GetSignatureRevocationCRDName() string {
+	return DefaultSignatureRevocationCRDName
+}
+
 func (self *IntegrityShield) GetShieldConfigCRName() string {
 	return self.Spec.ShieldConfigCrName
 }
@@ -389,6 +394,10 @@ func (self *IntegrityShield) GetIShieldResourceList(scheme *runtime.Scheme) ([]*
 			Kind: _crdType.Kind,
 			Name: self.GetHelmReleaseMetadataCRDName(),
 		},
+		{
+			Kind: _crdType.Kind,
+			Name: self.GetSignatureRevocationCRDName(),
+		},
 		{
 			Kind:      _ecType.Kind,
 			Name:      self.GetShieldConfigCRName(),
@@ -460,6 +469,12 @@ func (self *IntegrityShield) GetIShieldResourceList(scheme *runtime.Scheme) ([]*
 			Name:      self.GetIShieldServerDeploymentName(),
 			Namespace: self.Namespace,
 		},
+		{
+			// all SignatureRevocations in IShield namespace, which are created by IShield admins
+			Kind:      common.SignatureRevocationCustomResourceKind,
+			Name:      common.AnyResourceName,
+			Namespace: self.Namespace,
+		},
 	}
 	if len(self.Spec.ResourceSigningProfiles) > 0 {
 		for _, prof := range self.Spec.ResourceSigningProfiles {
@@ -163,6 +163,12 @@ func (r *IntegrityShieldReconciler) createOrUpdateResourceSigningProfileCRD(
 	return r.createOrUpdateCRD(instance, expected)
 }
 
+func (r *IntegrityShieldReconciler) createOrUpdateSignatureRevocationCRD(
+	instance *apiv1alpha1.IntegrityShield) (ctrl.Result, error) {
+	expected := res.BuildSignatureRevocationCRD(instance)
+	return r.createOrUpdateCRD(instance, expected)
+}
+
 func (r *IntegrityShieldReconciler) deleteShieldConfigCRD(
 	instance *apiv1alpha1.IntegrityShield) (ctrl.Result, error) {
 	expected := res.BuildShieldConfigCRD(instance)
@@ -192,6 +198,12 @@ func (r *IntegrityShieldReconciler) deleteResourceSigningProfileCRD(
 	return r.deleteCRD(instance, expected)
 }
 
+func (r *IntegrityShieldReconciler) deleteSignatureRevocationCRD(
+	instance *apiv1alpha1.IntegrityShield) (ctrl.Result, error) {
+	expected := res.BuildSignatureRevocationCRD(instance)
+	return r.deleteCRD(instance, expected)
+}
+
 /**********************************************
 
 				CR
@@ -43,7 +43,7 @@ type IntegrityShieldReconciler struct {
 
 // +kubebuilder:rbac:groups=core,resources=services;serviceaccounts;events;configmaps;secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=apis.integrityshield.io,resources=integrityshields;integrityshields/finalizers;shieldconfigs;signerconfigs;resourcesigningprofiles;resourcesignatures;helmreleasemetadatas,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apis.integrityshield.io,resources=integrityshields;integrityshields/finalizers;shieldconfigs;signerconfigs;resourcesigningprofiles;resourcesignatures;helmreleasemetadatas;signaturerevocations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=*
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings;roles;rolebindings,verbs=*
 // +kubebuilder:rbac:groups=policy,resources=podsecuritypolicies,verbs=get;list;watch;create;update;patch;delete
@@ -123,6 +123,11 @@ func (r *IntegrityShieldReconciler) Reconcile(req ctrl.Request) (ctrl.Result, er
 		return recResult, recErr
 	}
 
+	recResult, recErr = r.createOrUpdateSignatureRevocationCRD(instance)
+	if recErr != nil || recResult.Requeue {
+		return recResult, recErr
+	}
+
 	enabledPulgins := instance.Spec.ShieldConfig.GetEnabledPlugins()
 	if enabledPulgins["helm"] {
 		recResult, recErr = r.createOrUpdateHelmReleaseMetadataCRD(instance)
@@ -299,6 +304,11 @@ func (r *IntegrityShieldReconciler) deleteClusterScopedChildrenResources(instanc
 		}
 	}
 
+	_, err = r.deleteSignatureRevocationCRD(instance)
+	if err != nil {
+		return err
+	}
+
 	_, err = r.deleteResourceSigningProfileCRD(instance)
 	if err != nil {
 		return err
@@ -77,8 +77,8 @@ func BuildShieldConfigForIShield(cr *apiv1alpha1.IntegrityShield, scheme *runtim
 				// specify .gpg file name in case of pgp --> change to dir name?
 				keyPath := fmt.Sprintf("/%s/%s/%s", keyConf.Name, sigType, fileName)
 				keyPathList = append(keyPathList, keyPath)
-			} else if sigType == common.SignatureTypeX509 {
-				// specify only mounted dir name in case of x509
+			} else if sigType == common.SignatureTypeX509 || sigType == common.SignatureTypeSigstore {
+				// specify only mounted dir name in case of x509 and sigstore
 				keyPath := fmt.Sprintf("/%s/%s/", keyConf.Name, sigType)
 				keyPathList = append(keyPathList, keyPath)
 			}
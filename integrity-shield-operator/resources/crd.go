@@ -115,3 +115,15 @@ func BuildResourceSigningProfileCRD(cr *apiv1alpha1.IntegrityShield) *extv1.Cust
 	}
 	return buildCRD(cr.GetResourceSigningProfileCRDName(), cr.Namespace, crdNames)
 }
+
+// signature revocation crd
+func BuildSignatureRevocationCRD(cr *apiv1alpha1.IntegrityShield) *extv1.CustomResourceDefinition {
+	crdNames := extv1.CustomResourceDefinitionNames{
+		Kind:       "SignatureRevocation",
+		Plural:     "signaturerevocations",
+		ListKind:   "SignatureRevocationList",
+		Singular:   "signaturerevocation",
+		ShortNames: []string{"sigrevoke", "sigrevokes"},
+	}
+	return buildCRD(cr.GetSignatureRevocationCRDName(), cr.Namespace, crdNames)
+}
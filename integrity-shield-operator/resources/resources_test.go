@@ -157,6 +157,12 @@ func TestResourceSigningProfileCRD(t *testing.T) {
 	yamlPath := "./testdata/resourceSigningProfileCRD.yaml"
 	testObjAndYaml(t, obj, yamlPath)
 }
+func TestSignatureRevocationCRD(t *testing.T) {
+	instance := loadTestInstance(t)
+	obj := BuildSignatureRevocationCRD(instance)
+	yamlPath := "./testdata/signatureRevocationCRD.yaml"
+	testObjAndYaml(t, obj, yamlPath)
+}
 func TestShieldConfigCR(t *testing.T) {
 	instance := loadTestInstance(t)
 	obj := BuildShieldConfigForIShield(instance, nil, commonProfilePathList)
@@ -62,7 +62,7 @@ func BuildClusterRoleForIShield(cr *apiv1alpha1.IntegrityShield) *rbacv1.Cluster
 					"extensions", "", "apis.integrityshield.io",
 				},
 				Resources: []string{
-					"secrets", "namespaces", "resourcesignatures", "shieldconfigs", "signerconfigs", "signerconfigs", "resourcesigningprofiles", "resourcesignatures",
+					"secrets", "configmaps", "namespaces", "resourcesignatures", "shieldconfigs", "signerconfigs", "signerconfigs", "resourcesigningprofiles", "resourcesignatures", "signaturerevocations",
 				},
 				Verbs: []string{
 					"get", "list", "watch", "patch", "update",
@@ -234,6 +234,7 @@ func BuildRoleForIShieldAdmin(cr *apiv1alpha1.IntegrityShield) *rbacv1.Role {
 					"integrityshields",
 					"shieldconfigs",
 					"signerconfigs",
+					"signaturerevocations",
 				},
 				Verbs: []string{
 					"update", "create", "delete", "get", "list", "watch", "patch",
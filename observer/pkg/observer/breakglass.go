@@ -0,0 +1,192 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	sigconfapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signerconfig/v1alpha1"
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	kubeutil "github.com/IBM/integrity-enforcer/shield/pkg/util/kubeutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	breakGlassEnabledReason  = "BreakGlassEnabled"
+	breakGlassDisabledReason = "BreakGlassDisabled"
+)
+
+// BreakGlassStatus is a break glass condition defined in a SignerConfig
+type BreakGlassStatus struct {
+	SignerConfigNamespace string `json:"signerConfigNamespace"`
+	SignerConfigName      string `json:"signerConfigName"`
+	common.BreakGlassCondition
+}
+
+func (self BreakGlassStatus) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", self.SignerConfigNamespace, self.SignerConfigName, self.Scope, strings.Join(self.Namespaces, ","))
+}
+
+// getBreakGlassStatus returns break glass conditions which are active and expired at `now`
+func getBreakGlassStatus(sigConfList *sigconfapi.SignerConfigList, now time.Time) (map[string]BreakGlassStatus, map[string]BreakGlassStatus) {
+	active := map[string]BreakGlassStatus{}
+	expired := map[string]BreakGlassStatus{}
+	if sigConfList == nil {
+		return active, expired
+	}
+	for _, sigConf := range sigConfList.Items {
+		if sigConf.Spec.Config == nil {
+			continue
+		}
+		for _, cond := range sigConf.Spec.Config.BreakGlass {
+			status := BreakGlassStatus{
+				SignerConfigNamespace: sigConf.GetNamespace(),
+				SignerConfigName:      sigConf.GetName(),
+				BreakGlassCondition:   cond,
+			}
+			if cond.IsExpired(now) {
+				expired[status.key()] = status
+			} else {
+				active[status.key()] = status
+			}
+		}
+	}
+	return active, expired
+}
+
+// diffBreakGlass returns break glass conditions which are turned on and off since the last report
+func diffBreakGlass(last, current map[string]BreakGlassStatus) ([]BreakGlassStatus, []BreakGlassStatus) {
+	turnedOn := []BreakGlassStatus{}
+	turnedOff := []BreakGlassStatus{}
+	for key, status := range current {
+		if _, ok := last[key]; !ok {
+			turnedOn = append(turnedOn, status)
+		}
+	}
+	for key, status := range last {
+		if _, ok := current[key]; !ok {
+			turnedOff = append(turnedOff, status)
+		}
+	}
+	sortBreakGlass(turnedOn)
+	sortBreakGlass(turnedOff)
+	return turnedOn, turnedOff
+}
+
+func sortBreakGlass(items []BreakGlassStatus) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].key() < items[j].key()
+	})
+}
+
+// reportBreakGlass logs and creates Events for break glass conditions turned on/off since the last report,
+// and returns summary of currently active conditions.
+func (self *IntegrityShieldObserver) reportBreakGlass(data *RuntimeData) map[string]string {
+	active, expired := getBreakGlassStatus(data.SigConfList, time.Now())
+	turnedOn, turnedOff := diffBreakGlass(self.breakGlass, active)
+	// at the first report, conditions which are already active are reported as turned on
+	for _, status := range turnedOn {
+		msg := fmt.Sprintf("Break glass is turned on by SignerConfig `%s/%s` (%s)", status.SignerConfigNamespace, status.SignerConfigName, status.String())
+		self.logger.Warn(msg)
+		err := self.createBreakGlassEvent(status, breakGlassEnabledReason, msg)
+		if err != nil {
+			self.logger.Errorf("Failed to create break glass event; %s", err.Error())
+		}
+	}
+	for _, status := range turnedOff {
+		cause := "removed from"
+		if _, ok := expired[status.key()]; ok {
+			cause = "expired in"
+		}
+		msg := fmt.Sprintf("Break glass is turned off because it is %s SignerConfig `%s/%s` (%s)", cause, status.SignerConfigNamespace, status.SignerConfigName, status.String())
+		self.logger.Info(msg)
+		err := self.createBreakGlassEvent(status, breakGlassDisabledReason, msg)
+		if err != nil {
+			self.logger.Errorf("Failed to create break glass event; %s", err.Error())
+		}
+	}
+	self.breakGlass = active
+
+	activeList := []BreakGlassStatus{}
+	for _, status := range active {
+		activeList = append(activeList, status)
+	}
+	sortBreakGlass(activeList)
+	activeBytes, _ := json.Marshal(activeList)
+	return map[string]string{
+		"breakGlass.enabled": fmt.Sprintf("%t", len(activeList) > 0),
+		"breakGlass.active":  string(activeBytes),
+	}
+}
+
+func (self *IntegrityShieldObserver) createBreakGlassEvent(status BreakGlassStatus, reason, msg string) error {
+	config, err := kubeutil.GetKubeConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	sourceName := "IntegrityShieldObserver"
+	now := time.Now()
+	// several conditions in a SignerConfig can be turned on/off in a single report, so the name is generated by the API server
+	evtNamePrefix := fmt.Sprintf("ishield-%s-%s-", strings.ToLower(reason), status.SignerConfigName)
+	evtType := v1.EventTypeNormal
+	if reason == breakGlassEnabledReason {
+		evtType = v1.EventTypeWarning
+	}
+	evtNamespace := status.SignerConfigNamespace
+	if evtNamespace == "" {
+		evtNamespace = self.IShiledNamespace
+	}
+	evt := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: evtNamePrefix,
+			Annotations: map[string]string{
+				common.EventTypeAnnotationKey: common.EventTypeValueBreakGlass,
+			},
+		},
+		InvolvedObject: v1.ObjectReference{
+			Namespace:  evtNamespace,
+			APIVersion: common.SignerConfigCustomResourceAPIVersion,
+			Kind:       common.SignerConfigCustomResourceKind,
+			Name:       status.SignerConfigName,
+		},
+		Type:                evtType,
+		Reason:              reason,
+		Message:             msg,
+		Source:              v1.EventSource{Component: sourceName},
+		ReportingController: sourceName,
+		ReportingInstance:   sourceName,
+		Action:              reason,
+		Count:               1,
+		FirstTimestamp:      metav1.NewTime(now),
+		LastTimestamp:       metav1.NewTime(now),
+		EventTime:           metav1.NewMicroTime(now),
+	}
+	_, err = client.CoreV1().Events(evtNamespace).Create(context.Background(), evt, metav1.CreateOptions{})
+	return err
+}
@@ -0,0 +1,65 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"testing"
+	"time"
+
+	sigconfapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signerconfig/v1alpha1"
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiffBreakGlass(t *testing.T) {
+	sigConfList := &sigconfapi.SignerConfigList{
+		Items: []sigconfapi.SignerConfig{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "integrity-shield-operator-system", Name: "signer-config"},
+				Spec: sigconfapi.SignerConfigSpec{
+					Config: &common.SignerConfig{
+						BreakGlass: []common.BreakGlassCondition{
+							{Namespaces: []string{"ns1"}, ExpiresAt: "2021-01-01T00:00:00Z", Reason: "incident-1"},
+							{Namespaces: []string{"ns2"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	before := time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	active, _ := getBreakGlassStatus(sigConfList, before)
+	turnedOn, turnedOff := diffBreakGlass(nil, active)
+	if len(turnedOn) != 2 || len(turnedOff) != 0 {
+		t.Errorf("\nexpected: turnedOn 2, turnedOff 0\nactual: turnedOn %d, turnedOff %d", len(turnedOn), len(turnedOff))
+	}
+
+	activeAfter, expiredAfter := getBreakGlassStatus(sigConfList, after)
+	turnedOn, turnedOff = diffBreakGlass(active, activeAfter)
+	if len(turnedOn) != 0 || len(turnedOff) != 1 {
+		t.Fatalf("\nexpected: turnedOn 0, turnedOff 1\nactual: turnedOn %d, turnedOff %d", len(turnedOn), len(turnedOff))
+	}
+	if turnedOff[0].Reason != "incident-1" {
+		t.Errorf("\nexpected: incident-1\nactual: %s", turnedOff[0].Reason)
+	}
+	if _, ok := expiredAfter[turnedOff[0].key()]; !ok {
+		t.Errorf("turned off break glass condition should be expired")
+	}
+}
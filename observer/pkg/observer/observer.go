@@ -34,6 +34,7 @@ import (
 )
 
 const defaultIntervalSecondsStr = "30"
+const defaultExpiryWarningHoursStr = "168"
 const defaultSummaryConfigMapName = "integrity-shield-status-report"
 const timeFormat = "2006-01-02 15:04:05"
 
@@ -42,10 +43,13 @@ type IntegrityShieldObserver struct {
 	ShieldConfigName string
 	EventsFilePath   string
 	IntervalSeconds  uint64
+	// signatures which expire within these hours are reported
+	ExpiryWarningHours uint64
 
 	loader     *Loader
 	logger     *log.Logger
 	eventQueue []string
+	breakGlass map[string]BreakGlassStatus
 }
 
 func NewIntegrityShieldObserver(logger *log.Logger) *IntegrityShieldObserver {
@@ -62,15 +66,26 @@ func NewIntegrityShieldObserver(logger *log.Logger) *IntegrityShieldObserver {
 		intervalSeconds, _ = strconv.ParseUint(defaultIntervalSecondsStr, 10, 64)
 	}
 
+	expiryWarningHoursStr := os.Getenv("SIGNATURE_EXPIRY_WARNING_HOURS")
+	if expiryWarningHoursStr == "" {
+		expiryWarningHoursStr = defaultExpiryWarningHoursStr
+	}
+	expiryWarningHours, err := strconv.ParseUint(expiryWarningHoursStr, 10, 64)
+	if err != nil {
+		logger.Warningf("Failed to parse signature expiry warning hours `%s`; use default value: %s", expiryWarningHoursStr, defaultExpiryWarningHoursStr)
+		expiryWarningHours, _ = strconv.ParseUint(defaultExpiryWarningHoursStr, 10, 64)
+	}
+
 	loader := NewLoader(iShieldNS, shieldConfigName)
 
 	return &IntegrityShieldObserver{
-		IShiledNamespace: iShieldNS,
-		ShieldConfigName: shieldConfigName,
-		EventsFilePath:   eventsFilePath,
-		IntervalSeconds:  intervalSeconds,
-		loader:           loader,
-		logger:           logger,
+		IShiledNamespace:   iShieldNS,
+		ShieldConfigName:   shieldConfigName,
+		EventsFilePath:     eventsFilePath,
+		IntervalSeconds:    intervalSeconds,
+		ExpiryWarningHours: expiryWarningHours,
+		loader:             loader,
+		logger:             logger,
 	}
 }
 
@@ -182,6 +197,13 @@ func (self *IntegrityShieldObserver) summarize(data *RuntimeData, events []map[s
 			denyCount++
 		}
 	}
+	for k, v := range self.reportBreakGlass(data) {
+		summary[k] = v
+	}
+	for k, v := range self.reportExpiringSignatures(data) {
+		summary[k] = v
+	}
+
 	summary["count.events"] = strconv.Itoa(count)
 	summary["count.deniedEvents"] = strconv.Itoa(denyCount)
 	summary["resource.numOfRSPs"] = strconv.Itoa(rspNum)
@@ -17,9 +17,13 @@
 package observer
 
 import (
+	"encoding/base64"
 	"testing"
+	"time"
 
+	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
 	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var testLogger *log.Logger
@@ -34,3 +38,51 @@ func TestNewIntegrityShieldObserver(t *testing.T) {
 		t.Error("Failed to test NewIntegrityShieldObserver()")
 	}
 }
+
+func TestGetExpiringSignatures(t *testing.T) {
+	message := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm1
+  namespace: secure-ns
+  annotations:
+    integrityshield.io/notAfter: "2021-01-01T00:00:00Z"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm2
+  namespace: secure-ns
+  annotations:
+    integrityshield.io/notAfter: "2021-06-01T00:00:00Z"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm3
+  namespace: secure-ns
+`
+	resSigList := &rsigapi.ResourceSignatureList{
+		Items: []*rsigapi.ResourceSignature{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "secure-ns", Name: "rsig-cm"},
+				Spec: rsigapi.ResourceSignatureSpec{
+					Data: []*rsigapi.SignItem{
+						{Message: base64.StdEncoding.EncodeToString([]byte(message)), Type: rsigapi.SignatureTypeResource},
+					},
+				},
+			},
+		},
+	}
+
+	now := time.Date(2020, 12, 30, 0, 0, 0, 0, time.UTC)
+	expiring := getExpiringSignatures(resSigList, now, 7*24*time.Hour)
+	if len(expiring) != 1 || expiring[0].Resource.Name != "cm1" || expiring[0].Expired {
+		t.Fatalf("\nexpected: cm1 expiring\nactual: %v", expiring)
+	}
+
+	expired := getExpiringSignatures(resSigList, now.Add(30*24*time.Hour), 7*24*time.Hour)
+	if len(expired) != 1 || !expired[0].Expired {
+		t.Errorf("\nexpected: cm1 expired\nactual: %v", expired)
+	}
+}
@@ -0,0 +1,92 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	ishieldyaml "github.com/IBM/integrity-enforcer/shield/pkg/util/yaml"
+)
+
+// SignatureExpiry is a signature in ResourceSignature which has `notAfter`
+type SignatureExpiry struct {
+	ResourceSignatureNamespace string             `json:"resourceSignatureNamespace"`
+	ResourceSignatureName      string             `json:"resourceSignatureName"`
+	Resource                   common.ResourceRef `json:"resource"`
+	NotAfter                   string             `json:"notAfter"`
+	Expired                    bool               `json:"expired"`
+}
+
+// getExpiringSignatures returns signatures which expire within `warningPeriod` from `now` or have already expired
+func getExpiringSignatures(resSigList *rsigapi.ResourceSignatureList, now time.Time, warningPeriod time.Duration) []SignatureExpiry {
+	expiring := []SignatureExpiry{}
+	if resSigList == nil {
+		return expiring
+	}
+	for _, rsig := range resSigList.Items {
+		for _, si := range rsig.Spec.Data {
+			if si.Type == rsigapi.SignatureTypeDelete {
+				continue
+			}
+			for _, ri := range ishieldyaml.ParseMessage([]byte(si.Message)) {
+				validity := common.GetSignatureValidity(ri.Raw())
+				expiresIn, ok := validity.ExpiresIn(now)
+				if !ok || expiresIn > warningPeriod {
+					continue
+				}
+				expiring = append(expiring, SignatureExpiry{
+					ResourceSignatureNamespace: rsig.GetNamespace(),
+					ResourceSignatureName:      rsig.GetName(),
+					Resource:                   ri.ResourceRef,
+					NotAfter:                   validity.NotAfter,
+					Expired:                    expiresIn < 0,
+				})
+			}
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].NotAfter < expiring[j].NotAfter
+	})
+	return expiring
+}
+
+// reportExpiringSignatures logs signatures which are about to expire, and returns summary of them.
+func (self *IntegrityShieldObserver) reportExpiringSignatures(data *RuntimeData) map[string]string {
+	warningPeriod := time.Duration(self.ExpiryWarningHours) * time.Hour
+	expiring := getExpiringSignatures(data.ResSigList, time.Now(), warningPeriod)
+	expiredCount := 0
+	for _, s := range expiring {
+		if s.Expired {
+			expiredCount++
+			continue
+		}
+		self.logger.Warnf("Signature for %s `%s/%s` in ResourceSignature `%s/%s` expires at %s", s.Resource.Kind, s.Resource.Namespace, s.Resource.Name, s.ResourceSignatureNamespace, s.ResourceSignatureName, s.NotAfter)
+	}
+	expiringBytes, _ := json.Marshal(expiring)
+	return map[string]string{
+		"count.expiringSignatures": strconv.Itoa(len(expiring) - expiredCount),
+		"count.expiredSignatures":  strconv.Itoa(expiredCount),
+		"signature.expiring":       string(expiringBytes),
+		"signature.warningPeriod":  fmt.Sprintf("%dh", self.ExpiryWarningHours),
+	}
+}
@@ -18,20 +18,32 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
+	ecv1alpha1 "github.com/IBM/integrity-enforcer/shield/pkg/apis/shieldconfig/v1alpha1"
 	ecfgclient "github.com/IBM/integrity-enforcer/shield/pkg/client/shieldconfig/clientset/versioned/typed/shieldconfig/v1alpha1"
+	shield "github.com/IBM/integrity-enforcer/shield/pkg/shield"
 	cfg "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
 	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
+const defaultConfigResyncSeconds = 20
+
 type Config struct {
-	ShieldConfig *cfg.ShieldConfig
-	lastUpdated  time.Time
+	shieldConfig    *cfg.ShieldConfig
+	resourceVersion string
+	rejectedVersion string
+	lock            sync.RWMutex
 }
 
 func NewConfig() *Config {
@@ -39,56 +51,187 @@ func NewConfig() *Config {
 	return config
 }
 
-func (conf *Config) InitShieldConfig() bool {
+// GetShieldConfig returns the last valid ShieldConfig.
+// The returned config is shared among requests, so it must not be modified.
+func (conf *Config) GetShieldConfig() *cfg.ShieldConfig {
+	conf.lock.RLock()
+	defer conf.lock.RUnlock()
+	return conf.shieldConfig
+}
 
-	renew := false
-	t := time.Now()
-	if conf.ShieldConfig != nil {
+// InitShieldConfig loads the ShieldConfig at startup.
+// Later changes are applied by the informer started in WatchShieldConfig().
+func (conf *Config) InitShieldConfig() {
+	shieldNs := os.Getenv("SHIELD_NS")
+	shieldConfigName := os.Getenv("SHIELD_CONFIG_NAME")
+	ecres := LoadEnforceConfig(shieldNs, shieldConfigName)
+	if ecres == nil {
+		log.Fatal("Failed to initialize ShieldConfig. Exiting...")
+	}
+	if err := validateShieldConfig(ecres); err != nil {
+		log.Fatal("Failed to initialize ShieldConfig; ", err.Error())
+	}
+	conf.update(ecres)
+}
 
-		interval := 20
-		if s := os.Getenv("SHIELD_CM_RELOAD_SEC"); s != "" {
-			if v, err := strconv.Atoi(s); err != nil {
-				interval = v
-			}
-		}
+// WatchShieldConfig starts an informer on the ShieldConfig CR.
+// A valid config is swapped in atomically, and an invalid one is rejected with an Event
+// so that the last good config stays active.
+func (conf *Config) WatchShieldConfig(stopCh <-chan struct{}) error {
+	shieldNs := os.Getenv("SHIELD_NS")
+	shieldConfigName := os.Getenv("SHIELD_CONFIG_NAME")
 
-		duration := t.Sub(conf.lastUpdated)
-		if int(duration.Seconds()) > interval {
-			renew = true
-		}
-	} else {
-		renew = true
-	}
-
-	if renew {
-		shieldNs := os.Getenv("SHIELD_NS")
-		shieldConfigName := os.Getenv("SHIELD_CONFIG_NAME")
-		shieldConfig := LoadEnforceConfig(shieldNs, shieldConfigName)
-		if shieldConfig == nil {
-			if conf.ShieldConfig == nil {
-				log.Fatal("Failed to initialize ShieldConfig. Exiting...")
-			} else {
-				shieldConfig = conf.ShieldConfig
-				log.Warn("The loaded ShieldConfig is nil, re-use the existing one.")
-			}
-		}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	clientset, err := ecfgclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	lw := cache.NewListWatchFromClient(clientset.RESTClient(), "shieldconfigs", shieldNs, fields.OneTermEqualSelector("metadata.name", shieldConfigName))
+	_, controller := cache.NewInformer(lw, &ecv1alpha1.ShieldConfig{}, getResyncPeriod(), cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			conf.onChange(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			conf.onChange(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			log.Warn("ShieldConfig has been deleted, the current config stays active.")
+		},
+	})
+	go controller.Run(stopCh)
+	return nil
+}
+
+// WatchVerificationKeys starts informers on Secrets and ConfigMaps with verification keys in the shield namespace,
+// so that added or rotated keys are used without restarting the server.
+func WatchVerificationKeys(stopCh <-chan struct{}) error {
+	shieldNs := os.Getenv("SHIELD_NS")
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	return shield.StartClusterKeyProvider(clientset, shieldNs, getResyncPeriod(), stopCh)
+}
 
-		chartRepo := os.Getenv("CHART_BASE_URL")
-		if chartRepo == "" {
-			chartRepo = ""
+func (conf *Config) onChange(obj interface{}) {
+	ecres, ok := obj.(*ecv1alpha1.ShieldConfig)
+	if !ok {
+		return
+	}
+	rv := ecres.GetResourceVersion()
+
+	conf.lock.RLock()
+	alreadyProcessed := rv == conf.resourceVersion || rv == conf.rejectedVersion
+	conf.lock.RUnlock()
+	if alreadyProcessed {
+		return
+	}
+
+	if err := validateShieldConfig(ecres); err != nil {
+		log.Errorf("ShieldConfig (resourceVersion: %s) is rejected, the current config stays active; %s", rv, err.Error())
+		conf.lock.Lock()
+		conf.rejectedVersion = rv
+		conf.lock.Unlock()
+		if evtErr := createConfigRejectedEvent(ecres, err); evtErr != nil {
+			log.Error("Failed to create an event for the rejected ShieldConfig; ", evtErr.Error())
 		}
+		return
+	}
+
+	conf.update(ecres)
+	log.Infof("ShieldConfig (resourceVersion: %s) is loaded.", rv)
+}
+
+func (conf *Config) update(ecres *ecv1alpha1.ShieldConfig) {
+	// the config is copied so that the object in the informer cache is not modified
+	shieldConfig := ecres.Spec.ShieldConfig.DeepCopy()
+	shieldConfig.ChartRepo = os.Getenv("CHART_BASE_URL")
+	// fill in the default log config before the config is shared among requests
+	shieldConfig.LogConfig()
+
+	conf.lock.Lock()
+	defer conf.lock.Unlock()
+	conf.shieldConfig = shieldConfig
+	conf.resourceVersion = ecres.GetResourceVersion()
+}
 
-		if shieldConfig != nil {
-			shieldConfig.ChartRepo = chartRepo
-			conf.ShieldConfig = shieldConfig
-			conf.lastUpdated = t
+func getResyncPeriod() time.Duration {
+	interval := defaultConfigResyncSeconds
+	if s := os.Getenv("SHIELD_CM_RELOAD_SEC"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			interval = v
+		} else {
+			log.Warnf("Invalid SHIELD_CM_RELOAD_SEC \"%s\", use %d instead.", s, defaultConfigResyncSeconds)
 		}
 	}
+	return time.Duration(interval) * time.Second
+}
 
-	return renew
+func validateShieldConfig(ecres *ecv1alpha1.ShieldConfig) error {
+	if ecres.Spec.ShieldConfig == nil {
+		return fmt.Errorf("ShieldConfig is empty")
+	}
+	if err := ecres.Spec.ShieldConfig.Validate(); err != nil {
+		return fmt.Errorf("ShieldConfig is invalid; %s", err.Error())
+	}
+	if err := shield.ValidateCheckConfig(ecres.Spec.ShieldConfig); err != nil {
+		return fmt.Errorf("ShieldConfig is invalid; %s", err.Error())
+	}
+	return nil
 }
 
-func LoadEnforceConfig(namespace, cmname string) *cfg.ShieldConfig {
+func createConfigRejectedEvent(ecres *ecv1alpha1.ShieldConfig, cause error) error {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	sourceName := "IntegrityShield"
+	evtName := fmt.Sprintf("ishield-config-rejected-%s-%s", ecres.GetName(), ecres.GetResourceVersion())
+	now := time.Now()
+	evt := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      evtName,
+			Namespace: ecres.GetNamespace(),
+		},
+		InvolvedObject: v1.ObjectReference{
+			Namespace:       ecres.GetNamespace(),
+			APIVersion:      ecv1alpha1.SchemeGroupVersion.String(),
+			Kind:            "ShieldConfig",
+			Name:            ecres.GetName(),
+			UID:             ecres.GetUID(),
+			ResourceVersion: ecres.GetResourceVersion(),
+		},
+		Type:                v1.EventTypeWarning,
+		Reason:              "ConfigRejected",
+		Message:             fmt.Sprintf("[IntegrityShieldEvent] ShieldConfig update is rejected and the last valid config stays active; %s", cause.Error()),
+		Source:              v1.EventSource{Component: sourceName},
+		ReportingController: sourceName,
+		ReportingInstance:   evtName,
+		Action:              "reload",
+		Count:               1,
+		FirstTimestamp:      metav1.NewTime(now),
+		LastTimestamp:       metav1.NewTime(now),
+		EventTime:           metav1.NewMicroTime(now),
+	}
+	_, err = client.CoreV1().Events(ecres.GetNamespace()).Create(context.Background(), evt, metav1.CreateOptions{})
+	return err
+}
+
+func LoadEnforceConfig(namespace, cmname string) *ecv1alpha1.ShieldConfig {
 
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -104,7 +247,5 @@ func LoadEnforceConfig(namespace, cmname string) *cfg.ShieldConfig {
 		log.Error("failed to get ShieldConfig:", err.Error())
 		return nil
 	}
-
-	ec := ecres.Spec.ShieldConfig
-	return ec
+	return ecres
 }
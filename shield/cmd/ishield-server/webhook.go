@@ -17,14 +17,22 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	shield "github.com/IBM/integrity-enforcer/shield/pkg/shield"
+	"github.com/IBM/integrity-enforcer/shield/pkg/util/certwatcher"
 	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	admv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,9 +47,30 @@ var (
 	universalDeserializer = serializer.NewCodecFactory(runtime.NewScheme()).UniversalDeserializer()
 )
 
+// time to wait for in-flight requests on shutdown. this and shutdownDelay should be shorter than terminationGracePeriodSeconds of the pod in total.
+const shutdownTimeout = 25 * time.Second
+
+// time to keep serving after the readiness probe starts failing, so that the pod is removed from the service endpoints
+// before the server stops accepting new connections.
+const shutdownDelay = 3 * time.Second
+
+// AdmissionResponse in k8s.io/api v0.18 does not have `warnings` field yet,
+// so these types are used to add it to the response. (supported by Kubernetes v1.19+)
+type admissionResponseWithWarnings struct {
+	*admv1.AdmissionResponse
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type admissionReviewWithWarnings struct {
+	metav1.TypeMeta `json:",inline"`
+	Response        *admissionResponseWithWarnings `json:"response,omitempty"`
+}
+
 type WebhookServer struct {
 	mux               *http.ServeMux
 	certPath, keyPath string
+	shuttingDown      bool
+	lock              sync.RWMutex
 }
 
 func init() {
@@ -49,20 +78,20 @@ func init() {
 
 	config = NewConfig()
 	config.InitShieldConfig()
-	logger.SetSingletonLoggerLevel(config.ShieldConfig.Log.LogLevel)
+	logger.SetSingletonLoggerLevel(config.GetShieldConfig().Log.LogLevel)
 	logger.Info("Integrity Shield has been started.")
 
-	cfgBytes, _ := json.Marshal(config)
+	cfgBytes, _ := json.Marshal(config.GetShieldConfig())
 	logger.Trace(string(cfgBytes))
 	logger.Info("ShieldConfig is loaded.")
 }
 
-func (server *WebhookServer) handleAdmissionRequest(admissionReviewReq *admv1.AdmissionReview) *admv1.AdmissionResponse {
+func (server *WebhookServer) handleAdmissionRequest(admissionReviewReq *admv1.AdmissionReview) (*admv1.AdmissionResponse, []string) {
 
-	_ = config.InitShieldConfig()
+	shieldConfig := config.GetShieldConfig()
 
 	gv := metav1.GroupVersion{Group: admissionReviewReq.Request.Kind.Group, Version: admissionReviewReq.Request.Kind.Version}
-	metaLogger := logger.NewLogger(config.ShieldConfig.LoggerConfig())
+	metaLogger := logger.NewLogger(shieldConfig.LoggerConfig())
 	reqLog := metaLogger.WithFields(
 		log.Fields{
 			"namespace":  admissionReviewReq.Request.Namespace,
@@ -73,13 +102,13 @@ func (server *WebhookServer) handleAdmissionRequest(admissionReviewReq *admv1.Ad
 			"requestUID": string(admissionReviewReq.Request.UID),
 		},
 	)
-	reqHandler := shield.NewHandler(config.ShieldConfig, metaLogger, reqLog)
+	reqHandler := shield.NewHandler(shieldConfig, metaLogger, reqLog)
 	admissionRequest := admissionReviewReq.Request
 
 	//process request
 	admissionResponse := reqHandler.Run(admissionRequest)
 
-	return admissionResponse
+	return admissionResponse, reqHandler.Warnings()
 
 }
 
@@ -89,6 +118,10 @@ func (server *WebhookServer) checkLiveness(w http.ResponseWriter, r *http.Reques
 }
 
 func (server *WebhookServer) checkReadiness(w http.ResponseWriter, r *http.Request) {
+	if server.isShuttingDown() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
 	msg := "readiness ok"
 	_, _ = w.Write([]byte(msg))
 }
@@ -116,6 +149,7 @@ func (server *WebhookServer) serveRequest(w http.ResponseWriter, r *http.Request
 	}
 
 	var admissionResponse *admv1.AdmissionResponse
+	var warnings []string
 	admissionReviewReq := admv1.AdmissionReview{}
 	if _, _, err := universalDeserializer.Decode(body, nil, &admissionReviewReq); err != nil {
 
@@ -127,16 +161,19 @@ func (server *WebhookServer) serveRequest(w http.ResponseWriter, r *http.Request
 
 	} else {
 
-		admissionResponse = server.handleAdmissionRequest(&admissionReviewReq)
+		admissionResponse, warnings = server.handleAdmissionRequest(&admissionReviewReq)
 
 	}
 
-	admissionReview := admv1.AdmissionReview{
+	admissionReview := admissionReviewWithWarnings{
 		TypeMeta: admissionReviewReq.TypeMeta,
 	}
 
 	if admissionResponse != nil {
-		admissionReview.Response = admissionResponse
+		admissionReview.Response = &admissionResponseWithWarnings{
+			AdmissionResponse: admissionResponse,
+			Warnings:          warnings,
+		}
 		if admissionReviewReq.Request != nil {
 			admissionReview.Response.UID = admissionReviewReq.Request.UID
 		}
@@ -167,23 +204,65 @@ func createNewServer(certPath, keyPath string) *WebhookServer {
 
 func (server *WebhookServer) Run() {
 
-	pair, err := tls.LoadX509KeyPair(server.certPath, server.keyPath)
-
+	certWatcher, err := certwatcher.NewCertWatcher(server.certPath, server.keyPath)
 	if err != nil {
 		panic(fmt.Sprintf("unable to load certs: %v", err))
 	}
 
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go certWatcher.Start(stopCh)
+	if err := config.WatchShieldConfig(stopCh); err != nil {
+		panic(fmt.Sprintf("unable to watch ShieldConfig: %v", err))
+	}
+	if err := WatchVerificationKeys(stopCh); err != nil {
+		panic(fmt.Sprintf("unable to watch verification keys: %v", err))
+	}
+
 	server.mux.HandleFunc("/mutate", server.serveRequest)
 	server.mux.HandleFunc("/health/liveness", server.checkLiveness)
 	server.mux.HandleFunc("/health/readiness", server.checkReadiness)
+	server.mux.Handle("/metrics", promhttp.Handler())
 
 	serverObj := &http.Server{
 		Addr:      ":8443",
-		TLSConfig: &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12},
+		TLSConfig: &tls.Config{GetCertificate: certWatcher.GetCertificate, MinVersion: tls.VersionTLS12},
 		Handler:   server.mux,
 	}
 
-	if err := serverObj.ListenAndServeTLS("", ""); err != nil {
+	// on SIGTERM, stop accepting new connections and wait for in-flight admission requests
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+		sig := <-sigCh
+		logger.Info(fmt.Sprintf("Received %s, shutting down the webhook server.", sig.String()))
+		server.setShuttingDown()
+		time.Sleep(shutdownDelay)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := serverObj.Shutdown(ctx); err != nil {
+			logger.Error("Failed to shutdown the webhook server gracefully; ", err.Error())
+		}
+	}()
+
+	if err := serverObj.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 		panic(fmt.Sprintf("Fail to run webhook server: %v", err))
 	}
+	<-shutdownDone
+	logger.Info("Integrity Shield has been stopped.")
+}
+
+func (server *WebhookServer) setShuttingDown() {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.shuttingDown = true
+}
+
+func (server *WebhookServer) isShuttingDown() bool {
+	server.lock.RLock()
+	defer server.lock.RUnlock()
+	return server.shuttingDown
 }
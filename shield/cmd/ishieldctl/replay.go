@@ -0,0 +1,169 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	shield "github.com/IBM/integrity-enforcer/shield/pkg/shield"
+	log "github.com/sirupsen/logrus"
+	admv1 "k8s.io/api/admission/v1"
+)
+
+type replayResult struct {
+	File      string                 `json:"file"`
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Kind      string                 `json:"kind"`
+	Operation string                 `json:"operation"`
+	Decision  *shield.DecisionResult `json:"decision"`
+	Context   *shield.CheckContext   `json:"context"`
+}
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	var resourcePaths stringList
+	configPath := fs.String("config", "", "path to a ShieldConfig file (CR or spec only)")
+	fs.Var(&resourcePaths, "resources", "files or directories of ResourceSigningProfile, SignerConfig, Namespace and ResourceSignature (can be repeated)")
+	keyDir := fs.String("keys", "", keyDirUsage)
+	logLevel := fs.String("log-level", "error", "log level of the decision engine")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ishieldctl replay -config <file> [options] <request file>...\n\n")
+		fmt.Fprintf(fs.Output(), "A request file can contain AdmissionReview, AdmissionRequest or context log records which have `request.dump`.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *configPath == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("both -config and request files are required")
+	}
+
+	shieldConfig, err := loadShieldConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load ShieldConfig; %s", err.Error())
+	}
+	keyPathList, err := loadKeyPathList(*keyDir)
+	if err != nil {
+		return fmt.Errorf("failed to load keys; %s", err.Error())
+	}
+	setOfflineConfig(shieldConfig, keyPathList)
+
+	resourceFiles, err := expandPaths(resourcePaths)
+	if err != nil {
+		return err
+	}
+	resources, err := shield.LoadFileResources(resourceFiles)
+	if err != nil {
+		return err
+	}
+	loaderFunc := shield.NewFileLoaderFunc(resources)
+
+	requestFiles, err := expandPaths(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	metaLogger := log.New()
+	metaLogger.SetOutput(os.Stderr)
+	if lvl, err := log.ParseLevel(*logLevel); err == nil {
+		metaLogger.SetLevel(lvl)
+	}
+
+	results := []replayResult{}
+	for _, fpath := range requestFiles {
+		reqs, err := loadAdmissionRequests(fpath)
+		if err != nil {
+			return fmt.Errorf("failed to load requests in %s; %s", fpath, err.Error())
+		}
+		for _, req := range reqs {
+			reqLog := metaLogger.WithFields(log.Fields{
+				"namespace":  req.Namespace,
+				"name":       req.Name,
+				"kind":       req.Kind.Kind,
+				"operation":  req.Operation,
+				"requestUID": string(req.UID),
+			})
+			handler := shield.NewHandlerWithLoader(shieldConfig, metaLogger, reqLog, loaderFunc)
+			dr, ctx := handler.Evaluate(req)
+			results = append(results, replayResult{
+				File:      fpath,
+				Namespace: req.Namespace,
+				Name:      req.Name,
+				Kind:      req.Kind.Kind,
+				Operation: string(req.Operation),
+				Decision:  dr,
+				Context:   ctx,
+			})
+		}
+	}
+
+	resultBytes, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(resultBytes))
+	return nil
+}
+
+// loadAdmissionRequests reads AdmissionRequests from a file.
+// AdmissionReview, AdmissionRequest, ReqContext and context log records (`request.dump`) are supported.
+func loadAdmissionRequests(fpath string) ([]*admv1.AdmissionRequest, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := decodeDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	reqs := []*admv1.AdmissionRequest{}
+	for _, doc := range docs {
+		var reqBytes []byte
+		if dump, ok := doc["request.dump"].(string); ok {
+			if dump == "" {
+				continue
+			}
+			reqBytes = []byte(dump)
+		} else if reqStr, ok := doc["request"].(string); ok {
+			reqBytes = []byte(reqStr)
+		} else if reqObj, ok := doc["request"]; ok {
+			reqBytes, err = json.Marshal(reqObj)
+		} else if _, ok := doc["uid"]; ok {
+			reqBytes, err = json.Marshal(doc)
+		} else {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var req *admv1.AdmissionRequest
+		if err := json.Unmarshal(reqBytes, &req); err != nil {
+			return nil, err
+		}
+		if req.DryRun == nil {
+			dryRun := false
+			req.DryRun = &dryRun
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
@@ -0,0 +1,444 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	shield "github.com/IBM/integrity-enforcer/shield/pkg/shield"
+	pgp "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/pgp"
+	x509 "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
+	ishieldyaml "github.com/IBM/integrity-enforcer/shield/pkg/util/yaml"
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	signFormatAnnotation        = "annotation"
+	signFormatResourceSignature = "resourcesignature"
+)
+
+// signatures annotations which are replaced by `ishieldctl sign`.
+// the validity period (`notBefore` and `notAfter`) is not included, because it is written by the author to be signed.
+var signAnnotationKeys = []string{
+	common.SignatureAnnotationKey,
+	common.MessageAnnotationKey,
+	common.CertificateAnnotationKey,
+	common.SignatureTypeAnnotationKey,
+	common.MessageScopeAnnotationKey,
+	common.MutableAttrsAnnotationKey,
+	common.BundleAnnotationKey,
+}
+
+// signature annotations for additional signers, which have numbered suffix like `integrityshield.io/signature.1`
+var multiSignAnnotationKeys = []string{
+	common.SignatureAnnotationKey,
+	common.CertificateAnnotationKey,
+	common.BundleAnnotationKey,
+}
+
+// removeSignatureAnnotations removes the signature annotations of the previous signing,
+// so that stale signatures are not left in the re-signed resource.
+func removeSignatureAnnotations(annotations map[string]interface{}) {
+	for _, key := range signAnnotationKeys {
+		delete(annotations, key)
+	}
+	for key := range annotations {
+		for _, prefix := range multiSignAnnotationKeys {
+			if num, err := strconv.Atoi(strings.TrimPrefix(key, prefix+".")); strings.HasPrefix(key, prefix+".") && err == nil && num > 0 {
+				delete(annotations, key)
+			}
+		}
+	}
+}
+
+// setValidityAnnotations sets the validity period specified by the options, so that it is signed together with the resource.
+// the annotations written in the resource are kept if the options are empty.
+func setValidityAnnotations(annotations map[string]interface{}, opts *signOptions) {
+	if opts.notBefore != "" {
+		annotations[common.NotBeforeAnnotationKey] = opts.notBefore
+	}
+	if opts.notAfter != "" {
+		annotations[common.NotAfterAnnotationKey] = opts.notAfter
+	}
+}
+
+// resourceSignatureName returns the name of ResourceSignature for the resource. The namespace of the resource is included,
+// so that ResourceSignatures for resources with the same name in different namespaces do not collide.
+func resourceSignatureName(ref *common.ResourceRef) string {
+	name := fmt.Sprintf("rsig-%s-%s", strings.ToLower(ref.Kind), ref.Name)
+	if ref.Namespace != "" {
+		name = fmt.Sprintf("rsig-%s-%s-%s", strings.ToLower(ref.Kind), ref.Namespace, ref.Name)
+	}
+	// a long name is shortened with its hash
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		hash := sha256.Sum256([]byte(name))
+		name = fmt.Sprintf("%s-%s", name[:validation.DNS1123SubdomainMaxLength-17], hex.EncodeToString(hash[:])[:16])
+	}
+	return name
+}
+
+// messageSigner signs a message and returns the signature and the certificate (only for x509)
+type messageSigner interface {
+	Sign(msg []byte) ([]byte, []byte, error)
+}
+
+type pgpSigner struct {
+	keyRing    []byte
+	signer     string
+	passphrase []byte
+}
+
+func (self *pgpSigner) Sign(msg []byte) ([]byte, []byte, error) {
+	keyRing, err := pgp.ReadKeyRing(self.keyRing)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read keyring; %s", err.Error())
+	}
+	sig, err := pgp.DetachSign(keyRing, msg, self.signer, self.passphrase)
+	return sig, nil, err
+}
+
+type x509Signer struct {
+	privateKey  []byte
+	certificate []byte
+}
+
+func (self *x509Signer) Sign(msg []byte) ([]byte, []byte, error) {
+	sig, err := x509.GenerateSignature(msg, self.privateKey)
+	return sig, self.certificate, err
+}
+
+// signOptions is a set of options for signing resources, which are shared by all documents in the input
+type signOptions struct {
+	format        string
+	signatureType string
+	messageScope  string
+	mutableAttrs  string
+	namespace     string
+	notBefore     string
+	notAfter      string
+	signer        messageSigner
+	now           time.Time
+}
+
+func sign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	format := fs.String("format", signFormatAnnotation, "output format, `annotation` (signed resources) or `resourcesignature` (ResourceSignature for each resource)")
+	sigType := fs.String("type", string(common.SignatureTypePGP), "signature type, `pgp` or `x509`")
+	keyPath := fs.String("key", "", "pgp: secret keyring file (binary or armored), x509: private key file in PEM format")
+	certPath := fs.String("cert", "", "x509: certificate file of the signing key in PEM format")
+	signerName := fs.String("signer", "", "pgp: email, name or comment of the signer key in the keyring (the first key by default)")
+	passphraseFile := fs.String("passphrase-file", "", "pgp: file which contains the passphrase of the encrypted signer key")
+	signatureType := fs.String("signature-type", rsigapi.SignatureTypeResource, "signature type for verification, `resource`, `applyingResource` or `patch`")
+	messageScope := fs.String("message-scope", "", "comma separated attributes to be signed instead of the whole resource (e.g. `spec`)")
+	mutableAttrs := fs.String("mutable-attrs", "", "comma separated attributes in the message scope which can be changed")
+	namespace := fs.String("namespace", "", "namespace of ResourceSignatures")
+	notBefore := fs.String("not-before", "", "start of the signature validity period in RFC3339 format (e.g. `2021-01-01T00:00:00Z`), which overwrites the `notBefore` annotation")
+	notAfter := fs.String("not-after", "", "end of the signature validity period in RFC3339 format (e.g. `2021-06-30T00:00:00Z`), which overwrites the `notAfter` annotation")
+	outputPath := fs.String("o", "", "output file (default: stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ishieldctl sign -key <file> [options] <YAML file>...\n\n")
+		fmt.Fprintf(fs.Output(), "Sign all resources in the YAML files, and output the signed resources or ResourceSignatures.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *keyPath == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("both -key and YAML files are required")
+	}
+	if *format != signFormatAnnotation && *format != signFormatResourceSignature {
+		return fmt.Errorf("unsupported format `%s`", *format)
+	}
+	if *mutableAttrs != "" && *messageScope == "" {
+		return fmt.Errorf("-mutable-attrs can be used only with -message-scope")
+	}
+	if *messageScope != "" && *format != signFormatAnnotation {
+		// a ResourceSignature is found by the resource in its message, so the message cannot be omitted
+		return fmt.Errorf("-message-scope can be used only with annotation format")
+	}
+	for name, value := range map[string]string{"-not-before": *notBefore, "-not-after": *notAfter} {
+		if _, err := time.Parse(time.RFC3339, value); value != "" && err != nil {
+			return fmt.Errorf("%s must be in RFC3339 format; %s", name, err.Error())
+		}
+	}
+
+	signer, err := newMessageSigner(common.SignatureType(*sigType), *keyPath, *certPath, *signerName, *passphraseFile)
+	if err != nil {
+		return err
+	}
+	opts := &signOptions{
+		format:        *format,
+		signatureType: *signatureType,
+		messageScope:  *messageScope,
+		mutableAttrs:  *mutableAttrs,
+		namespace:     *namespace,
+		notBefore:     *notBefore,
+		notAfter:      *notAfter,
+		signer:        signer,
+		now:           time.Now(),
+	}
+
+	docs := []map[string]interface{}{}
+	for _, fpath := range fs.Args() {
+		data, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return err
+		}
+		fileDocs, err := decodeDocuments(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s; %s", fpath, err.Error())
+		}
+		docs = append(docs, fileDocs...)
+	}
+	signed, err := signDocuments(docs, opts)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = out.Write(signed)
+	return err
+}
+
+func newMessageSigner(sigType common.SignatureType, keyPath, certPath, signer, passphraseFile string) (messageSigner, error) {
+	key, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	switch sigType {
+	case common.SignatureTypePGP:
+		var passphrase []byte
+		if passphraseFile != "" {
+			if passphrase, err = ioutil.ReadFile(passphraseFile); err != nil {
+				return nil, err
+			}
+			passphrase = []byte(strings.TrimRight(string(passphrase), "\r\n"))
+		}
+		return &pgpSigner{keyRing: key, signer: signer, passphrase: passphrase}, nil
+	case common.SignatureTypeX509:
+		if certPath == "" {
+			return nil, fmt.Errorf("-cert is required for x509 signature")
+		}
+		cert, err := ioutil.ReadFile(certPath)
+		if err != nil {
+			return nil, err
+		}
+		return &x509Signer{privateKey: key, certificate: cert}, nil
+	}
+	return nil, fmt.Errorf("unsupported signature type `%s`", sigType)
+}
+
+// signDocuments signs each resource and returns the output documents in multi-document YAML
+func signDocuments(docs []map[string]interface{}, opts *signOptions) ([]byte, error) {
+	outputs := []string{}
+	for _, doc := range docs {
+		var signed map[string]interface{}
+		var err error
+		if opts.format == signFormatResourceSignature {
+			signed, err = newSignedResourceSignature(doc, opts)
+		} else {
+			signed, err = signWithAnnotations(doc, opts)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign %s; %s", describeDocument(doc), err.Error())
+		}
+		signedYaml, err := yaml.Marshal(signed)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, string(signedYaml))
+	}
+	return []byte(strings.Join(outputs, "---\n")), nil
+}
+
+// signWithAnnotations returns the resource with signature annotations.
+// The whole resource is signed and attached as `message`, or only `messageScope` is signed if it is specified.
+func signWithAnnotations(doc map[string]interface{}, opts *signOptions) (map[string]interface{}, error) {
+	obj := copyDocument(doc)
+	annotations := getAnnotations(obj)
+	removeSignatureAnnotations(annotations)
+	setValidityAnnotations(annotations, opts)
+	if opts.signatureType != rsigapi.SignatureTypeResource {
+		annotations[common.SignatureTypeAnnotationKey] = opts.signatureType
+	}
+
+	var message string
+	if opts.messageScope != "" {
+		annotations[common.MessageScopeAnnotationKey] = opts.messageScope
+		if opts.mutableAttrs != "" {
+			annotations[common.MutableAttrsAnnotationKey] = opts.mutableAttrs
+		}
+		// the message is generated from the requested object in the same way when it is verified
+		objBytes, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		message = shield.GenerateMessageFromRawObj(objBytes, opts.messageScope, opts.mutableAttrs)
+	} else {
+		msgBytes, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		message = string(msgBytes)
+	}
+
+	sig, cert, err := opts.signer.Sign([]byte(message))
+	if err != nil {
+		return nil, err
+	}
+	if opts.messageScope == "" {
+		annotations[common.MessageAnnotationKey] = ishieldyaml.Base64encode(ishieldyaml.Compress(message))
+	}
+	annotations[common.SignatureAnnotationKey] = ishieldyaml.Base64encode(string(sig))
+	if cert != nil {
+		annotations[common.CertificateAnnotationKey] = ishieldyaml.Base64encode(string(cert))
+	}
+	return obj, nil
+}
+
+// newSignedResourceSignature returns a ResourceSignature for the resource. The ResourceSignature itself is signed
+// with the scope `spec` so that it can be protected in the same way as other signed resources.
+func newSignedResourceSignature(doc map[string]interface{}, opts *signOptions) (map[string]interface{}, error) {
+	obj := copyDocument(doc)
+	annotations := getAnnotations(obj)
+	removeSignatureAnnotations(annotations)
+	setValidityAnnotations(annotations, opts)
+	if len(annotations) == 0 {
+		delete(obj["metadata"].(map[string]interface{}), "annotations")
+	}
+	ref := getResourceRef(obj)
+	if ref.ApiVersion == "" || ref.Kind == "" || ref.Name == "" {
+		return nil, fmt.Errorf("apiVersion, kind and metadata.name are required")
+	}
+
+	msgBytes, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	message := string(msgBytes)
+	signItem := map[string]interface{}{
+		"message": ishieldyaml.Base64encode(ishieldyaml.Compress(message)),
+		"type":    opts.signatureType,
+	}
+	sig, cert, err := opts.signer.Sign([]byte(message))
+	if err != nil {
+		return nil, err
+	}
+	signItem["signature"] = ishieldyaml.Base64encode(string(sig))
+	signItem["certificate"] = ""
+	if cert != nil {
+		signItem["certificate"] = ishieldyaml.Base64encode(string(cert))
+	}
+
+	metadata := map[string]interface{}{
+		"name": resourceSignatureName(ref),
+		"labels": map[string]interface{}{
+			common.ResSigLabelApiVer: strings.ReplaceAll(ref.ApiVersion, "/", "_"),
+			common.ResSigLabelKind:   ref.Kind,
+			common.ResSigLabelTime:   strconv.FormatInt(opts.now.Unix(), 10),
+		},
+		"annotations": map[string]interface{}{
+			common.MessageScopeAnnotationKey: "spec",
+		},
+	}
+	if opts.namespace != "" {
+		metadata["namespace"] = opts.namespace
+	}
+	rsig := map[string]interface{}{
+		"apiVersion": rsigapi.SchemeGroupVersion.String(),
+		"kind":       common.SignatureCustomResourceKind,
+		"metadata":   metadata,
+		"spec": map[string]interface{}{
+			"data": []interface{}{signItem},
+		},
+	}
+
+	rsigBytes, err := json.Marshal(rsig)
+	if err != nil {
+		return nil, err
+	}
+	rsigMessage := shield.GenerateMessageFromRawObj(rsigBytes, "spec", "")
+	rsigSig, rsigCert, err := opts.signer.Sign([]byte(rsigMessage))
+	if err != nil {
+		return nil, err
+	}
+	rsigAnnotations := metadata["annotations"].(map[string]interface{})
+	rsigAnnotations[common.SignatureAnnotationKey] = ishieldyaml.Base64encode(string(rsigSig))
+	if rsigCert != nil {
+		rsigAnnotations[common.CertificateAnnotationKey] = ishieldyaml.Base64encode(string(rsigCert))
+	}
+	return rsig, nil
+}
+
+// copyDocument returns a deep copy of the document so that the input is not modified
+func copyDocument(doc map[string]interface{}) map[string]interface{} {
+	docBytes, _ := json.Marshal(doc)
+	var copied map[string]interface{}
+	_ = json.Unmarshal(docBytes, &copied)
+	return copied
+}
+
+// getAnnotations returns `metadata.annotations` of the object, which is created if it does not exist
+func getAnnotations(obj map[string]interface{}) map[string]interface{} {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		obj["metadata"] = metadata
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	return annotations
+}
+
+func getResourceRef(obj map[string]interface{}) *common.ResourceRef {
+	ref := &common.ResourceRef{}
+	ref.ApiVersion, _ = obj["apiVersion"].(string)
+	ref.Kind, _ = obj["kind"].(string)
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		ref.Name, _ = metadata["name"].(string)
+		ref.Namespace, _ = metadata["namespace"].(string)
+	}
+	return ref
+}
+
+func describeDocument(doc map[string]interface{}) string {
+	ref := getResourceRef(doc)
+	return fmt.Sprintf("%s %s", ref.Kind, ref.Name)
+}
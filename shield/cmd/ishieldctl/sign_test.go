@@ -0,0 +1,170 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	pgp "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/pgp"
+	ishieldyaml "github.com/IBM/integrity-enforcer/shield/pkg/util/yaml"
+	"golang.org/x/crypto/openpgp"
+)
+
+const testSignInput = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-cm
+  namespace: secure-ns
+data:
+  key1: val1
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-cm2
+  namespace: secure-ns
+  annotations:
+    integrityshield.io/signature: old-signature
+    integrityshield.io/signature.1: old-signature-1
+    integrityshield.io/notAfter: "2099-01-01T00:00:00Z"
+    integrityshield.io/signature.note: kept
+data:
+  key2: val2
+`
+
+func newTestPGPSigner(t *testing.T) (*pgpSigner, openpgp.EntityList) {
+	entity, err := openpgp.NewEntity("TestSigner", "", "signer@enterprise.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secring := bytes.NewBuffer(nil)
+	if err := entity.SerializePrivate(secring, nil); err != nil {
+		t.Fatal(err)
+	}
+	pubring := bytes.NewBuffer(nil)
+	if err := entity.Serialize(pubring); err != nil {
+		t.Fatal(err)
+	}
+	keyRing, _ := pgp.ReadKeyRing(pubring.Bytes())
+	return &pgpSigner{keyRing: secring.Bytes(), signer: "signer@enterprise.com"}, keyRing
+}
+
+func TestSignAnnotation(t *testing.T) {
+	signer, keyRing := newTestPGPSigner(t)
+	docs, _ := decodeDocuments([]byte(testSignInput))
+	opts := &signOptions{format: signFormatAnnotation, signatureType: "resource", signer: signer, now: time.Now()}
+	signed, err := signDocuments(docs, opts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	signedDocs, _ := decodeDocuments(signed)
+	if len(signedDocs) != 2 {
+		t.Errorf("Sign Failed\nexpected: %d documents\nactual: %d documents", 2, len(signedDocs))
+		return
+	}
+	for _, doc := range signedDocs {
+		annotations := getAnnotations(doc)
+		message := ishieldyaml.Decompress(ishieldyaml.Base64decode(annotations[common.MessageAnnotationKey].(string)))
+		signature := ishieldyaml.Base64decode(annotations[common.SignatureAnnotationKey].(string))
+		if ok, reasonFail, _, _, _ := pgp.VerifySignatureWithKeyRing(keyRing, message, signature); !ok {
+			t.Errorf("Sign Failed\nexpected: verified signature of %s\nactual: %s", describeDocument(doc), reasonFail)
+		}
+		// signatures of the previous signing are removed
+		if _, ok := annotations["integrityshield.io/signature.1"]; ok {
+			t.Errorf("Sign Failed\nexpected: no stale annotation %s\nactual: %v", "integrityshield.io/signature.1", annotations)
+		}
+		ref := getResourceRef(doc)
+		if found, _ := ishieldyaml.FindSingleYaml([]byte(annotations[common.MessageAnnotationKey].(string)), ref.ApiVersion, ref.Kind, ref.Name, ref.Namespace); !found {
+			t.Errorf("Sign Failed\nexpected: the message includes %s\nactual: %s", describeDocument(doc), message)
+		}
+	}
+}
+
+func TestSignValidity(t *testing.T) {
+	signer, _ := newTestPGPSigner(t)
+	docs, _ := decodeDocuments([]byte(testSignInput))
+
+	// the validity period written by the author is kept and signed
+	opts := &signOptions{format: signFormatAnnotation, signatureType: "resource", signer: signer, now: time.Now()}
+	signed, err := signDocuments(docs, opts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	signedDocs, _ := decodeDocuments(signed)
+	annotations := getAnnotations(signedDocs[1])
+	message := ishieldyaml.Decompress(ishieldyaml.Base64decode(annotations[common.MessageAnnotationKey].(string)))
+	validity := common.GetSignatureValidity([]byte(message))
+	if annotations[common.NotAfterAnnotationKey] != "2099-01-01T00:00:00Z" || validity.NotAfter != "2099-01-01T00:00:00Z" {
+		t.Errorf("Sign Failed\nexpected: signed notAfter %s\nactual: %v, message: %s", "2099-01-01T00:00:00Z", annotations, message)
+	}
+
+	// the options overwrite the validity period in the resource
+	opts.format = signFormatResourceSignature
+	opts.notBefore = "2021-02-01T00:00:00Z"
+	opts.notAfter = "2021-03-01T00:00:00Z"
+	signed, err = signDocuments(docs, opts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rsigs, _ := decodeDocuments(signed)
+	for _, rsig := range rsigs {
+		signItem := rsig["spec"].(map[string]interface{})["data"].([]interface{})[0].(map[string]interface{})
+		message := ishieldyaml.Decompress(ishieldyaml.Base64decode(signItem["message"].(string)))
+		validity := common.GetSignatureValidity([]byte(message))
+		if validity.NotBefore != opts.notBefore || validity.NotAfter != opts.notAfter {
+			t.Errorf("Sign Failed\nexpected: signed period %s - %s\nactual: %v", opts.notBefore, opts.notAfter, validity)
+		}
+	}
+}
+
+func TestSignResourceSignature(t *testing.T) {
+	signer, keyRing := newTestPGPSigner(t)
+	docs, _ := decodeDocuments([]byte(testSignInput))
+	now := time.Unix(1609900327, 0)
+	opts := &signOptions{format: signFormatResourceSignature, signatureType: "resource", namespace: "secure-ns", signer: signer, now: now}
+	signed, err := signDocuments(docs, opts)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rsigs, _ := decodeDocuments(signed)
+	if len(rsigs) != 2 {
+		t.Errorf("Sign Failed\nexpected: %d ResourceSignatures\nactual: %d ResourceSignatures", 2, len(rsigs))
+		return
+	}
+	rsig := rsigs[1]
+	metadata := rsig["metadata"].(map[string]interface{})
+	labels := metadata["labels"].(map[string]interface{})
+	if metadata["name"] != "rsig-configmap-secure-ns-test-cm2" || metadata["namespace"] != "secure-ns" || labels[common.ResSigLabelApiVer] != "v1" || labels[common.ResSigLabelKind] != "ConfigMap" || labels[common.ResSigLabelTime] != "1609900327" {
+		t.Errorf("Sign Failed\nexpected: rsig-configmap-secure-ns-test-cm2 with labels\nactual: %v", metadata)
+	}
+	signItem := rsig["spec"].(map[string]interface{})["data"].([]interface{})[0].(map[string]interface{})
+	message := ishieldyaml.Decompress(ishieldyaml.Base64decode(signItem["message"].(string)))
+	signature := ishieldyaml.Base64decode(signItem["signature"].(string))
+	if ok, reasonFail, _, _, _ := pgp.VerifySignatureWithKeyRing(keyRing, message, signature); !ok {
+		t.Errorf("Sign Failed\nexpected: verified signature\nactual: %s", reasonFail)
+	}
+	if bytes.Contains([]byte(message), []byte("old-signature")) {
+		t.Errorf("Sign Failed\nexpected: the message without signature annotations\nactual: %s", message)
+	}
+}
@@ -0,0 +1,187 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ecv1alpha1 "github.com/IBM/integrity-enforcer/shield/pkg/apis/shieldconfig/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// stringList is a flag which can be specified multiple times or as a comma separated list
+type stringList []string
+
+func (self *stringList) String() string {
+	return strings.Join(*self, ",")
+}
+
+func (self *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v != "" {
+			*self = append(*self, v)
+		}
+	}
+	return nil
+}
+
+// expandPaths returns all files in the given paths. directories are walked recursively.
+func expandPaths(paths []string) ([]string, error) {
+	files := []string{}
+	for _, p := range paths {
+		err := filepath.Walk(p, func(fpath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if fpath != p && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+			files = append(files, fpath)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// loadKeyPathList returns key paths in the directory which has the same layout as the key mount path of IShield server,
+// i.e. `<dir>/<keyConfig name>/pgp/<keyring file>` and `<dir>/<keyConfig name>/x509/<cert files>`.
+// Same as IShield server, a pgp keyring is specified by its file path and x509 certs are specified by the directory.
+const keyDirUsage = "directory of verification keys in the layout `<dir>/<keyConfig>/<pgp|x509|sigstore>/<file>`"
+
+func loadKeyPathList(dir string) ([]string, error) {
+	keyPathList := []string{}
+	if dir == "" {
+		return keyPathList, nil
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	keyConfDirs, err := ioutil.ReadDir(absDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, keyConfDir := range keyConfDirs {
+		if !keyConfDir.IsDir() {
+			continue
+		}
+		pgpDir := filepath.Join(absDir, keyConfDir.Name(), string(common.SignatureTypePGP))
+		if pgpFiles, err := ioutil.ReadDir(pgpDir); err == nil {
+			for _, f := range pgpFiles {
+				if !f.IsDir() && !strings.HasPrefix(f.Name(), ".") {
+					keyPathList = append(keyPathList, filepath.Join(pgpDir, f.Name()))
+				}
+			}
+		}
+		for _, sigType := range []common.SignatureType{common.SignatureTypeX509, common.SignatureTypeSigstore} {
+			keyDir := filepath.Join(absDir, keyConfDir.Name(), string(sigType))
+			if info, err := os.Stat(keyDir); err == nil && info.IsDir() {
+				keyPathList = append(keyPathList, keyDir+"/")
+			}
+		}
+	}
+	return keyPathList, nil
+}
+
+// setOfflineConfig sets the keys in local files to ShieldConfig and disables signature stores,
+// because ishieldctl evaluates requests without accessing a cluster or network.
+func setOfflineConfig(shieldConfig *config.ShieldConfig, keyPathList []string) {
+	shieldConfig.KeyPathList = keyPathList
+	shieldConfig.SignatureStores = nil
+}
+
+// loadShieldConfig reads a ShieldConfig from a file. Both ShieldConfig CR and its spec are accepted.
+// Default values are filled in for `log` in the same way as ishield-server.
+func loadShieldConfig(fpath string) (*config.ShieldConfig, error) {
+	shieldConfig, err := readShieldConfig(fpath)
+	if err != nil {
+		return nil, err
+	}
+	if shieldConfig == nil {
+		return nil, fmt.Errorf("ShieldConfig in %s is empty", fpath)
+	}
+	shieldConfig.LogConfig()
+	return shieldConfig, nil
+}
+
+func readShieldConfig(fpath string) (*config.ShieldConfig, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	jsonBytes, err := k8syaml.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(jsonBytes, &obj); err != nil {
+		return nil, err
+	}
+	if kind, _ := obj["kind"].(string); kind == "ShieldConfig" {
+		var ecres *ecv1alpha1.ShieldConfig
+		if err := json.Unmarshal(jsonBytes, &ecres); err != nil {
+			return nil, err
+		}
+		if ecres.Spec.ShieldConfig == nil {
+			return nil, fmt.Errorf("ShieldConfig in %s is empty", fpath)
+		}
+		return ecres.Spec.ShieldConfig, nil
+	}
+	var shieldConfig *config.ShieldConfig
+	if err := json.Unmarshal(jsonBytes, &shieldConfig); err != nil {
+		return nil, err
+	}
+	return shieldConfig, nil
+}
+
+// decodeDocuments decodes all YAML documents or JSON objects (e.g. JSON lines) in the data.
+func decodeDocuments(data []byte) ([]map[string]interface{}, error) {
+	dec := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	docs := []map[string]interface{}{}
+	for {
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		docs = append(docs, obj)
+	}
+	return docs, nil
+}
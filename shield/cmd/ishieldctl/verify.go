@@ -0,0 +1,237 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	shield "github.com/IBM/integrity-enforcer/shield/pkg/shield"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	log "github.com/sirupsen/logrus"
+	admv1 "k8s.io/api/admission/v1"
+	authv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const verifyUserName = "ishieldctl"
+
+type verifyResult struct {
+	File           string `json:"file"`
+	ApiVersion     string `json:"apiVersion"`
+	Kind           string `json:"kind"`
+	Namespace      string `json:"namespace"`
+	Name           string `json:"name"`
+	Allowed        bool   `json:"allowed"`
+	Verified       bool   `json:"verified"`
+	Signer         string `json:"signer,omitempty"`
+	MatchedProfile string `json:"matchedProfile,omitempty"`
+	ReasonCode     int    `json:"reasonCode"`
+	Message        string `json:"message"`
+	Diff           string `json:"diff,omitempty"`
+	DryRunSkipped  bool   `json:"dryRunSkipped,omitempty"`
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var resourcePaths stringList
+	configPath := fs.String("config", "", "path to a ShieldConfig file (CR or spec only)")
+	fs.Var(&resourcePaths, "resources", "files or directories of ResourceSigningProfile, SignerConfig, Namespace, ResourceSignature and SignatureRevocation (can be repeated)")
+	keyDir := fs.String("keys", "", keyDirUsage)
+	namespace := fs.String("namespace", "", "namespace of the resources which do not have metadata.namespace (empty for cluster scope)")
+	logLevel := fs.String("log-level", "error", "log level of the decision engine")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ishieldctl verify -config <file> [options] <YAML file>...\n\n")
+		fmt.Fprintf(fs.Output(), "Each resource in the files is verified as a CREATE request without accessing a cluster. ResourceSignatures in the files are used for verification.\n")
+		fmt.Fprintf(fs.Output(), "Dry-run is not available offline, so a resource which matches its signature only after defaulting by the API server is reported with `dryRunSkipped`.\n")
+		fmt.Fprintf(fs.Output(), "The command fails if any resource is denied.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *configPath == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("both -config and YAML files are required")
+	}
+
+	shieldConfig, err := loadShieldConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load ShieldConfig; %s", err.Error())
+	}
+	keyPathList, err := loadKeyPathList(*keyDir)
+	if err != nil {
+		return fmt.Errorf("failed to load keys; %s", err.Error())
+	}
+	setOfflineConfig(shieldConfig, keyPathList)
+
+	resourceFiles, err := expandPaths(resourcePaths)
+	if err != nil {
+		return err
+	}
+	resources, err := shield.LoadFileResources(resourceFiles)
+	if err != nil {
+		return err
+	}
+
+	yamlFiles, err := expandPaths(fs.Args())
+	if err != nil {
+		return err
+	}
+	targets, err := loadVerifyTargets(yamlFiles, resources)
+	if err != nil {
+		return err
+	}
+
+	metaLogger := log.New()
+	metaLogger.SetOutput(os.Stderr)
+	if lvl, err := log.ParseLevel(*logLevel); err == nil {
+		metaLogger.SetLevel(lvl)
+	}
+
+	results, err := verifyResources(targets, resources, shieldConfig, *namespace, metaLogger)
+	if err != nil {
+		return err
+	}
+
+	resultBytes, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(resultBytes))
+
+	denied := 0
+	for _, r := range results {
+		if !r.Allowed {
+			denied++
+		}
+	}
+	if denied > 0 {
+		return fmt.Errorf("%d of %d resources are denied", denied, len(results))
+	}
+	return nil
+}
+
+type verifyTarget struct {
+	file string
+	doc  map[string]interface{}
+}
+
+// loadVerifyTargets reads resources to be verified from the files.
+// ResourceSignatures in the files are added to the resources for verification instead of being verified.
+func loadVerifyTargets(files []string, resources *shield.FileResources) ([]verifyTarget, error) {
+	targets := []verifyTarget{}
+	for _, fpath := range files {
+		data, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := decodeDocuments(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s; %s", fpath, err.Error())
+		}
+		for _, doc := range docs {
+			if kind, _ := doc["kind"].(string); kind == common.SignatureCustomResourceKind {
+				if err := resources.Add(doc); err != nil {
+					return nil, fmt.Errorf("failed to load %s; %s", fpath, err.Error())
+				}
+				continue
+			}
+			targets = append(targets, verifyTarget{file: fpath, doc: doc})
+		}
+	}
+	return targets, nil
+}
+
+func verifyResources(targets []verifyTarget, resources *shield.FileResources, shieldConfig *config.ShieldConfig, namespace string, metaLogger *log.Logger) ([]verifyResult, error) {
+	loaderFunc := shield.NewFileLoaderFunc(resources)
+	results := []verifyResult{}
+	for _, target := range targets {
+		req, err := newCreateRequest(target.doc, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s in %s; %s", describeDocument(target.doc), target.file, err.Error())
+		}
+		reqLog := metaLogger.WithFields(log.Fields{
+			"namespace": req.Namespace,
+			"name":      req.Name,
+			"kind":      req.Kind.Kind,
+			"file":      target.file,
+		})
+		handler := shield.NewHandlerWithLoader(shieldConfig, metaLogger, reqLog, loaderFunc)
+		dr, ctx := handler.Evaluate(req)
+		result := verifyResult{
+			File:       target.file,
+			ApiVersion: getResourceRef(target.doc).ApiVersion,
+			Kind:       req.Kind.Kind,
+			Namespace:  req.Namespace,
+			Name:       req.Name,
+			Allowed:    dr.Type == common.DecisionAllow,
+			Verified:   dr.Verified,
+			ReasonCode: dr.ReasonCode,
+			Message:    dr.Message,
+		}
+		if ctx != nil {
+			result.MatchedProfile = ctx.MatchedProfile
+			if sigResult := ctx.SignatureEvalResult; sigResult != nil {
+				result.Signer = sigResult.SignerName
+				result.Diff = sigResult.Diff
+				result.DryRunSkipped = sigResult.DryRunSkipped
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// newCreateRequest returns an AdmissionRequest to create the resource.
+// The namespace is used only when the resource does not have metadata.namespace.
+func newCreateRequest(doc map[string]interface{}, namespace string) (*admv1.AdmissionRequest, error) {
+	ref := getResourceRef(doc)
+	if ref.ApiVersion == "" || ref.Kind == "" || ref.Name == "" {
+		return nil, fmt.Errorf("apiVersion, kind and metadata.name are required")
+	}
+	gv, err := schema.ParseGroupVersion(ref.ApiVersion)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = namespace
+	}
+	objBytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := false
+	req := &admv1.AdmissionRequest{
+		UID:       types.UID(fmt.Sprintf("ishieldctl-verify-%s-%s-%s", strings.ToLower(ref.Kind), ref.Namespace, ref.Name)),
+		Kind:      metav1.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: ref.Kind},
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+		Operation: admv1.Create,
+		UserInfo:  authv1.UserInfo{Username: verifyUserName},
+		Object:    runtime.RawExtension{Raw: objBytes},
+		DryRun:    &dryRun,
+	}
+	return req, nil
+}
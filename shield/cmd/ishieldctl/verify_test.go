@@ -0,0 +1,146 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	shield "github.com/IBM/integrity-enforcer/shield/pkg/shield"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const testVerifyResources = `apiVersion: apis.integrityshield.io/v1alpha1
+kind: ResourceSigningProfile
+metadata:
+  name: sample-rsp
+  namespace: secure-ns
+spec:
+  protectRules:
+  - match:
+    - kind: ConfigMap
+---
+apiVersion: apis.integrityshield.io/v1alpha1
+kind: SignerConfig
+metadata:
+  name: signer-config
+  namespace: integrity-shield-operator-system
+spec:
+  config:
+    policies:
+    - namespaces:
+      - "*"
+      signers:
+      - signer-a
+    signers:
+    - name: signer-a
+      keyConfig: sample-keyconfig
+      subjects:
+      - email: signer@enterprise.com
+`
+
+func TestVerifyResources(t *testing.T) {
+	signer, keyRing := newTestPGPSigner(t)
+	tmpDir, err := ioutil.TempDir("", "ishieldctl-verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pgpDir := filepath.Join(tmpDir, "keys", "sample-keyconfig", "pgp")
+	_ = os.MkdirAll(pgpDir, 0755)
+	pubring := bytes.NewBuffer(nil)
+	_ = keyRing[0].Serialize(pubring)
+	_ = ioutil.WriteFile(filepath.Join(pgpDir, "pubring.gpg"), pubring.Bytes(), 0644)
+	resPath := filepath.Join(tmpDir, "resources.yaml")
+	_ = ioutil.WriteFile(resPath, []byte(testVerifyResources), 0644)
+
+	docs, _ := decodeDocuments([]byte(testSignInput))
+	opts := &signOptions{format: signFormatAnnotation, signatureType: "resource", signer: signer, now: time.Now()}
+	signed, err := signDocuments(docs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedPath := filepath.Join(tmpDir, "signed.yaml")
+	_ = ioutil.WriteFile(signedPath, signed, 0644)
+	tamperedPath := filepath.Join(tmpDir, "tampered.yaml")
+	_ = ioutil.WriteFile(tamperedPath, []byte(strings.Replace(string(signed), "key1: val1", "key1: changed", 1)), 0644)
+
+	shieldConfig, err := loadShieldConfig("../../pkg/shield/testdata/config_2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// signature stores are not used offline
+	shieldConfig.SignatureStores = []config.SignatureStoreConfig{{Type: config.SignatureStoreTypeConfigMap, ConfigMapName: "signature-store"}}
+	keyPathList, _ := loadKeyPathList(filepath.Join(tmpDir, "keys"))
+	setOfflineConfig(shieldConfig, keyPathList)
+	if shieldConfig.SignatureStores != nil {
+		t.Errorf("Verify Failed\nexpected: no signature store\nactual: %v", shieldConfig.SignatureStores)
+	}
+	resources, err := shield.LoadFileResources([]string{resPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, err := loadVerifyTargets([]string{signedPath, tamperedPath}, resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metaLogger := log.New()
+	metaLogger.SetOutput(ioutil.Discard)
+	results, err := verifyResources(targets, resources, shieldConfig, "", metaLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Errorf("Verify Failed\nexpected: %d results\nactual: %d results", 4, len(results))
+		return
+	}
+	expected := []bool{true, true, false, true}
+	for i, r := range results {
+		if r.Allowed != expected[i] {
+			t.Errorf("Verify Failed for %s in %s\nexpected: allowed=%t\nactual: allowed=%t (%s)", r.Name, r.File, expected[i], r.Allowed, r.Message)
+		}
+		if r.MatchedProfile != "secure-ns/sample-rsp" {
+			t.Errorf("Verify Failed for %s in %s\nexpected: %s\nactual: %s", r.Name, r.File, "secure-ns/sample-rsp", r.MatchedProfile)
+		}
+	}
+	tampered := results[2]
+	if !tampered.DryRunSkipped || !strings.Contains(tampered.Diff, "data.key1") {
+		t.Errorf("Verify Failed\nexpected: dryRunSkipped with diff of data.key1\nactual: dryRunSkipped=%t, diff=%s", tampered.DryRunSkipped, tampered.Diff)
+	}
+	if results[0].Signer != "signer@enterprise.com" {
+		t.Errorf("Verify Failed\nexpected: %s\nactual: %s", "signer@enterprise.com", results[0].Signer)
+	}
+}
+
+func TestNewCreateRequest(t *testing.T) {
+	docs, _ := decodeDocuments([]byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"))
+	req, err := newCreateRequest(docs[0], "default-ns")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if req.Kind.Group != "apps" || req.Kind.Version != "v1" || req.Namespace != "default-ns" || req.Operation != "CREATE" {
+		t.Errorf("NewCreateRequest Failed\nexpected: %s\nactual: %s/%s %s %s", "apps/v1 default-ns CREATE", req.Kind.Group, req.Kind.Version, req.Namespace, req.Operation)
+	}
+}
@@ -32,6 +32,7 @@ const (
 	SignatureTypeResource         string = "resource"
 	SignatureTypeApplyingResource string = "applyingResource"
 	SignatureTypePatch            string = "patch"
+	SignatureTypeDelete           string = "delete"
 	// SignatureTypeHelm string = "helm"
 )
 
@@ -88,6 +89,10 @@ func (ss *ResourceSignature) FindSignature(apiVersion, kind, name, namespace str
 func (ss *ResourceSignature) FindSignItem(apiVersion, kind, name, namespace string) (*SignItem, []byte, bool) {
 	signItem := &SignItem{}
 	for _, si := range ss.Spec.Data {
+		// deletion approval is not a signature for the resource itself
+		if si.Type == SignatureTypeDelete {
+			continue
+		}
 		if found, singleYamlBytes := ishieldyaml.FindSingleYaml([]byte(si.Message), apiVersion, kind, name, namespace); found {
 			return si, singleYamlBytes, true
 		}
@@ -95,6 +100,22 @@ func (ss *ResourceSignature) FindSignItem(apiVersion, kind, name, namespace stri
 	return signItem, nil, false
 }
 
+// FindSignItems returns all SignItems for the object, which are used as signatures by multiple signers.
+func (ss *ResourceSignature) FindSignItems(apiVersion, kind, name, namespace string) ([]*SignItem, [][]byte) {
+	signItems := []*SignItem{}
+	yamlBytesList := [][]byte{}
+	for _, si := range ss.Spec.Data {
+		if si.Type == SignatureTypeDelete {
+			continue
+		}
+		if found, singleYamlBytes := ishieldyaml.FindSingleYaml([]byte(si.Message), apiVersion, kind, name, namespace); found {
+			signItems = append(signItems, si)
+			yamlBytesList = append(yamlBytesList, singleYamlBytes)
+		}
+	}
+	return signItems, yamlBytesList
+}
+
 func (ss *ResourceSignature) Validate() (bool, string) {
 	if ss == nil {
 		return false, "ResourceSignature Validation failed. ss is nil."
@@ -153,6 +174,26 @@ func (ssl *ResourceSignatureList) FindSignItem(apiVersion, kind, name, namespace
 	return false, signItem, nil, ""
 }
 
+// FoundSignItem is a SignItem found in ResourceSignatureList
+// +k8s:deepcopy-gen=false
+type FoundSignItem struct {
+	SignItem             *SignItem
+	YamlBytes            []byte
+	ResourceSignatureUID string
+}
+
+// FindSignItems returns SignItems for the object in all ResourceSignatures.
+func (ssl *ResourceSignatureList) FindSignItems(apiVersion, kind, name, namespace string) []FoundSignItem {
+	found := []FoundSignItem{}
+	for _, ss := range ssl.Items {
+		signItems, yamlBytesList := ss.FindSignItems(apiVersion, kind, name, namespace)
+		for i, si := range signItems {
+			found = append(found, FoundSignItem{SignItem: si, YamlBytes: yamlBytesList[i], ResourceSignatureUID: string(ss.GetUID())})
+		}
+	}
+	return found
+}
+
 type SignItem struct {
 	Message      string `json:"message,omitempty"`
 	MessageScope string `json:"messageScope,omitempty"`
@@ -160,6 +201,8 @@ type SignItem struct {
 	Signature    string `json:"signature"`
 	Certificate  string `json:"certificate"`
 	Type         string `json:"type"`
+	// transparency log bundle of keyless signature
+	Bundle string `json:"bundle,omitempty"`
 }
 
 type ResourceInfo struct {
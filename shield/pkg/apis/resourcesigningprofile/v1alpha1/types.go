@@ -30,6 +30,10 @@ const maxHistoryLength = 3
 // ResourceSigningProfileSpec defines the desired state of AppEnforcePolicy
 type ResourceSigningProfileSpec struct {
 	Disabled bool `json:"disabled,omitempty"`
+	// `Mode` overrides ShieldConfig.Mode for requests denied by this profile (enforce, detect or warn)
+	Mode common.IntegrityShieldMode `json:"mode,omitempty"`
+	// `ProtectDelete` requires a signed deletion approval for DELETE requests, which are allowed without any check otherwise
+	ProtectDelete bool `json:"protectDelete,omitempty"`
 	// `TargetNamespaceSelector` is used only for profile in iShield NS
 	TargetNamespaceSelector *common.NamespaceSelector  `json:"targetNamespaceSelector,omitempty"`
 	ProtectRules            []*common.Rule             `json:"protectRules,omitempty"`
@@ -39,6 +43,10 @@ type ResourceSigningProfileSpec struct {
 	ProtectAttrs            []*common.AttrsPattern     `json:"protectAttrs,omitempty"`
 	UnprotectAttrs          []*common.AttrsPattern     `json:"unprotectAttrs,omitempty"`
 	IgnoreAttrs             []*common.AttrsPattern     `json:"ignoreAttrs,omitempty"`
+	// `ServiceAccountPatterns` allows requests by the service accounts without signature
+	ServiceAccountPatterns []*common.ServiceAccountPattern `json:"serviceAccountPatterns,omitempty"`
+	// `OwnerCheck` allows requests by controllers for resources owned by a verified resource
+	OwnerCheck *common.OwnerCheck `json:"ownerCheck,omitempty"`
 }
 
 // ResourceSigningProfileStatus defines the observed state of AppEnforcePolicy
@@ -54,8 +62,9 @@ type ProfileStatusSummary struct {
 }
 
 type ProfileStatusDetail struct {
-	Request *common.Request `json:"request,omitempty"`
-	Result  *common.Result  `json:"result,omitempty"`
+	Request *common.Request            `json:"request,omitempty"`
+	Result  *common.Result             `json:"result,omitempty"`
+	Mode    common.IntegrityShieldMode `json:"mode,omitempty"`
 }
 
 // +genclient
@@ -80,7 +89,9 @@ func (self ResourceSigningProfile) IsEmpty() bool {
 	return len(self.Spec.ProtectRules) == 0
 }
 
-func (self ResourceSigningProfile) Match(reqFields map[string]string, iShieldNS string) (bool, *common.Rule) {
+// Match returns true and the matched rule if the request is protected by this profile.
+// `vars` are the variables of conditions decoded from the request, which are shared among profiles.
+func (self ResourceSigningProfile) Match(reqFields map[string]string, vars *common.ConditionVars, iShieldNS string) (bool, *common.Rule) {
 
 	rspNS := self.ObjectMeta.Namespace
 
@@ -94,24 +105,20 @@ func (self ResourceSigningProfile) Match(reqFields map[string]string, iShieldNS
 		strictMatch = true
 	}
 
+	// a condition which cannot be evaluated is regarded as matched for the rules to protect resources,
+	// and not matched for the rules to allow requests
 	for _, rule := range self.Spec.ForceCheckRules {
-		if strictMatch && rule.StrictMatchWithRequest(reqFields) {
-			return true, rule
-		} else if !strictMatch && rule.MatchWithRequest(reqFields) {
+		if matchRule(rule, reqFields, vars, strictMatch, true) {
 			return true, rule
 		}
 	}
 	for _, rule := range self.Spec.IgnoreRules {
-		if strictMatch && rule.StrictMatchWithRequest(reqFields) {
-			return false, rule
-		} else if !strictMatch && rule.MatchWithRequest(reqFields) {
+		if rule.IgnoreMatchWithRequest(reqFields, strictMatch) && rule.MatchCondition(vars, false) {
 			return false, rule
 		}
 	}
 	for _, rule := range self.Spec.ProtectRules {
-		if strictMatch && rule.StrictMatchWithRequest(reqFields) {
-			return true, rule
-		} else if !strictMatch && rule.MatchWithRequest(reqFields) {
+		if matchRule(rule, reqFields, vars, strictMatch, true) {
 			return true, rule
 		}
 	}
@@ -119,6 +126,16 @@ func (self ResourceSigningProfile) Match(reqFields map[string]string, iShieldNS
 	return false, nil
 }
 
+func matchRule(rule *common.Rule, reqFields map[string]string, vars *common.ConditionVars, strictMatch, onConditionError bool) bool {
+	matched := false
+	if strictMatch {
+		matched = rule.StrictMatchWithRequest(reqFields)
+	} else {
+		matched = rule.MatchWithRequest(reqFields)
+	}
+	return matched && rule.MatchCondition(vars, onConditionError)
+}
+
 func (self ResourceSigningProfile) Merge(another ResourceSigningProfile) ResourceSigningProfile {
 	newProfile := self
 	newProfile.Spec.ProtectRules = append(newProfile.Spec.ProtectRules, another.Spec.ProtectRules...)
@@ -139,33 +156,33 @@ func (self ResourceSigningProfile) Kustomize(reqFields map[string]string) []*com
 	return patterns
 }
 
-func (self ResourceSigningProfile) ProtectAttrs(reqFields map[string]string) []*common.AttrsPattern {
+func (self ResourceSigningProfile) ProtectAttrs(reqFields map[string]string, vars *common.ConditionVars) []*common.AttrsPattern {
 	patterns := []*common.AttrsPattern{}
 	for _, attrsPattern := range self.Spec.ProtectAttrs {
-		if attrsPattern.MatchWith(reqFields) {
+		if attrsPattern.MatchWith(reqFields) && attrsPattern.MatchCondition(vars, true) {
 			patterns = append(patterns, attrsPattern)
 		}
 	}
 	return patterns
 }
 
-func (self ResourceSigningProfile) IgnoreAttrs(reqFields map[string]string) []*common.AttrsPattern {
+func (self ResourceSigningProfile) IgnoreAttrs(reqFields map[string]string, vars *common.ConditionVars) []*common.AttrsPattern {
 	patterns := []*common.AttrsPattern{}
 	for _, attrsPattern := range self.Spec.IgnoreAttrs {
-		if attrsPattern.MatchWith(reqFields) {
+		if attrsPattern.MatchExistingWith(reqFields) && attrsPattern.MatchCondition(vars, false) {
 			patterns = append(patterns, attrsPattern)
 		}
 	}
 	// `UnprotectAttrs` is deprecated, but keep this for backward compatibility
 	for _, attrsPattern := range self.Spec.UnprotectAttrs {
-		if attrsPattern.MatchWith(reqFields) {
+		if attrsPattern.MatchExistingWith(reqFields) && attrsPattern.MatchCondition(vars, false) {
 			patterns = append(patterns, attrsPattern)
 		}
 	}
 	return patterns
 }
 
-func (self *ResourceSigningProfile) UpdateStatus(request *common.Request, errMsg string) *ResourceSigningProfile {
+func (self *ResourceSigningProfile) UpdateStatus(request *common.Request, errMsg string, mode common.IntegrityShieldMode) *ResourceSigningProfile {
 
 	// Increment DenyCount
 	self.Status.DenyCount = self.Status.DenyCount + 1
@@ -196,7 +213,7 @@ func (self *ResourceSigningProfile) UpdateStatus(request *common.Request, errMsg
 		Timestamp: time.Now().UTC().Format(layout),
 	}
 	newLatestEvents := []*ProfileStatusDetail{}
-	newSingleEvent := &ProfileStatusDetail{Request: request, Result: result}
+	newSingleEvent := &ProfileStatusDetail{Request: request, Result: result, Mode: mode}
 	newLatestEvents = append(newLatestEvents, newSingleEvent)
 	newLatestEvents = append(newLatestEvents, self.Status.Latest...)
 	if len(newLatestEvents) > maxHistoryLength {
@@ -204,6 +204,20 @@ func (in *ResourceSigningProfileSpec) DeepCopyInto(out *ResourceSigningProfileSp
 			}
 		}
 	}
+	if in.ServiceAccountPatterns != nil {
+		in, out := &in.ServiceAccountPatterns, &out.ServiceAccountPatterns
+		*out = make([]*common.ServiceAccountPattern, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = (*in).DeepCopy()
+			}
+		}
+	}
+	if in.OwnerCheck != nil {
+		in, out := &in.OwnerCheck, &out.OwnerCheck
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -0,0 +1,69 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package v1alpha1
+
+import (
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SignatureRevocationSpec defines revoked signatures, ResourceSignatures and signers
+type SignatureRevocationSpec struct {
+	common.RevocationList `json:",inline"`
+	// why these signatures are revoked (e.g. incident ID)
+	Reason string `json:"reason,omitempty"`
+}
+
+// SignatureRevocationStatus defines the observed state of SignatureRevocation
+type SignatureRevocationStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +resource:path=signaturerevocation,scope=Namespaced
+
+// SignatureRevocation is the CRD. Only instances in IShield namespace are used for signature verification.
+type SignatureRevocation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SignatureRevocationSpec   `json:"spec,omitempty"`
+	Status SignatureRevocationStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SignatureRevocationList contains a list of SignatureRevocation
+type SignatureRevocationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SignatureRevocation `json:"items"`
+}
+
+// GetRevocationList returns all revocations in the list
+func (self *SignatureRevocationList) GetRevocationList() *common.RevocationList {
+	revocations := &common.RevocationList{}
+	if self == nil {
+		return revocations
+	}
+	for _, item := range self.Items {
+		revocations = revocations.Merge(&item.Spec.RevocationList)
+	}
+	return revocations
+}
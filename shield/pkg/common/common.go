@@ -19,7 +19,9 @@ package common
 import (
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/IBM/integrity-enforcer/shield/pkg/util/kubeutil"
 	"github.com/jinzhu/copier"
@@ -40,6 +42,9 @@ const (
 	SignerConfigCustomResourceAPIVersion = "apis.integrityshield.io/v1alpha1"
 	SignerConfigCustomResourceKind       = "SignerConfig"
 
+	SignatureRevocationCustomResourceAPIVersion = "apis.integrityshield.io/v1alpha1"
+	SignatureRevocationCustomResourceKind       = "SignatureRevocation"
+
 	ProfileCustomResourceAPIVersion = "apis.integrityshield.io/v1alpha1"
 	ProfileCustomResourceKind       = "ResourceSigningProfile"
 
@@ -59,11 +64,22 @@ const (
 	SignatureTypeAnnotationKey = "integrityshield.io/signatureType"
 	MessageScopeAnnotationKey  = "integrityshield.io/messageScope"
 	MutableAttrsAnnotationKey  = "integrityshield.io/mutableAttrs"
+	BundleAnnotationKey        = "integrityshield.io/bundle"
+	NotBeforeAnnotationKey     = "integrityshield.io/notBefore"
+	NotAfterAnnotationKey      = "integrityshield.io/notAfter"
+
+	DeleteSignatureAnnotationKey   = "integrityshield.io/deleteSignature"
+	DeleteMessageAnnotationKey     = "integrityshield.io/deleteMessage"
+	DeleteCertificateAnnotationKey = "integrityshield.io/deleteCertificate"
 
 	ResSigLabelApiVer = "integrityshield.io/sigobject-apiversion"
 	ResSigLabelKind   = "integrityshield.io/sigobject-kind"
 	ResSigLabelTime   = "integrityshield.io/sigtime"
 
+	// labels of Secrets and ConfigMaps which include verification keys
+	KeyConfigLabelKey     = "integrityshield.io/keyConfig"
+	SignatureTypeLabelKey = "integrityshield.io/signatureType"
+
 	LabelValueVerified   = "verified"
 	LabelValueUnverified = "unverified"
 )
@@ -74,6 +90,7 @@ const (
 
 	EventTypeValueReconcileReport = "reconcile-report"
 	EventTypeValueVerifyResult    = "verify-result"
+	EventTypeValueBreakGlass      = "break-glass"
 	EventResultValueAllow         = "allow"
 	EventResultValueDeny          = "deny"
 )
@@ -84,6 +101,8 @@ const (
 	SignatureTypeDefault = ""
 	SignatureTypePGP     = "pgp"
 	SignatureTypeX509    = "x509"
+	// keyless signature with a short-lived certificate and a transparency log entry in cosign format
+	SignatureTypeSigstore = "sigstore"
 )
 
 type DecisionType string
@@ -175,6 +194,17 @@ func (self *ResourceRef) EqualsWithoutVersionCheck(ref *ResourceRef) bool {
 		self.Kind == ref.Kind)
 }
 
+// AnyResourceName can be used as the name of ResourceRef to match with all resources of the kind in the namespace
+const AnyResourceName = "*"
+
+// MatchWithoutVersionCheck is the same as EqualsWithoutVersionCheck, but AnyResourceName matches with any name
+func (self *ResourceRef) MatchWithoutVersionCheck(ref *ResourceRef) bool {
+	return (ref != nil &&
+		(self.Name == ref.Name || self.Name == AnyResourceName) &&
+		self.Namespace == ref.Namespace &&
+		self.Kind == ref.Kind)
+}
+
 /**********************************************
 
                 CheckError
@@ -217,6 +247,14 @@ func NewResourceLabel(values map[string]string) *ResourceLabel {
 	}
 }
 
+// Values returns all labels
+func (self *ResourceLabel) Values() map[string]string {
+	if self == nil {
+		return nil
+	}
+	return self.values
+}
+
 func (self *ResourceLabel) IntegrityVerified() bool {
 	return self.getString(ResourceIntegrityLabelKey) == LabelValueVerified
 }
@@ -262,6 +300,23 @@ type SignatureAnnotation struct {
 	Message       string
 	MessageScope  string
 	MutableAttrs  string
+	Bundle        string
+	// validity in the annotations of the object. this is signed only when it is included in `MessageScope`.
+	Validity SignatureValidity
+}
+
+func NewResourceAnnotation(values map[string]string) *ResourceAnnotation {
+	return &ResourceAnnotation{
+		values: values,
+	}
+}
+
+// Values returns all annotations
+func (self *ResourceAnnotation) Values() map[string]string {
+	if self == nil {
+		return nil
+	}
+	return self.values
 }
 
 func (self *ResourceAnnotation) SignatureAnnotations() *SignatureAnnotation {
@@ -272,9 +327,59 @@ func (self *ResourceAnnotation) SignatureAnnotations() *SignatureAnnotation {
 		Message:       self.getString(MessageAnnotationKey),
 		MessageScope:  self.getString(MessageScopeAnnotationKey),
 		MutableAttrs:  self.getString(MutableAttrsAnnotationKey),
+		Bundle:        self.getString(BundleAnnotationKey),
+		Validity: SignatureValidity{
+			NotBefore: self.getString(NotBeforeAnnotationKey),
+			NotAfter:  self.getString(NotAfterAnnotationKey),
+		},
+	}
+}
+
+// DeleteApprovalAnnotations returns a signed deletion approval attached to the object
+func (self *ResourceAnnotation) DeleteApprovalAnnotations() *SignatureAnnotation {
+	return &SignatureAnnotation{
+		Signature:   self.getString(DeleteSignatureAnnotationKey),
+		Certificate: self.getString(DeleteCertificateAnnotationKey),
+		Message:     self.getString(DeleteMessageAnnotationKey),
 	}
 }
 
+// MultiSignatureAnnotations returns the signature annotation and additional ones for multiple signers.
+// Additional signatures are set to annotations with numbered suffix like `integrityshield.io/signature.1`
+// (and `integrityshield.io/certificate.1`, `integrityshield.io/bundle.1` if needed), and share the other annotations such as message.
+func (self *ResourceAnnotation) MultiSignatureAnnotations() []*SignatureAnnotation {
+	annotations := []*SignatureAnnotation{}
+	base := self.SignatureAnnotations()
+	if base.Signature != "" {
+		annotations = append(annotations, base)
+	}
+	prefix := SignatureAnnotationKey + "."
+	suffixes := []int{}
+	for key := range self.values {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if num, err := strconv.Atoi(strings.TrimPrefix(key, prefix)); err == nil && num > 0 {
+			suffixes = append(suffixes, num)
+		}
+	}
+	sort.Ints(suffixes)
+	for _, num := range suffixes {
+		suffix := fmt.Sprintf(".%d", num)
+		annotations = append(annotations, &SignatureAnnotation{
+			Signature:     self.getString(SignatureAnnotationKey + suffix),
+			SignatureType: base.SignatureType,
+			Certificate:   self.getString(CertificateAnnotationKey + suffix),
+			Message:       base.Message,
+			MessageScope:  base.MessageScope,
+			MutableAttrs:  base.MutableAttrs,
+			Bundle:        self.getString(BundleAnnotationKey + suffix),
+			Validity:      base.Validity,
+		})
+	}
+	return annotations
+}
+
 func (self *ResourceAnnotation) getString(key string) string {
 	if s, ok := self.values[key]; ok {
 		return s
@@ -312,6 +417,9 @@ type SignatureEvalResult struct {
 	Allow                bool        `json:"allow"`
 	MatchedSignerConfig  string      `json:"matchedSignerConfig"`
 	ResourceSignatureUID string      `json:"resourceSignatureUID"`
+	SignatureSource      string      `json:"signatureSource"`
+	Diff                 string      `json:"diff,omitempty"`
+	DryRunSkipped        bool        `json:"dryRunSkipped,omitempty"`
 	Error                *CheckError `json:"error"`
 }
 
@@ -340,6 +448,9 @@ type SignerInfo struct {
 	CommonName         string
 	SerialNumber       *big.Int
 	Fingerprint        []byte
+	// OIDC identity and issuer of keyless signature
+	Identity string
+	Issuer   string
 }
 
 func (self *SignerInfo) GetName() string {
@@ -352,6 +463,9 @@ func (self *SignerInfo) GetName() string {
 	if self.Name != "" {
 		return self.Name
 	}
+	if self.Identity != "" {
+		return self.Identity
+	}
 	return ""
 }
 
@@ -401,6 +515,10 @@ const (
 	REASON_NO_MATCH_SIGNER_CONFIG
 	REASON_UNEXPECTED
 	REASON_ERROR
+	REASON_WARN
+	REASON_INVALID_CERT
+	REASON_EXPIRED_SIG
+	REASON_REVOKED_SIG
 )
 
 var ReasonCodeMap = map[int]ReasonCode{
@@ -496,4 +614,20 @@ var ReasonCodeMap = map[int]ReasonCode{
 		Message: "error",
 		Code:    "error",
 	},
+	REASON_WARN: {
+		Message: "allowed by warn mode",
+		Code:    "warn",
+	},
+	REASON_INVALID_CERT: {
+		Message: "Signature verification is required for this request, but the signer certificate is revoked or expired",
+		Code:    "invalid-certificate",
+	},
+	REASON_EXPIRED_SIG: {
+		Message: "Signature verification is required for this request, but the signature is not valid at this time",
+		Code:    "expired-signature",
+	},
+	REASON_REVOKED_SIG: {
+		Message: "Signature verification is required for this request, but the signature is revoked",
+		Code:    "revoked-signature",
+	},
 }
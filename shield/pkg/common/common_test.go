@@ -70,3 +70,96 @@ func TestResourceRef(t *testing.T) {
 		return
 	}
 }
+
+func TestSubjectConditionKeyless(t *testing.T) {
+	cond := &SubjectCondition{
+		Name:      "ci",
+		KeyConfig: "sigstore-keys",
+		Subject: SubjectMatchPattern{
+			Identity: "https://github.com/example/repo/*",
+			Issuer:   "https://token.actions.githubusercontent.com",
+		},
+	}
+	signer := &SignerInfo{
+		Identity: "https://github.com/example/repo/.github/workflows/release.yaml@refs/heads/main",
+		Issuer:   "https://token.actions.githubusercontent.com",
+	}
+	if ok := cond.Match(signer); !ok {
+		t.Error("TestSubjectConditionKeyless() Failed")
+		return
+	}
+	signer.Issuer = "https://accounts.google.com"
+	if ok := cond.Match(signer); ok {
+		t.Error("TestSubjectConditionKeyless() Failed")
+		return
+	}
+	// a subject without `issuer` must not match any keyless signer
+	signer.Issuer = "https://token.actions.githubusercontent.com"
+	cond.Subject.Issuer = ""
+	if ok := cond.Match(signer); ok {
+		t.Error("TestSubjectConditionKeyless() Failed")
+		return
+	}
+}
+
+func TestMatchMultiSigners(t *testing.T) {
+	sigConf := &SignerConfig{
+		Policies: []SignerConfigCondition{
+			{Namespaces: []string{"*"}, Signers: []string{"alice", "bob", "carol"}, MinSigners: 2, RequiredSigners: []string{"alice"}},
+		},
+		Signers: []SignerCondition{
+			{Name: "alice", KeyConfig: "team-keys", Subjects: []SubjectMatchPattern{{Email: "alice@example.com"}}},
+			{Name: "bob", KeyConfig: "team-keys", Subjects: []SubjectMatchPattern{{Email: "bob@example.com"}}},
+			{Name: "carol", KeyConfig: "team-keys", Subjects: []SubjectMatchPattern{{Email: "d*"}}},
+		},
+	}
+	signer := func(email string) VerifiedSigner {
+		return VerifiedSigner{Signer: &SignerInfo{Email: email}, VerifiedKeyConfigs: []string{"team-keys"}}
+	}
+
+	testCases := []struct {
+		name     string
+		signers  []VerifiedSigner
+		expected bool
+		matched  int
+	}{
+		{"required and another signer", []VerifiedSigner{signer("alice@example.com"), signer("bob@example.com")}, true, 2},
+		{"required signer only", []VerifiedSigner{signer("alice@example.com")}, false, 1},
+		{"same signer twice", []VerifiedSigner{signer("alice@example.com"), signer("alice@example.com")}, false, 1},
+		{"without required signer", []VerifiedSigner{signer("bob@example.com"), signer("dave@example.com")}, false, 2},
+	}
+	for _, tc := range testCases {
+		ok, spc, names := sigConf.MatchMultiSigners("sample-ns", tc.signers)
+		if ok != tc.expected || len(names) != tc.matched {
+			t.Errorf("TestMatchMultiSigners() Failed: %s\nexpected: %v, %d signers\nactual: %v, %v", tc.name, tc.expected, tc.matched, ok, names)
+		}
+		if spc == nil || spc.NumOfRequiredSigners() != 2 {
+			t.Errorf("TestMatchMultiSigners() Failed: %s; the closest policy is not returned", tc.name)
+		}
+	}
+}
+
+func TestMultiSignatureAnnotations(t *testing.T) {
+	rsigAnnotation := &ResourceAnnotation{
+		values: map[string]string{
+			SignatureAnnotationKey:          "sig0",
+			SignatureAnnotationKey + ".2":   "sig2",
+			SignatureAnnotationKey + ".1":   "sig1",
+			CertificateAnnotationKey + ".1": "cert1",
+			MessageAnnotationKey:            "msg",
+		},
+	}
+	annotations := rsigAnnotation.MultiSignatureAnnotations()
+	if len(annotations) != 3 {
+		t.Errorf("TestMultiSignatureAnnotations() Failed\nexpected: 3 signatures\nactual: %d signatures", len(annotations))
+		return
+	}
+	for i, expected := range []string{"sig0", "sig1", "sig2"} {
+		if annotations[i].Signature != expected || annotations[i].Message != "msg" {
+			t.Errorf("TestMultiSignatureAnnotations() Failed\nexpected: %s\nactual: %s", expected, annotations[i].Signature)
+		}
+	}
+	if annotations[1].Certificate != "cert1" {
+		t.Errorf("TestMultiSignatureAnnotations() Failed\nexpected: cert1\nactual: %s", annotations[1].Certificate)
+	}
+}
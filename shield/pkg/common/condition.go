@@ -0,0 +1,167 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	"github.com/google/cel-go/cel"
+	log "github.com/sirupsen/logrus"
+)
+
+/**********************************************
+
+				Condition Functions
+
+***********************************************/
+
+// variables which can be used in the CEL expression of conditions
+const (
+	// ConditionVarObject is the requested object (null for DELETE)
+	ConditionVarObject = "object"
+	// ConditionVarOldObject is the existing object (null for CREATE)
+	ConditionVarOldObject = "oldObject"
+	// ConditionVarRequest is the admission request, e.g. `request.userInfo.username` and `request.operation`
+	ConditionVarRequest = "request"
+)
+
+// max cost of evaluating a condition, so that a condition cannot block admission requests for a long time
+const conditionCostLimit = 1000000
+
+var (
+	conditionEnv     *cel.Env
+	conditionEnvErr  error
+	conditionEnvOnce sync.Once
+	conditionCache   sync.Map
+)
+
+func getConditionEnv() (*cel.Env, error) {
+	conditionEnvOnce.Do(func() {
+		conditionEnv, conditionEnvErr = cel.NewEnv(
+			cel.Variable(ConditionVarObject, cel.DynType),
+			cel.Variable(ConditionVarOldObject, cel.DynType),
+			cel.Variable(ConditionVarRequest, cel.DynType),
+		)
+	})
+	return conditionEnv, conditionEnvErr
+}
+
+// CompileCondition compiles the CEL expression of a condition. Compiled programs are cached by the expression,
+// so an expression is compiled only once.
+func CompileCondition(expr string) (cel.Program, error) {
+	expr = strings.TrimSpace(expr)
+	if cached, ok := conditionCache.Load(expr); ok {
+		return cached.(cel.Program), nil
+	}
+	env, err := getConditionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss != nil && iss.Err() != nil {
+		return nil, fmt.Errorf("failed to compile condition `%s`; %s", expr, iss.Err().Error())
+	}
+	if !cel.BoolType.IsAssignableType(ast.OutputType()) {
+		return nil, fmt.Errorf("condition `%s` must return bool, but returns %s", expr, ast.OutputType().String())
+	}
+	prg, err := env.Program(ast, cel.CostLimit(conditionCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile condition `%s`; %s", expr, err.Error())
+	}
+	conditionCache.Store(expr, prg)
+	return prg, nil
+}
+
+// ConditionVars are the variables of conditions decoded from the admission request.
+// The request is decoded once per request, and the variables are shared by all conditions evaluated for it.
+type ConditionVars struct {
+	vars map[string]interface{}
+	err  error
+}
+
+// NewConditionVars decodes the admission request (JSON) into the variables of conditions.
+// A decode error is returned when a condition is evaluated with the variables.
+func NewConditionVars(requestJson string) *ConditionVars {
+	request := map[string]interface{}{}
+	if requestJson != "" {
+		if err := json.Unmarshal([]byte(requestJson), &request); err != nil {
+			return &ConditionVars{err: fmt.Errorf("failed to decode request for condition; %s", err.Error())}
+		}
+	}
+	return &ConditionVars{
+		vars: map[string]interface{}{
+			ConditionVarObject:    request["object"],
+			ConditionVarOldObject: request["oldObject"],
+			ConditionVarRequest:   request,
+		},
+	}
+}
+
+// EvalCondition evaluates the CEL expression with the variables decoded from the admission request.
+// An empty expression is always true.
+func EvalCondition(expr string, vars *ConditionVars) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+	prg, err := CompileCondition(expr)
+	if err != nil {
+		return false, err
+	}
+	if vars == nil {
+		vars = NewConditionVars("")
+	}
+	if vars.err != nil {
+		return false, vars.err
+	}
+	out, _, err := prg.Eval(vars.vars)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition `%s`; %s", expr, err.Error())
+	}
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition `%s` returned non-bool value `%v`", expr, out.Value())
+	}
+	return result, nil
+}
+
+// matchCondition returns the result of the condition, or `onError` if it cannot be evaluated
+// (e.g. true for rules to protect resources and false for rules to allow requests).
+func matchCondition(expr string, vars *ConditionVars, onError bool) bool {
+	matched, err := EvalCondition(expr, vars)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			"err":       err,
+			"condition": expr,
+		}).Warn("Error when evaluating condition, so it is regarded as ", onError)
+		return onError
+	}
+	return matched
+}
+
+func validateCondition(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	if _, err := CompileCondition(expr); err != nil {
+		return fmt.Errorf("condition: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	cmCreate := `{"operation":"CREATE","userInfo":{"username":"alice"},"object":{"kind":"ConfigMap","data":{"key1":"val1"}}}`
+	deployUpdate := `{"operation":"UPDATE","userInfo":{"username":"system:serviceaccount:kube-system:horizontal-pod-autoscaler"},` +
+		`"object":{"kind":"Deployment","spec":{"replicas":3}},"oldObject":{"kind":"Deployment","spec":{"replicas":2}}}`
+	testCases := []struct {
+		condition string
+		request   string
+		expected  bool
+		err       bool
+	}{
+		{"", cmCreate, true, false},
+		{"has(object.data.key1)", cmCreate, true, false},
+		{"has(object.data.key2)", cmCreate, false, false},
+		{"'key1' in object.data && object.data.key1 == 'val1'", cmCreate, true, false},
+		{"request.userInfo.username == 'alice' && request.operation == 'CREATE'", cmCreate, true, false},
+		{"request.userInfo.username.endsWith(':horizontal-pod-autoscaler') && object.spec.replicas != oldObject.spec.replicas", deployUpdate, true, false},
+		{"object.spec.replicas == 3", deployUpdate, true, false},
+		{"object.data.key2 == 'val2'", cmCreate, false, true},
+		{"object.data", cmCreate, false, true},
+		{"object.data.(", cmCreate, false, true},
+	}
+	for _, tc := range testCases {
+		actual, err := EvalCondition(tc.condition, NewConditionVars(tc.request))
+		if tc.err {
+			if err == nil {
+				t.Errorf("TestEvalCondition() Failed\ncondition: %s\nexpected: error\nactual: %v", tc.condition, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("TestEvalCondition() Failed\ncondition: %s\nexpected: %v\nactual: error %s", tc.condition, tc.expected, err.Error())
+		} else if actual != tc.expected {
+			t.Errorf("TestEvalCondition() Failed\ncondition: %s\nexpected: %v\nactual: %v", tc.condition, tc.expected, actual)
+		}
+	}
+}
+
+func TestRuleMatchCondition(t *testing.T) {
+	reqFields := map[string]string{
+		"Kind":              "ConfigMap",
+		ReqFieldRequestJson: `{"operation":"CREATE","object":{"kind":"ConfigMap","data":{"key1":"val1"}}}`,
+	}
+	kind := RulePattern("ConfigMap")
+	rule := &Rule{Match: []*RequestPattern{{Kind: &kind}}, Condition: "has(object.data.key1)"}
+	vars := NewConditionVars(reqFields[ReqFieldRequestJson])
+	if !rule.MatchWithRequest(reqFields) || !rule.MatchCondition(vars, false) {
+		t.Errorf("TestRuleMatchCondition() Failed\nexpected: true\nactual: false")
+	}
+	rule.Condition = "object.data.key2 == 'val2'"
+	if rule.MatchCondition(vars, false) {
+		t.Errorf("TestRuleMatchCondition() Failed\nexpected: false for error with onError=false\nactual: true")
+	}
+	if !rule.MatchCondition(vars, true) {
+		t.Errorf("TestRuleMatchCondition() Failed\nexpected: true for error with onError=true\nactual: false")
+	}
+	if err := rule.Validate(); err != nil {
+		t.Errorf("TestRuleMatchCondition() Failed\nexpected: valid\nactual: %s", err.Error())
+	}
+	rule.Condition = "object.data"
+	if err := rule.Validate(); err == nil {
+		t.Errorf("TestRuleMatchCondition() Failed\nexpected: error for non-bool condition\nactual: nil")
+	}
+}
+
+func TestReqContextConditionVars(t *testing.T) {
+	reqc := &ReqContext{RequestJsonStr: `{"operation":"CREATE","object":{"kind":"ConfigMap","data":{"key1":"val1"}}}`}
+	vars := reqc.ConditionVars()
+	// the request is decoded only once and shared by the conditions
+	if reqc.ConditionVars() != vars {
+		t.Errorf("TestReqContextConditionVars() Failed\nexpected: the same variables for a request\nactual: decoded again")
+	}
+	if _, ok := reqc.Map()["conditionVars"]; ok {
+		t.Errorf("TestReqContextConditionVars() Failed\nexpected: no conditionVars in request fields\nactual: %v", reqc.Map())
+	}
+	if matched, err := EvalCondition("object.data.key1 == 'val1'", vars); err != nil || !matched {
+		t.Errorf("TestReqContextConditionVars() Failed\nexpected: true\nactual: %v, %v", matched, err)
+	}
+	if _, err := EvalCondition("has(object.data)", NewConditionVars("{invalid")); err == nil {
+		t.Errorf("TestReqContextConditionVars() Failed\nexpected: error for invalid request\nactual: nil")
+	}
+}
@@ -0,0 +1,50 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VerificationKey is a set of key files for verifying signatures, which is identified by the name of keyConfig
+// and the signature type. Files are a keyring for pgp, CA certificates and CRLs for x509, and Fulcio certificates
+// and Rekor public key for sigstore. Source tells where the files are loaded from (e.g. a mounted path or a Secret).
+type VerificationKey struct {
+	KeyConfig string
+	Type      SignatureType
+	Source    string
+	Files     map[string][]byte
+}
+
+func (self VerificationKey) ID() string {
+	return fmt.Sprintf("%s/%s", self.KeyConfig, self.Type)
+}
+
+func (self VerificationKey) String() string {
+	return fmt.Sprintf("%s (%s)", self.ID(), self.Source)
+}
+
+// FileNames returns the names of key files in sorted order
+func (self VerificationKey) FileNames() []string {
+	names := []string{}
+	for name := range self.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
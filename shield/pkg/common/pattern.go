@@ -17,9 +17,14 @@
 package common
 
 import (
+	"fmt"
 	"math/big"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 /**********************************************
@@ -28,24 +33,135 @@ import (
 
 ***********************************************/
 
+const (
+	// RegexPatternPrefix is the prefix of a pattern which is a regular expression (e.g. `re:^app-[0-9]+$`)
+	RegexPatternPrefix = "re:"
+	// NegationPatternPrefix is the prefix of a pattern which matches values not matching the rest (e.g. `!kube-*`)
+	NegationPatternPrefix = "!"
+)
+
+var regexpCache sync.Map
+
+// MatchPattern returns true if the value matches with the pattern.
+// A pattern with `re:` prefix is a regular expression which must match the whole value (e.g. `re:app-[0-9]+`).
+// Otherwise, it is a comma separated list of glob patterns where `*` matches any characters, `?` matches a single character
+// and `-` matches an empty value (e.g. `*-config`). The value must match one of them and none of ones negated by `!` prefix,
+// so `!kube-*,!openshift-*` matches any value (including an empty value) except ones with these prefixes.
 func MatchPattern(pattern, value string) bool {
 	pattern = strings.TrimSpace(pattern)
 	if pattern == "" {
 		return true
-	} else if pattern == "*" {
-		return true
-	} else if pattern == "-" && value == "" {
-		return true
-	} else if strings.HasSuffix(pattern, "*") {
-		return strings.HasPrefix(value, strings.TrimRight(pattern, "*"))
-	} else if pattern == value {
-		return true
-	} else if strings.Contains(pattern, ",") {
-		patterns := SplitRule(pattern)
-		return MatchWithPatternArray(value, patterns)
-	} else {
-		return false
 	}
+	if isRegexPattern(pattern) {
+		return matchSinglePattern(pattern, value)
+	}
+	included := false
+	hasPositive := false
+	for _, p := range SplitRule(pattern) {
+		if strings.HasPrefix(p, NegationPatternPrefix) {
+			if matchSinglePattern(strings.TrimPrefix(p, NegationPatternPrefix), value) {
+				return false
+			}
+			continue
+		}
+		hasPositive = true
+		if !included && matchSinglePattern(p, value) {
+			included = true
+		}
+	}
+	return included || !hasPositive
+}
+
+// ValidatePattern returns an error if the pattern cannot be used for MatchPattern, e.g. an invalid regular expression.
+func ValidatePattern(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil
+	}
+	if isRegexPattern(pattern) {
+		_, err := compileRegexPattern(strings.TrimPrefix(strings.TrimPrefix(pattern, NegationPatternPrefix), RegexPatternPrefix))
+		if err != nil {
+			return fmt.Errorf("invalid regular expression `%s`; %s", pattern, err.Error())
+		}
+		return nil
+	}
+	for _, p := range SplitRule(pattern) {
+		if p == "" || p == NegationPatternPrefix {
+			return fmt.Errorf("empty pattern in `%s`", pattern)
+		}
+	}
+	return nil
+}
+
+// ValidatePatternArray returns the first error of ValidatePattern for the patterns.
+func ValidatePatternArray(patternArray []string) error {
+	for _, pattern := range patternArray {
+		if err := ValidatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isRegexPattern(pattern string) bool {
+	return strings.HasPrefix(strings.TrimPrefix(pattern, NegationPatternPrefix), RegexPatternPrefix)
+}
+
+func matchSinglePattern(pattern, value string) bool {
+	if strings.HasPrefix(pattern, NegationPatternPrefix) {
+		return !matchSinglePattern(strings.TrimPrefix(pattern, NegationPatternPrefix), value)
+	}
+	if strings.HasPrefix(pattern, RegexPatternPrefix) {
+		re, err := compileRegexPattern(strings.TrimPrefix(pattern, RegexPatternPrefix))
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value)
+	}
+	if pattern == "-" {
+		return value == ""
+	}
+	return matchGlob(pattern, value)
+}
+
+func compileRegexPattern(expr string) (*regexp.Regexp, error) {
+	if cached, ok := regexpCache.Load(expr); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, err
+	}
+	regexpCache.Store(expr, re)
+	return re, nil
+}
+
+// matchGlob matches the value with the pattern in which `*` matches any characters (including `/`)
+// and `?` matches a single character.
+func matchGlob(pattern, value string) bool {
+	p, v := []rune(pattern), []rune(value)
+	pi, vi := 0, 0
+	starIdx, matchIdx := -1, 0
+	for vi < len(v) {
+		if pi < len(p) && (p[pi] == '?' || p[pi] == v[vi]) {
+			pi++
+			vi++
+		} else if pi < len(p) && p[pi] == '*' {
+			starIdx = pi
+			matchIdx = vi
+			pi++
+		} else if starIdx >= 0 {
+			pi = starIdx + 1
+			matchIdx++
+			vi = matchIdx
+		} else {
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
 }
 
 func ExactMatch(pattern, value string) bool {
@@ -129,3 +245,40 @@ func SplitRule(rules string) []string {
 	}
 	return result
 }
+
+// MatchSelector returns true if the label selector matches with the values (labels or annotations).
+// Unlike labels.Selector, the values are not validated as label values, so this can be used for annotations too.
+func MatchSelector(selector *metav1.LabelSelector, values map[string]string) bool {
+	if selector == nil {
+		return false
+	}
+	for key, value := range selector.MatchLabels {
+		if actual, ok := values[key]; !ok || actual != value {
+			return false
+		}
+	}
+	for _, expr := range selector.MatchExpressions {
+		actual, ok := values[expr.Key]
+		switch expr.Operator {
+		case metav1.LabelSelectorOpIn:
+			if !ok || !ExactMatchWithPatternArray(actual, expr.Values) {
+				return false
+			}
+		case metav1.LabelSelectorOpNotIn:
+			if ok && ExactMatchWithPatternArray(actual, expr.Values) {
+				return false
+			}
+		case metav1.LabelSelectorOpExists:
+			if !ok {
+				return false
+			}
+		case metav1.LabelSelectorOpDoesNotExist:
+			if ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -35,3 +35,77 @@ func TestPattern(t *testing.T) {
 		t.Errorf("TestPattern() Failed")
 	}
 }
+
+func TestMatchPatternSyntax(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"*-config", "app-config", true},
+		{"*-config", "app-configs", false},
+		{"app-*-cm", "app-1-cm", true},
+		{"app-?", "app-1", true},
+		{"app-?", "app-10", false},
+		{"apps/*", "apps/v1", true},
+		{"-", "", true},
+		{"-,app-*", "app-1", true},
+		{"!kube-*", "default", true},
+		{"!kube-*", "kube-system", false},
+		{"!kube-*,!openshift-*", "openshift-config", false},
+		{"app-*,!app-test", "app-1", true},
+		{"app-*,!app-test", "app-test", false},
+		{"app-*,!app-test", "default", false},
+		{"re:app-[0-9]+", "app-10", true},
+		{"re:app-[0-9]+", "app-10-x", false},
+		{"re:^(dev|stg)-.*$", "stg-ns", true},
+		{"re:a{1,2}", "aa", true},
+		{"!re:a{1,2}", "aa", false},
+		{"re:(", "(", false},
+	}
+	for _, tc := range testCases {
+		actual := MatchPattern(tc.pattern, tc.value)
+		if actual != tc.expected {
+			t.Errorf("TestMatchPatternSyntax() Failed\npattern: %s, value: %s\nexpected: %v\nactual: %v", tc.pattern, tc.value, tc.expected, actual)
+		}
+	}
+}
+
+// a list of only negated patterns matches everything else, including an empty value
+func TestMatchPatternNegationOnly(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"!kube-*,!openshift-*", "default", true},
+		{"!kube-*,!openshift-*", "", true},
+		{"!kube-*,!openshift-*", "kube-system", false},
+		{"!kube-*,!openshift-*", "openshift-config", false},
+		{"!kube-*,!-", "", false},
+		{"!kube-*,!-", "default", true},
+		{"!app-?", "app-1", false},
+		{"!app-?", "app-10", true},
+	}
+	for _, tc := range testCases {
+		actual := MatchPattern(tc.pattern, tc.value)
+		if actual != tc.expected {
+			t.Errorf("TestMatchPatternNegationOnly() Failed\npattern: %s, value: %s\nexpected: %v\nactual: %v", tc.pattern, tc.value, tc.expected, actual)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	validPatterns := []string{"", "*", "-", "*-config", "!kube-*,default", "re:app-[0-9]+", "!re:a{1,2}"}
+	for _, pattern := range validPatterns {
+		if err := ValidatePattern(pattern); err != nil {
+			t.Errorf("TestValidatePattern() Failed\npattern: %s\nexpected: nil\nactual: %s", pattern, err.Error())
+		}
+	}
+	invalidPatterns := []string{"re:(", "!re:[a-", "app,,default", "app,!"}
+	for _, pattern := range invalidPatterns {
+		if err := ValidatePattern(pattern); err == nil {
+			t.Errorf("TestValidatePattern() Failed\npattern: %s\nexpected: error\nactual: nil", pattern)
+		}
+	}
+}
@@ -19,6 +19,7 @@ package common
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jinzhu/copier"
 )
@@ -37,6 +38,7 @@ const (
 	UnknownMode IntegrityShieldMode = ""
 	EnforceMode IntegrityShieldMode = "enforce"
 	DetectMode  IntegrityShieldMode = "detect"
+	WarnMode    IntegrityShieldMode = "warn"
 )
 
 /**********************************************
@@ -88,8 +90,9 @@ func (self *SignerConfig) Merge(data *SignerConfig) *SignerConfig {
 	return merged
 }
 
-func (self *SignerConfig) GetCandidatePubkeys(keyPathList []string, namespace string) map[SignatureType][]string {
-	candidates := []string{}
+// GetCandidatePubkeys returns the keys of keyConfigs which are used by the signers for the namespace
+func (self *SignerConfig) GetCandidatePubkeys(keys []VerificationKey, namespace string) map[SignatureType][]VerificationKey {
+	candidates := map[string]bool{}
 	for _, spc := range self.Policies {
 		var included, excluded bool
 		if namespace == "" {
@@ -109,34 +112,51 @@ func (self *SignerConfig) GetCandidatePubkeys(keyPathList []string, namespace st
 		for _, signerName := range spc.Signers {
 			for _, signerCondition := range self.Signers {
 				if signerCondition.Name == signerName {
-					candidates = append(candidates, signerCondition.KeyConfig)
+					candidates[signerCondition.KeyConfig] = true
 				}
 			}
 		}
 	}
-	candidateKeys := map[SignatureType][]string{
-		SignatureTypePGP:  {},
-		SignatureTypeX509: {},
-	}
-	for _, keyPath := range keyPathList {
-		for _, keyConfName := range candidates {
-			pgpPattern := fmt.Sprintf("/%s/%s/", keyConfName, string(SignatureTypePGP))
-			x509Pattern := fmt.Sprintf("/%s/%s/", keyConfName, string(SignatureTypeX509))
-			if strings.Contains(keyPath, pgpPattern) {
-				candidateKeys[SignatureTypePGP] = append(candidateKeys[SignatureTypePGP], keyPath)
-				break
-			} else if strings.Contains(keyPath, x509Pattern) {
-				candidateKeys[SignatureTypeX509] = append(candidateKeys[SignatureTypeX509], keyPath)
-				break
-			}
+	candidateKeys := map[SignatureType][]VerificationKey{
+		SignatureTypePGP:      {},
+		SignatureTypeX509:     {},
+		SignatureTypeSigstore: {},
+	}
+	for _, key := range keys {
+		if _, ok := candidateKeys[key.Type]; !ok {
+			continue
+		}
+		if candidates[key.KeyConfig] {
+			candidateKeys[key.Type] = append(candidateKeys[key.Type], key)
 		}
 	}
 	return candidateKeys
 }
 
-func (self *SignerConfig) Match(namespace string, signer *SignerInfo, verifiedKeyPathList []string) (bool, *SignerConfigCondition) {
+func (self *SignerConfig) Match(namespace string, signer *SignerInfo, verifiedKeyConfigs []string) (bool, *SignerConfigCondition) {
+	matched, spc, _ := self.MatchMultiSigners(namespace, []VerifiedSigner{{Signer: signer, VerifiedKeyConfigs: verifiedKeyConfigs}})
+	return matched, spc
+}
+
+// VerifiedSigner is a signer of a verified signature and names of keyConfig used for the verification
+type VerifiedSigner struct {
+	Signer             *SignerInfo
+	VerifiedKeyConfigs []string
+	// signing time of the signature given by `notBefore`. zero if unknown.
+	SignedAt time.Time
+}
+
+// MatchMultiSigners returns true if any policy for the namespace is satisfied by the signers of verified signatures.
+// A signature is counted as at most one of `signers` in a policy, so that one person cannot satisfy `minSigners` alone.
+// It also returns the names of matched signers. If no policy is satisfied, the policy with the most matched signers is returned.
+func (self *SignerConfig) MatchMultiSigners(namespace string, signers []VerifiedSigner) (bool, *SignerConfigCondition, []string) {
 	signerMap := self.GetSignerMap()
-	for _, spc := range self.Policies {
+	signers = uniqueSigners(signers)
+	now := time.Now()
+	var closest *SignerConfigCondition
+	closestNames := []string{}
+	for i := range self.Policies {
+		spc := self.Policies[i]
 		var included, excluded bool
 		if namespace == "" {
 			if spc.Scope == ScopeCluster {
@@ -149,33 +169,42 @@ func (self *SignerConfig) Match(namespace string, signer *SignerInfo, verifiedKe
 				excluded = MatchWithPatternArray(namespace, spc.ExcludeNamespaces)
 			}
 		}
-		signerMatched := false
-		for _, signerName := range spc.Signers {
-			subjectConditions, ok := signerMap[signerName]
-			if !ok {
-				continue
-			}
-			for _, subjectCondition := range subjectConditions {
-				if subjectOk := subjectCondition.Match(signer); !subjectOk {
-					continue
-				}
-				for _, keyPath := range verifiedKeyPathList {
-					if strings.Contains(keyPath, fmt.Sprintf("/%s/", subjectCondition.KeyConfig)) {
-						signerMatched = true
-						break
-					}
-				}
-			}
-			if signerMatched {
-				break
-			}
+		if !included || excluded {
+			continue
 		}
-		matched := included && !excluded && signerMatched
-		if matched {
-			return true, &spc
+		ok, matchedNames := spc.matchSigners(signerMap, signers, now)
+		if ok {
+			return true, &spc, matchedNames
 		}
+		if closest == nil || len(matchedNames) > len(closestNames) {
+			closest = &spc
+			closestNames = matchedNames
+		}
+	}
+	return false, closest, closestNames
+}
+
+// uniqueSigners merges verified signatures by the same signer
+func uniqueSigners(signers []VerifiedSigner) []VerifiedSigner {
+	unique := []VerifiedSigner{}
+	index := map[string]int{}
+	for _, vs := range signers {
+		if vs.Signer == nil {
+			continue
+		}
+		key := fmt.Sprintf("%s/%x/%v", vs.Signer.GetName(), vs.Signer.Fingerprint, vs.Signer.SerialNumber)
+		if i, ok := index[key]; ok {
+			unique[i].VerifiedKeyConfigs = append(unique[i].VerifiedKeyConfigs, vs.VerifiedKeyConfigs...)
+			// the latest signature by the same signer is used for checking signature age
+			if vs.SignedAt.After(unique[i].SignedAt) {
+				unique[i].SignedAt = vs.SignedAt
+			}
+			continue
+		}
+		index[key] = len(unique)
+		unique = append(unique, VerifiedSigner{Signer: vs.Signer, VerifiedKeyConfigs: append([]string{}, vs.VerifiedKeyConfigs...), SignedAt: vs.SignedAt})
 	}
-	return false, nil
+	return unique
 }
 
 type SignerConfigCondition struct {
@@ -183,6 +212,96 @@ type SignerConfigCondition struct {
 	Namespaces        []string  `json:"namespaces,omitempty"`
 	ExcludeNamespaces []string  `json:"excludeNamespaces,omitempty"`
 	Signers           []string  `json:"signers,omitempty"`
+	// the number of signers in `signers` who must sign a resource. default is 1.
+	MinSigners int `json:"minSigners,omitempty"`
+	// signers who must sign a resource in any case. they are counted for `minSigners` too.
+	RequiredSigners []string `json:"requiredSigners,omitempty"`
+	// signatures older than this duration (e.g. `720h`) are not accepted. the age is calculated from `notBefore` of the signature.
+	MaxSignatureAge string `json:"maxSignatureAge,omitempty"`
+}
+
+// NumOfRequiredSigners returns how many different signers must sign a resource for this policy.
+func (self SignerConfigCondition) NumOfRequiredSigners() int {
+	num := self.MinSigners
+	if len(self.RequiredSigners) > num {
+		num = len(self.RequiredSigners)
+	}
+	if num < 1 {
+		num = 1
+	}
+	return num
+}
+
+// IsTooOld returns true if the signature is older than `maxSignatureAge` of this policy.
+// A signature without `notBefore` is regarded as too old because its age is unknown.
+func (self SignerConfigCondition) IsTooOld(vs VerifiedSigner, now time.Time) bool {
+	if self.MaxSignatureAge == "" {
+		return false
+	}
+	maxAge, err := time.ParseDuration(self.MaxSignatureAge)
+	if err != nil || vs.SignedAt.IsZero() {
+		return true
+	}
+	return now.Sub(vs.SignedAt) > maxAge
+}
+
+// matchSigners assigns verified signers to signer names in this policy one-to-one (bipartite matching).
+// Required signers are assigned first, and augmenting paths never unassign them afterwards.
+func (self SignerConfigCondition) matchSigners(signerMap map[string][]SubjectCondition, signers []VerifiedSigner, now time.Time) (bool, []string) {
+	names := GetUnionOfArrays(self.RequiredSigners, self.Signers)
+	// candidates[i] is the list of indices of signers who can be names[i]
+	candidates := make([][]int, len(names))
+	for i, name := range names {
+		for j, vs := range signers {
+			if !self.IsTooOld(vs, now) && matchSignerName(signerMap[name], vs) {
+				candidates[i] = append(candidates[i], j)
+			}
+		}
+	}
+
+	assigned := make([]int, len(signers)) // assigned[j] is the index of name assigned to signers[j]
+	for j := range assigned {
+		assigned[j] = -1
+	}
+	var augment func(i int, visited []bool) bool
+	augment = func(i int, visited []bool) bool {
+		for _, j := range candidates[i] {
+			if visited[j] {
+				continue
+			}
+			visited[j] = true
+			if assigned[j] < 0 || augment(assigned[j], visited) {
+				assigned[j] = i
+				return true
+			}
+		}
+		return false
+	}
+
+	matchedNames := []string{}
+	requiredOk := true
+	for i, name := range names {
+		if augment(i, make([]bool, len(signers))) {
+			matchedNames = append(matchedNames, name)
+		} else if ExactMatchWithPatternArray(name, self.RequiredSigners) {
+			requiredOk = false
+		}
+	}
+	return requiredOk && len(matchedNames) >= self.NumOfRequiredSigners(), matchedNames
+}
+
+func matchSignerName(subjectConditions []SubjectCondition, vs VerifiedSigner) bool {
+	for _, subjectCondition := range subjectConditions {
+		if subjectOk := subjectCondition.Match(vs.Signer); !subjectOk {
+			continue
+		}
+		for _, keyConfig := range vs.VerifiedKeyConfigs {
+			if keyConfig == subjectCondition.KeyConfig {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 type SignerCondition struct {
@@ -194,6 +313,37 @@ type SignerCondition struct {
 type BreakGlassCondition struct {
 	Scope      ScopeType `json:"scope,omitempty"`
 	Namespaces []string  `json:"namespaces,omitempty"`
+	// `ExpiresAt` is RFC3339 format. the condition is ignored after this time.
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	RequestedBy string `json:"requestedBy,omitempty"`
+}
+
+// IsExpired returns true if `expiresAt` has passed. a condition without `expiresAt` never expires,
+// but one with invalid `expiresAt` is regarded as expired so that protection is not disabled by mistake.
+func (self BreakGlassCondition) IsExpired(now time.Time) bool {
+	if self.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, self.ExpiresAt)
+	if err != nil {
+		return true
+	}
+	return now.After(expiresAt)
+}
+
+func (self BreakGlassCondition) String() string {
+	s := fmt.Sprintf("scope: %s, namespaces: %s", self.Scope, strings.Join(self.Namespaces, ","))
+	if self.ExpiresAt != "" {
+		s = fmt.Sprintf("%s, expiresAt: %s", s, self.ExpiresAt)
+	}
+	if self.Reason != "" {
+		s = fmt.Sprintf("%s, reason: %s", s, self.Reason)
+	}
+	if self.RequestedBy != "" {
+		s = fmt.Sprintf("%s, requestedBy: %s", s, self.RequestedBy)
+	}
+	return s
 }
 
 type SubjectMatchPattern struct {
@@ -208,6 +358,36 @@ type SubjectMatchPattern struct {
 	PostalCode         string `json:"postalCode,omitempty"`
 	CommonName         string `json:"commonName,omitempty"`
 	SerialNumber       string `json:"serialNumber,omitempty"`
+	// `identity` and `issuer` are matched with OIDC identity (email or URI) and issuer in the certificate of keyless signature
+	Identity string `json:"identity,omitempty"`
+	Issuer   string `json:"issuer,omitempty"`
+}
+
+// Validate returns an error if any pattern in the subject is invalid.
+func (self SubjectMatchPattern) Validate() error {
+	fields := []struct {
+		name    string
+		pattern string
+	}{
+		{"email", self.Email},
+		{"uid", self.Uid},
+		{"country", self.Country},
+		{"organization", self.Organization},
+		{"organizationalUnit", self.OrganizationalUnit},
+		{"locality", self.Locality},
+		{"province", self.Province},
+		{"streetAddress", self.StreetAddress},
+		{"postalCode", self.PostalCode},
+		{"commonName", self.CommonName},
+		{"identity", self.Identity},
+		{"issuer", self.Issuer},
+	}
+	for _, f := range fields {
+		if err := ValidatePattern(f.pattern); err != nil {
+			return fmt.Errorf("%s: %s", f.name, err.Error())
+		}
+	}
+	return nil
 }
 
 type SubjectCondition struct {
@@ -216,7 +396,13 @@ type SubjectCondition struct {
 	Subject   SubjectMatchPattern `json:"subject"`
 }
 
+// Match returns true if the signer matches with all patterns in the subject.
+// A signer of keyless signature (with OIDC identity or issuer) matches only with a subject which has both `identity` and `issuer`,
+// because empty patterns match with any certificate issued by Fulcio.
 func (self *SubjectCondition) Match(signer *SignerInfo) bool {
+	if (signer.Identity != "" || signer.Issuer != "") && (self.Subject.Identity == "" || self.Subject.Issuer == "") {
+		return false
+	}
 	return MatchPattern(self.Subject.Email, signer.Email) &&
 		MatchPattern(self.Subject.Uid, signer.Uid) &&
 		MatchPattern(self.Subject.Country, signer.Country) &&
@@ -227,5 +413,7 @@ func (self *SubjectCondition) Match(signer *SignerInfo) bool {
 		MatchPattern(self.Subject.StreetAddress, signer.StreetAddress) &&
 		MatchPattern(self.Subject.PostalCode, signer.PostalCode) &&
 		MatchPattern(self.Subject.CommonName, signer.CommonName) &&
-		MatchBigInt(self.Subject.SerialNumber, signer.SerialNumber)
+		MatchBigInt(self.Subject.SerialNumber, signer.SerialNumber) &&
+		MatchPattern(self.Subject.Identity, signer.Identity) &&
+		MatchPattern(self.Subject.Issuer, signer.Issuer)
 }
@@ -18,11 +18,14 @@ package common
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/jinzhu/copier"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -34,11 +37,13 @@ type CommonProfile struct {
 type Rule struct {
 	Match   []*RequestPattern `json:"match,omitempty"`
 	Exclude []*RequestPattern `json:"exclude,omitempty"`
+	// Condition is a CEL expression which must be true in addition to `match` (e.g. `has(object.data.key1)`)
+	Condition string `json:"condition,omitempty"`
 }
 
 type RequestPattern struct {
-	Scope *RulePattern `json:"scope,omitempty"`
-	// Namespace  *RulePattern `json:"namespace,omitempty"`
+	Scope      *RulePattern `json:"scope,omitempty"`
+	Namespace  *RulePattern `json:"namespace,omitempty"`
 	ApiGroup   *RulePattern `json:"apiGroup,omitempty"`
 	ApiVersion *RulePattern `json:"apiVersion,omitempty"`
 	Kind       *RulePattern `json:"kind,omitempty"`
@@ -46,6 +51,12 @@ type RequestPattern struct {
 	Operation  *RulePattern `json:"operation,omitempty"`
 	UserName   *RulePattern `json:"username,omitempty"`
 	UserGroup  *RulePattern `json:"usergroup,omitempty"`
+	// LabelSelector matches with labels of the requested object or the existing object (e.g. for UPDATE and DELETE).
+	// In patterns which allow requests (e.g. `exclude` and `ignoreRules`), only the existing object is used if any.
+	// For CREATE requests, labels are controlled by the requester.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// AnnotationSelector matches with annotations in the same way as LabelSelector
+	AnnotationSelector *metav1.LabelSelector `json:"annotationSelector,omitempty"`
 }
 
 type KustomizePattern struct {
@@ -77,36 +88,54 @@ func (self *RequestPatternWithNamespace) Match(reqFields map[string]string) bool
 	return nsMatched && otherMatched
 }
 
+// MatchExisting is the same as Match, but selectors are matched in the same way as RequestPattern.MatchExisting
+func (self *RequestPatternWithNamespace) MatchExisting(reqFields map[string]string) bool {
+	if self.Namespace == nil && self.RequestPattern == nil {
+		return false
+	}
+	if self.Namespace != nil && !MatchPattern(string(*self.Namespace), reqFields["Namespace"]) {
+		return false
+	}
+	if self.RequestPattern != nil {
+		return self.RequestPattern.MatchExisting(reqFields)
+	}
+	return true
+}
+
 func (self *Rule) String() string {
 	rB, _ := json.Marshal(self)
 	return string(rB)
 }
 
+// MatchWithRequest returns true if the request matches with `match` and does not match with `exclude`.
+// This is used for rules which protect resources (e.g. `protectRules` and `forceCheckRules`).
 func (self *Rule) MatchWithRequest(reqFields map[string]string) bool {
-	matched := false
-	for _, m := range self.Match {
-		if m.Match(reqFields) {
-			matched = true
-			break
-		}
-	}
-	excluded := false
-	if matched {
-		for _, ex := range self.Exclude {
-			if ex.Match(reqFields) {
-				excluded = true
-				break
-			}
-		}
-	}
+	return self.matchWithRequest(reqFields, false, false)
+}
 
-	return matched && !excluded
+// MatchCondition returns true if `condition` is empty or evaluated as true for the request.
+// `onError` is returned if the condition cannot be evaluated.
+func (self *Rule) MatchCondition(vars *ConditionVars, onError bool) bool {
+	if self.Condition == "" {
+		return true
+	}
+	return matchCondition(self.Condition, vars, onError)
 }
 
 func (self *Rule) StrictMatchWithRequest(reqFields map[string]string) bool {
+	return self.matchWithRequest(reqFields, true, false)
+}
+
+// IgnoreMatchWithRequest is the same as MatchWithRequest, but this is used for rules which allow requests (e.g. `ignoreRules`),
+// so selectors in `match` are evaluated against the existing object and selectors in `exclude` against either object.
+func (self *Rule) IgnoreMatchWithRequest(reqFields map[string]string, exactMatchForName bool) bool {
+	return self.matchWithRequest(reqFields, exactMatchForName, true)
+}
+
+func (self *Rule) matchWithRequest(reqFields map[string]string, exactMatchForName, ignore bool) bool {
 	matched := false
 	for _, m := range self.Match {
-		if m.StrictMatch(reqFields) {
+		if m.match(reqFields, exactMatchForName, ignore) {
 			matched = true
 			break
 		}
@@ -114,7 +143,7 @@ func (self *Rule) StrictMatchWithRequest(reqFields map[string]string) bool {
 	excluded := false
 	if matched {
 		for _, ex := range self.Exclude {
-			if ex.StrictMatch(reqFields) {
+			if ex.match(reqFields, exactMatchForName, !ignore) {
 				excluded = true
 				break
 			}
@@ -126,15 +155,21 @@ func (self *Rule) StrictMatchWithRequest(reqFields map[string]string) bool {
 
 // match the input request with pattern, allow wildcard for resource name
 func (self *RequestPattern) Match(reqFields map[string]string) bool {
-	return self.match(reqFields, false)
+	return self.match(reqFields, false, false)
 }
 
 // match the input request with pattern, exact match for resource name
 func (self *RequestPattern) StrictMatch(reqFields map[string]string) bool {
-	return self.match(reqFields, true)
+	return self.match(reqFields, true, false)
+}
+
+// MatchExisting is the same as Match, but selectors must match with the existing object for UPDATE and DELETE.
+// This is used for patterns which allow requests, so that a request cannot be allowed only by adding labels or annotations.
+func (self *RequestPattern) MatchExisting(reqFields map[string]string) bool {
+	return self.match(reqFields, false, true)
 }
 
-func (self *RequestPattern) match(reqFields map[string]string, exactMatchForName bool) bool {
+func (self *RequestPattern) match(reqFields map[string]string, exactMatchForName, existingOnly bool) bool {
 	scope := "Namespaced"
 	if reqScope, ok := reqFields["ResourceScope"]; ok && reqScope == "Cluster" {
 		scope = reqScope
@@ -171,9 +206,49 @@ func (self *RequestPattern) match(reqFields map[string]string, exactMatchForName
 			continue
 		}
 	}
+	if self.LabelSelector != nil {
+		patternCount += 1
+		matched = matched && matchSelectorWithRequest(self.LabelSelector, reqFields, ReqFieldObjectLabels, ReqFieldOldObjectLabels, existingOnly)
+	}
+	if self.AnnotationSelector != nil {
+		patternCount += 1
+		matched = matched && matchSelectorWithRequest(self.AnnotationSelector, reqFields, ReqFieldObjectAnnotations, ReqFieldOldObjectAnnotations, existingOnly)
+	}
 	return (patternCount > 0) && matched
 }
 
+// matchSelectorWithRequest returns true if the selector matches with either of the requested object or the existing object
+// (for UPDATE and DELETE), so that a protected resource cannot be excluded from the rule only by changing its labels.
+// If `existingOnly` is true, the existing object must match (both objects for UPDATE), so that a request cannot be
+// allowed only by adding labels. CREATE never matches in this case, because there is no existing object to trust.
+func matchSelectorWithRequest(selector *metav1.LabelSelector, reqFields map[string]string, objectKey, oldObjectKey string, existingOnly bool) bool {
+	operation := reqFields["Operation"]
+	newMatched := func() bool { return MatchSelector(selector, decodeMapField(reqFields[objectKey])) }
+	oldMatched := func() bool { return MatchSelector(selector, decodeMapField(reqFields[oldObjectKey])) }
+	switch operation {
+	case "UPDATE":
+		if existingOnly {
+			return newMatched() && oldMatched()
+		}
+		return newMatched() || oldMatched()
+	case "DELETE":
+		return oldMatched()
+	default:
+		if existingOnly {
+			return false
+		}
+		return newMatched()
+	}
+}
+
+func decodeMapField(value string) map[string]string {
+	values := map[string]string{}
+	if value != "" {
+		_ = json.Unmarshal([]byte(value), &values)
+	}
+	return values
+}
+
 type RulePattern string
 
 func (self *RulePattern) match(value string) bool {
@@ -184,6 +259,59 @@ func (self *RulePattern) exactMatch(value string) bool {
 	return ExactMatch(string(*self), value)
 }
 
+// Validate returns an error if any pattern in the request pattern is invalid.
+func (self *RequestPattern) Validate() error {
+	if self == nil {
+		return nil
+	}
+	fields := []struct {
+		name    string
+		pattern *RulePattern
+	}{
+		{"scope", self.Scope},
+		{"namespace", self.Namespace},
+		{"apiGroup", self.ApiGroup},
+		{"apiVersion", self.ApiVersion},
+		{"kind", self.Kind},
+		{"name", self.Name},
+		{"operation", self.Operation},
+		{"username", self.UserName},
+		{"usergroup", self.UserGroup},
+	}
+	for _, f := range fields {
+		if f.pattern == nil {
+			continue
+		}
+		if err := ValidatePattern(string(*f.pattern)); err != nil {
+			return fmt.Errorf("%s: %s", f.name, err.Error())
+		}
+	}
+	return nil
+}
+
+func validateRequestPatterns(field string, patterns []*RequestPattern) error {
+	for i, p := range patterns {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("%s[%s].%s", field, strconv.Itoa(i), err.Error())
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if any pattern in the rule is invalid.
+func (self *Rule) Validate() error {
+	if self == nil {
+		return nil
+	}
+	if err := validateRequestPatterns("match", self.Match); err != nil {
+		return err
+	}
+	if err := validateRequestPatterns("exclude", self.Exclude); err != nil {
+		return err
+	}
+	return validateCondition(self.Condition)
+}
+
 // reverse the string
 func reverse(s string) string {
 	runes := []rune(s)
@@ -273,9 +401,115 @@ type ServiceAccountPattern struct {
 	ServiceAccountNames []string                     `json:"serviceAccountNames,omitempty"`
 }
 
+// MatchWith returns true if the request is made by one of ServiceAccountNames (user name patterns),
+// and it matches with `match` and does not match with `except`. Empty `match` matches all requests.
+func (self *ServiceAccountPattern) MatchWith(reqFields map[string]string) bool {
+	if !MatchWithPatternArray(reqFields["UserName"], self.ServiceAccountNames) {
+		return false
+	}
+	if self.Match != nil && !self.Match.MatchExisting(reqFields) {
+		return false
+	}
+	if self.Except != nil && self.Except.Match(reqFields) {
+		return false
+	}
+	return true
+}
+
+// Validate returns an error if any pattern in the service account pattern is invalid.
+func (self *ServiceAccountPattern) Validate() error {
+	if self == nil {
+		return nil
+	}
+	if err := ValidatePatternArray(self.ServiceAccountNames); err != nil {
+		return fmt.Errorf("serviceAccountNames: %s", err.Error())
+	}
+	for _, f := range []struct {
+		name    string
+		pattern *RequestPatternWithNamespace
+	}{{"match", self.Match}, {"except", self.Except}} {
+		name, p := f.name, f.pattern
+		if p == nil {
+			continue
+		}
+		if p.Namespace != nil {
+			if err := ValidatePattern(string(*p.Namespace)); err != nil {
+				return fmt.Errorf("%s.namespace: %s", name, err.Error())
+			}
+		}
+		if err := p.RequestPattern.Validate(); err != nil {
+			return fmt.Errorf("%s.%s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// OwnerCheck allows requests for resources owned by a verified resource, e.g. ReplicaSets and Pods created from a verified Deployment.
+// Owners are followed by the controller reference in `metadata.ownerReferences`.
+type OwnerCheck struct {
+	// MaxDepth is the max number of owners to be followed (e.g. 2 for Pod -> ReplicaSet -> Deployment)
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// ControllerServiceAccounts is a list of user name patterns of the controllers which create and update owned resources
+	ControllerServiceAccounts []string `json:"controllerServiceAccounts,omitempty"`
+}
+
+const (
+	DefaultOwnerCheckMaxDepth = 2
+)
+
+var DefaultOwnerCheckControllerServiceAccounts = []string{"system:serviceaccount:kube-system:*"}
+
+// Validate returns an error if any pattern in the owner check is invalid.
+func (self *OwnerCheck) Validate() error {
+	if self == nil {
+		return nil
+	}
+	if err := ValidatePatternArray(self.ControllerServiceAccounts); err != nil {
+		return fmt.Errorf("controllerServiceAccounts: %s", err.Error())
+	}
+	return nil
+}
+
+func (self *OwnerCheck) GetMaxDepth() int {
+	if self.MaxDepth <= 0 {
+		return DefaultOwnerCheckMaxDepth
+	}
+	return self.MaxDepth
+}
+
+func (self *OwnerCheck) MatchControllerServiceAccount(userName string) bool {
+	patterns := self.ControllerServiceAccounts
+	if len(patterns) == 0 {
+		patterns = DefaultOwnerCheckControllerServiceAccounts
+	}
+	return MatchWithPatternArray(userName, patterns)
+}
+
 type AttrsPattern struct {
 	Match []*RequestPattern `json:"match,omitempty"`
 	Attrs []string          `json:"attrs,omitempty"`
+	// Condition is a CEL expression which must be true in addition to `match`
+	Condition string `json:"condition,omitempty"`
+}
+
+// Validate returns an error if any pattern in `match` is invalid.
+func (self *AttrsPattern) Validate() error {
+	if self == nil {
+		return nil
+	}
+	if err := validateRequestPatterns("match", self.Match); err != nil {
+		return err
+	}
+	return validateCondition(self.Condition)
+}
+
+// MatchCondition returns true if `condition` is empty or evaluated as true for the request.
+// `onError` is returned if the condition cannot be evaluated.
+func (self *AttrsPattern) MatchCondition(vars *ConditionVars, onError bool) bool {
+	if self.Condition == "" {
+		return true
+	}
+	return matchCondition(self.Condition, vars, onError)
 }
 
 func (self *AttrsPattern) MatchWith(reqFields map[string]string) bool {
@@ -287,6 +521,16 @@ func (self *AttrsPattern) MatchWith(reqFields map[string]string) bool {
 	return false
 }
 
+// MatchExistingWith is the same as MatchWith, but this is used for `ignoreAttrs` (see RequestPattern.MatchExisting)
+func (self *AttrsPattern) MatchExistingWith(reqFields map[string]string) bool {
+	for _, reqPattern := range self.Match {
+		if reqPattern.MatchExisting(reqFields) {
+			return true
+		}
+	}
+	return false
+}
+
 type Request struct {
 	// Scope      string `json:"scope,omitempty"`
 	Operation  string `json:"operation,omitempty"`
@@ -384,6 +628,16 @@ func (p *AttrsPattern) DeepCopy() *AttrsPattern {
 	return p2
 }
 
+func (p *OwnerCheck) DeepCopyInto(p2 *OwnerCheck) {
+	copier.Copy(&p2, &p)
+}
+
+func (p *OwnerCheck) DeepCopy() *OwnerCheck {
+	p2 := &OwnerCheck{}
+	p.DeepCopyInto(p2)
+	return p2
+}
+
 func (p *Result) DeepCopyInto(p2 *Result) {
 	copier.Copy(&p2, &p)
 }
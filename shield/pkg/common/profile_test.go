@@ -129,3 +129,126 @@ func TestKustomizePattern(t *testing.T) {
 		return
 	}
 }
+
+func TestRequestPatternSelector(t *testing.T) {
+	var rule *Rule
+	ruleBytes := []byte(`{"match":[{"kind":"ConfigMap","namespace":"prod-*","labelSelector":{"matchLabels":{"tier":"prod"}}},{"annotationSelector":{"matchExpressions":[{"key":"example.com/owner","operator":"In","values":["team-a"]}]}}]}`)
+	if err := json.Unmarshal(ruleBytes, &rule); err != nil {
+		t.Error(err)
+		return
+	}
+	newReqc := func(operation, namespace string, labels, oldLabels, annotations map[string]string) *ReqContext {
+		return &ReqContext{
+			Operation:       operation,
+			Namespace:       namespace,
+			Kind:            "ConfigMap",
+			ClaimedMetadata: &ObjectMetadata{Labels: NewResourceLabel(labels), Annotations: NewResourceAnnotation(annotations)},
+			OrgMetadata:     &ObjectMetadata{Labels: NewResourceLabel(oldLabels), Annotations: NewResourceAnnotation(nil)},
+		}
+	}
+	prod := map[string]string{"tier": "prod"}
+	dev := map[string]string{"tier": "dev"}
+	testCases := []struct {
+		name     string
+		reqc     *ReqContext
+		expected bool
+	}{
+		{"labeled", newReqc("CREATE", "prod-ns", prod, nil, nil), true},
+		{"not labeled", newReqc("CREATE", "prod-ns", dev, nil, nil), false},
+		{"other namespace", newReqc("CREATE", "dev-ns", prod, nil, nil), false},
+		{"label removed", newReqc("UPDATE", "prod-ns", dev, prod, nil), true},
+		{"label of old object for CREATE", newReqc("CREATE", "prod-ns", dev, prod, nil), false},
+		{"delete labeled", newReqc("DELETE", "prod-ns", nil, prod, nil), true},
+		{"annotated", newReqc("CREATE", "dev-ns", nil, nil, map[string]string{"example.com/owner": "team-a"}), true},
+		{"annotated by other", newReqc("CREATE", "dev-ns", nil, nil, map[string]string{"example.com/owner": "team-b"}), false},
+	}
+	for _, tc := range testCases {
+		actual := rule.MatchWithRequest(tc.reqc.Map())
+		if actual != tc.expected {
+			t.Errorf("[%s] MatchWithRequest() Failed\nexpected: %t\nactual: %t", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestRuleIgnoreMatchSelector(t *testing.T) {
+	var rule *Rule
+	ruleBytes := []byte(`{"match":[{"kind":"ConfigMap","labelSelector":{"matchLabels":{"skip":"true"}}}],"exclude":[{"labelSelector":{"matchLabels":{"tier":"prod"}}}]}`)
+	if err := json.Unmarshal(ruleBytes, &rule); err != nil {
+		t.Error(err)
+		return
+	}
+	newReqFields := func(operation string, labels, oldLabels map[string]string) map[string]string {
+		reqc := &ReqContext{
+			Operation:       operation,
+			Kind:            "ConfigMap",
+			ClaimedMetadata: &ObjectMetadata{Labels: NewResourceLabel(labels), Annotations: NewResourceAnnotation(nil)},
+			OrgMetadata:     &ObjectMetadata{Labels: NewResourceLabel(oldLabels), Annotations: NewResourceAnnotation(nil)},
+		}
+		return reqc.Map()
+	}
+	skip := map[string]string{"skip": "true"}
+	skipProd := map[string]string{"skip": "true", "tier": "prod"}
+	testCases := []struct {
+		name      string
+		reqFields map[string]string
+		expected  bool
+	}{
+		{"skip label added", newReqFields("UPDATE", skip, nil), false},
+		{"skip label removed", newReqFields("UPDATE", nil, skip), false},
+		{"skip label kept", newReqFields("UPDATE", skip, skip), true},
+		{"delete with skip label", newReqFields("DELETE", nil, skip), true},
+		// labels of CREATE are controlled by the requester
+		{"create with skip label", newReqFields("CREATE", skip, nil), false},
+		// `exclude` of ignore rules matches with either object
+		{"prod label removed", newReqFields("UPDATE", skip, skipProd), false},
+	}
+	for _, tc := range testCases {
+		actual := rule.IgnoreMatchWithRequest(tc.reqFields, false)
+		if actual != tc.expected {
+			t.Errorf("[%s] IgnoreMatchWithRequest() Failed\nexpected: %t\nactual: %t", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestMatchExistingCreate(t *testing.T) {
+	var saPattern *ServiceAccountPattern
+	saBytes := []byte(`{"match":{"kind":"ConfigMap","labelSelector":{"matchLabels":{"skip":"true"}}},"serviceAccountNames":["system:serviceaccount:test-ns:*"]}`)
+	if err := json.Unmarshal(saBytes, &saPattern); err != nil {
+		t.Error(err)
+		return
+	}
+	var attrsPattern *AttrsPattern
+	attrsBytes := []byte(`{"match":[{"kind":"ConfigMap","annotationSelector":{"matchLabels":{"example.com/skip":"true"}}}],"attrs":["data.key1"]}`)
+	if err := json.Unmarshal(attrsBytes, &attrsPattern); err != nil {
+		t.Error(err)
+		return
+	}
+	newReqFields := func(operation string, labels, oldLabels map[string]string) map[string]string {
+		reqc := &ReqContext{
+			Operation:       operation,
+			Kind:            "ConfigMap",
+			UserName:        "system:serviceaccount:test-ns:sample-sa",
+			ClaimedMetadata: &ObjectMetadata{Labels: NewResourceLabel(labels), Annotations: NewResourceAnnotation(labels)},
+			OrgMetadata:     &ObjectMetadata{Labels: NewResourceLabel(oldLabels), Annotations: NewResourceAnnotation(oldLabels)},
+		}
+		return reqc.Map()
+	}
+	skip := map[string]string{"skip": "true", "example.com/skip": "true"}
+	testCases := []struct {
+		name      string
+		reqFields map[string]string
+		expected  bool
+	}{
+		// there is no existing object for CREATE, so the selectors set by the requester are not trusted
+		{"create with skip label", newReqFields("CREATE", skip, nil), false},
+		{"update with skip label kept", newReqFields("UPDATE", skip, skip), true},
+	}
+	for _, tc := range testCases {
+		if actual := saPattern.MatchWith(tc.reqFields); actual != tc.expected {
+			t.Errorf("[%s] ServiceAccountPattern.MatchWith() Failed\nexpected: %t\nactual: %t", tc.name, tc.expected, actual)
+		}
+		if actual := attrsPattern.MatchExistingWith(tc.reqFields); actual != tc.expected {
+			t.Errorf("[%s] AttrsPattern.MatchExistingWith() Failed\nexpected: %t\nactual: %t", tc.name, tc.expected, actual)
+		}
+	}
+}
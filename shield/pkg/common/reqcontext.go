@@ -52,6 +52,18 @@ type ReqContext struct {
 	Type            string          `json:"Type"`
 	ObjectHashType  string          `json:"objectHashType"`
 	ObjectHash      string          `json:"objectHash"`
+
+	// decoded once for the conditions in RSPs
+	conditionVars *ConditionVars
+}
+
+// ConditionVars returns the variables of conditions decoded from the request.
+// The request is decoded only at the first call.
+func (reqc *ReqContext) ConditionVars() *ConditionVars {
+	if reqc.conditionVars == nil {
+		reqc.conditionVars = NewConditionVars(reqc.RequestJsonStr)
+	}
+	return reqc.conditionVars
 }
 
 type ObjectMetadata struct {
@@ -59,6 +71,38 @@ type ObjectMetadata struct {
 	Labels      *ResourceLabel      `json:"labels"`
 }
 
+// keys of labels and annotations in the map of request fields, which are used for the selectors in RequestPattern
+const (
+	ReqFieldObjectLabels         = "ObjectLabels"
+	ReqFieldObjectAnnotations    = "ObjectAnnotations"
+	ReqFieldOldObjectLabels      = "OldObjectLabels"
+	ReqFieldOldObjectAnnotations = "OldObjectAnnotations"
+)
+
+// key of the admission request (JSON) in the map of request fields, which is used for the conditions in Rule and AttrsPattern
+const ReqFieldRequestJson = "RequestJsonStr"
+
+// SetMetadataFields adds labels and annotations of the requested object and the existing object to the request fields.
+// The values are JSON of the maps, and they are not set when empty.
+func SetMetadataFields(reqFields map[string]string, claimed, org *ObjectMetadata) {
+	setMapField := func(key string, values map[string]string) {
+		if len(values) == 0 {
+			return
+		}
+		if valuesBytes, err := json.Marshal(values); err == nil {
+			reqFields[key] = string(valuesBytes)
+		}
+	}
+	if claimed != nil {
+		setMapField(ReqFieldObjectLabels, claimed.Labels.Values())
+		setMapField(ReqFieldObjectAnnotations, claimed.Annotations.Values())
+	}
+	if org != nil {
+		setMapField(ReqFieldOldObjectLabels, org.Labels.Values())
+		setMapField(ReqFieldOldObjectAnnotations, org.Annotations.Values())
+	}
+}
+
 func (reqc *ReqContext) ResourceRef() *ResourceRef {
 	gv := schema.GroupVersion{
 		Group:   reqc.ApiGroup,
@@ -77,6 +121,10 @@ func (reqc *ReqContext) Map() map[string]string {
 	v := reflect.Indirect(reflect.ValueOf(reqc))
 	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
+		// unexported fields are not request fields
+		if t.Field(i).PkgPath != "" {
+			continue
+		}
 		f := v.Field(i)
 		itf := f.Interface()
 		if value, ok := itf.(string); ok {
@@ -86,6 +134,7 @@ func (reqc *ReqContext) Map() map[string]string {
 			continue
 		}
 	}
+	SetMetadataFields(m, reqc.ClaimedMetadata, reqc.OrgMetadata)
 	return m
 }
 
@@ -0,0 +1,95 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// RevocationList is a list of revoked signatures. A signature is revoked by its digest (SHA-256 of the signature,
+// hex encoded), by UID of ResourceSignature which includes it, or by fingerprint of the signer.
+type RevocationList struct {
+	SignatureDigests      []string `json:"signatureDigests,omitempty"`
+	ResourceSignatureUIDs []string `json:"resourceSignatureUIDs,omitempty"`
+	SignerFingerprints    []string `json:"signerFingerprints,omitempty"`
+}
+
+func (in *RevocationList) DeepCopyInto(out *RevocationList) {
+	*out = *in
+	out.SignatureDigests = append([]string{}, in.SignatureDigests...)
+	out.ResourceSignatureUIDs = append([]string{}, in.ResourceSignatureUIDs...)
+	out.SignerFingerprints = append([]string{}, in.SignerFingerprints...)
+}
+
+// Merge returns a new list which includes all revocations in both lists
+func (self *RevocationList) Merge(data *RevocationList) *RevocationList {
+	merged := &RevocationList{}
+	if self != nil {
+		self.DeepCopyInto(merged)
+	}
+	if data == nil {
+		return merged
+	}
+	merged.SignatureDigests = append(merged.SignatureDigests, data.SignatureDigests...)
+	merged.ResourceSignatureUIDs = append(merged.ResourceSignatureUIDs, data.ResourceSignatureUIDs...)
+	merged.SignerFingerprints = append(merged.SignerFingerprints, data.SignerFingerprints...)
+	return merged
+}
+
+func (self *RevocationList) IsEmpty() bool {
+	return self == nil || (len(self.SignatureDigests) == 0 && len(self.ResourceSignatureUIDs) == 0 && len(self.SignerFingerprints) == 0)
+}
+
+// SignatureDigest returns SHA-256 digest of the decoded signature, which is used in RevocationList
+func SignatureDigest(signature []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(signature))
+}
+
+// IsSignatureRevoked returns the reason if the signature or the ResourceSignature which includes it is revoked
+func (self *RevocationList) IsSignatureRevoked(signature []byte, rsigUID string) (bool, string) {
+	if self == nil {
+		return false, ""
+	}
+	digest := SignatureDigest(signature)
+	for _, revoked := range self.SignatureDigests {
+		if strings.EqualFold(revoked, digest) {
+			return true, fmt.Sprintf("the signature (sha256:%s) is revoked", digest)
+		}
+	}
+	if rsigUID != "" && ExactMatchWithPatternArray(rsigUID, self.ResourceSignatureUIDs) {
+		return true, fmt.Sprintf("ResourceSignature (uid: %s) is revoked", rsigUID)
+	}
+	return false, ""
+}
+
+// IsSignerRevoked returns the reason if all signatures by the signer are revoked
+func (self *RevocationList) IsSignerRevoked(signer *SignerInfo) (bool, string) {
+	if self == nil || signer == nil || len(signer.Fingerprint) == 0 {
+		return false, ""
+	}
+	fingerprint := string(signer.Fingerprint)
+	// fingerprints can be written with separators like `gpg --fingerprint` output
+	separators := strings.NewReplacer(":", "", " ", "")
+	for _, revoked := range self.SignerFingerprints {
+		if strings.EqualFold(separators.Replace(revoked), fingerprint) {
+			return true, fmt.Sprintf("the signer %s is revoked", signer.GetNameWithFingerprint())
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"testing"
+)
+
+func TestRevocationList(t *testing.T) {
+	sig := []byte("sample-signature")
+	digest := SignatureDigest(sig)
+	signer := &SignerInfo{Email: "signer@enterprise.com", Fingerprint: []byte("ABCD1234EF")}
+
+	var nilList *RevocationList
+	if revoked, _ := nilList.IsSignatureRevoked(sig, "uid-1"); revoked {
+		t.Errorf("nothing should be revoked by nil list")
+	}
+
+	byDigest := &RevocationList{SignatureDigests: []string{digest}}
+	byUID := &RevocationList{ResourceSignatureUIDs: []string{"uid-1"}}
+	bySigner := &RevocationList{SignerFingerprints: []string{"ab:cd:12:34:ef"}}
+
+	if revoked, _ := byDigest.IsSignatureRevoked(sig, ""); !revoked {
+		t.Errorf("signature should be revoked by digest %s", digest)
+	}
+	if revoked, _ := byDigest.IsSignatureRevoked([]byte("another-signature"), ""); revoked {
+		t.Errorf("another signature should not be revoked")
+	}
+	if revoked, _ := byUID.IsSignatureRevoked(sig, "uid-1"); !revoked {
+		t.Errorf("signature should be revoked by ResourceSignature UID")
+	}
+	if revoked, _ := byUID.IsSignatureRevoked(sig, ""); revoked {
+		t.Errorf("annotation signature should not be revoked by ResourceSignature UID")
+	}
+	if revoked, reason := bySigner.IsSignerRevoked(signer); !revoked {
+		t.Errorf("TestRevocationList() Failed\nexpected: signer is revoked\nactual: %s", reason)
+	}
+
+	merged := byDigest.Merge(byUID).Merge(bySigner)
+	if len(byDigest.ResourceSignatureUIDs) != 0 {
+		t.Errorf("Merge() should not modify the original list")
+	}
+	if merged.IsEmpty() || len(merged.SignatureDigests) != 1 || len(merged.ResourceSignatureUIDs) != 1 || len(merged.SignerFingerprints) != 1 {
+		t.Errorf("TestRevocationList() Failed\nexpected: 1 item for each\nactual: %v", merged)
+	}
+	if !nilList.Merge(nil).IsEmpty() {
+		t.Errorf("merged nil lists should be empty")
+	}
+}
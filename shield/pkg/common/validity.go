@@ -0,0 +1,89 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"fmt"
+	"time"
+
+	mapnode "github.com/IBM/integrity-enforcer/shield/pkg/util/mapnode"
+)
+
+// SignatureValidity is a validity period of a signature. It is given by `integrityshield.io/notBefore` and
+// `integrityshield.io/notAfter` annotations (RFC3339 format) which are signed together with the resource.
+type SignatureValidity struct {
+	NotBefore string `json:"notBefore,omitempty"`
+	NotAfter  string `json:"notAfter,omitempty"`
+}
+
+// GetSignatureValidity reads the validity from the annotations of a signed YAML/JSON resource
+func GetSignatureValidity(rawObj []byte) SignatureValidity {
+	node, err := mapnode.NewFromYamlBytes(rawObj)
+	if err != nil || node == nil {
+		return SignatureValidity{}
+	}
+	validity := SignatureValidity{
+		NotBefore: node.GetString(fmt.Sprintf("metadata.annotations.\"%s\"", NotBeforeAnnotationKey)),
+		NotAfter:  node.GetString(fmt.Sprintf("metadata.annotations.\"%s\"", NotAfterAnnotationKey)),
+	}
+	return validity
+}
+
+func (self SignatureValidity) IsEmpty() bool {
+	return self.NotBefore == "" && self.NotAfter == ""
+}
+
+// Check returns an error if `now` is out of the validity period. Invalid time format is regarded as an error too.
+func (self SignatureValidity) Check(now time.Time) error {
+	if self.NotBefore != "" {
+		notBefore, err := time.Parse(time.RFC3339, self.NotBefore)
+		if err != nil {
+			return fmt.Errorf("failed to parse `notBefore` of the signature; %s", err.Error())
+		}
+		if now.Before(notBefore) {
+			return fmt.Errorf("the signature is not valid before %s", self.NotBefore)
+		}
+	}
+	if self.NotAfter != "" {
+		notAfter, err := time.Parse(time.RFC3339, self.NotAfter)
+		if err != nil {
+			return fmt.Errorf("failed to parse `notAfter` of the signature; %s", err.Error())
+		}
+		if now.After(notAfter) {
+			return fmt.Errorf("the signature expired at %s", self.NotAfter)
+		}
+	}
+	return nil
+}
+
+// SignedAt returns `notBefore` as the signing time. It returns zero time if `notBefore` is not available.
+func (self SignatureValidity) SignedAt() time.Time {
+	notBefore, err := time.Parse(time.RFC3339, self.NotBefore)
+	if err != nil {
+		return time.Time{}
+	}
+	return notBefore
+}
+
+// ExpiresIn returns the duration until `notAfter`. It returns false if the signature has no valid `notAfter`.
+func (self SignatureValidity) ExpiresIn(now time.Time) (time.Duration, bool) {
+	notAfter, err := time.Parse(time.RFC3339, self.NotAfter)
+	if err != nil {
+		return 0, false
+	}
+	return notAfter.Sub(now), true
+}
@@ -0,0 +1,77 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignatureValidity(t *testing.T) {
+	yamlBytes := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: sample-cm
+  annotations:
+    integrityshield.io/notBefore: "2020-12-01T00:00:00Z"
+    integrityshield.io/notAfter: 2020-12-31T00:00:00Z
+`)
+	validity := GetSignatureValidity(yamlBytes)
+	if validity.NotBefore != "2020-12-01T00:00:00Z" || validity.NotAfter != "2020-12-31T00:00:00Z" {
+		t.Errorf("TestSignatureValidity() Failed\nexpected: 2020-12-01T00:00:00Z - 2020-12-31T00:00:00Z\nactual: %s - %s", validity.NotBefore, validity.NotAfter)
+		return
+	}
+	if err := validity.Check(time.Date(2020, 12, 15, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("signature should be valid in the validity period; %s", err.Error())
+	}
+	if err := validity.Check(time.Date(2020, 11, 15, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Errorf("signature should not be valid before notBefore")
+	}
+	if err := validity.Check(time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Errorf("signature should not be valid after notAfter")
+	}
+	if expiresIn, ok := validity.ExpiresIn(time.Date(2020, 12, 30, 0, 0, 0, 0, time.UTC)); !ok || expiresIn != 24*time.Hour {
+		t.Errorf("TestSignatureValidity() Failed\nexpected: %s\nactual: %s", 24*time.Hour, expiresIn)
+	}
+
+	if err := (SignatureValidity{NotAfter: "tomorrow"}).Check(time.Now()); err == nil {
+		t.Errorf("invalid notAfter should be rejected")
+	}
+	if empty := GetSignatureValidity([]byte("kind: ConfigMap")); !empty.IsEmpty() || empty.Check(time.Now()) != nil {
+		t.Errorf("signature without validity should be valid")
+	}
+}
+
+func TestMaxSignatureAge(t *testing.T) {
+	now := time.Now()
+	spc := SignerConfigCondition{Signers: []string{"signer-a"}, MaxSignatureAge: "720h"}
+	testCases := []struct {
+		name     string
+		signedAt time.Time
+		expected bool
+	}{
+		{"new signature", now.Add(-24 * time.Hour), false},
+		{"old signature", now.Add(-1000 * time.Hour), true},
+		{"signature without notBefore", time.Time{}, true},
+	}
+	for _, tc := range testCases {
+		vs := VerifiedSigner{Signer: &SignerInfo{Email: "signer@example.com"}, SignedAt: tc.signedAt}
+		if actual := spc.IsTooOld(vs, now); actual != tc.expected {
+			t.Errorf("TestMaxSignatureAge() Failed: %s\nexpected: %v\nactual: %v", tc.name, tc.expected, actual)
+		}
+	}
+}
@@ -452,3 +452,19 @@ func VerifyChartAndProv(chart, prov []byte, keyPathList []string) (bool, *common
 	msg := fmt.Sprintf("Failed to verify helm chart and its provenance.")
 	return false, nil, msg, nil
 }
+
+// VerifyChartAndProvWithKeyRings verifies the chart in the same way as VerifyChartAndProv with keyrings which are already loaded.
+// The keyrings are written to temporary files because helm provenance reads a keyring from a file.
+func VerifyChartAndProvWithKeyRings(chart, prov []byte, keyRings [][]byte) (bool, *common.SignerInfo, string, error) {
+	keyPathList := []string{}
+	for i, keyRing := range keyRings {
+		keyringPath := filepath.Join(tempDir, fmt.Sprintf("keyring-%d.gpg", i))
+		err := ioutil.WriteFile(keyringPath, keyRing, 0644)
+		if err != nil {
+			msg := fmt.Sprintf("Error in verifying chart file; %s", err.Error())
+			return false, nil, msg, fmt.Errorf("%s", msg)
+		}
+		keyPathList = append(keyPathList, keyringPath)
+	}
+	return VerifyChartAndProv(chart, prov, keyPathList)
+}
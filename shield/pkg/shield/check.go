@@ -0,0 +1,238 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+)
+
+/**********************************************
+
+				Check
+
+***********************************************/
+
+const (
+	inScopeCheckName                = "inScopeCheck"
+	formatCheckName                 = "formatCheck"
+	iShieldResourceCheckName        = "iShieldResourceCheck"
+	deleteCheckName                 = "deleteCheck"
+	protectedCheckName              = "protectedCheck"
+	resourceSigningProfileCheckName = "resourceSigningProfileCheck"
+)
+
+// Check is a single step of Handler.Check().
+// Checks run in order until one of them returns a decision which is not undetermined.
+// A Check may record its own steps in CheckContext.Trace, otherwise Handler records one step with its name.
+type Check interface {
+	Name() string
+	Run(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult
+}
+
+// CheckFunc is a function which can be used as a Check with NewCheck().
+type CheckFunc func(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult
+
+type funcCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+func NewCheck(name string, fn CheckFunc) Check {
+	return &funcCheck{name: name, fn: fn}
+}
+
+func (self *funcCheck) Name() string {
+	return self.name
+}
+
+func (self *funcCheck) Run(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult {
+	return self.fn(reqc, config, data, ctx)
+}
+
+var (
+	checkRegistry     []Check
+	checkRegistryLock sync.RWMutex
+)
+
+func init() {
+	RegisterCheck(NewCheck(inScopeCheckName, inScopeCheck))
+	RegisterCheck(NewCheck(formatCheckName, formatCheck))
+	RegisterCheck(NewCheck(iShieldResourceCheckName, iShieldResourceCheck))
+	RegisterCheck(NewCheck(deleteCheckName, deleteCheck))
+	RegisterCheck(&protectedCheckStep{})
+	RegisterCheck(&resourceSigningProfileCheckStep{})
+}
+
+// RegisterCheck appends the check to the default order.
+// If a check with the same name is already registered, it is replaced at the same position.
+func RegisterCheck(chk Check) {
+	checkRegistryLock.Lock()
+	defer checkRegistryLock.Unlock()
+	for i, registered := range checkRegistry {
+		if registered.Name() == chk.Name() {
+			checkRegistry[i] = chk
+			return
+		}
+	}
+	checkRegistry = append(checkRegistry, chk)
+}
+
+// RegisteredChecks returns all registered checks in the default order.
+func RegisteredChecks() []Check {
+	checkRegistryLock.RLock()
+	defer checkRegistryLock.RUnlock()
+	checks := make([]Check, len(checkRegistry))
+	copy(checks, checkRegistry)
+	return checks
+}
+
+// GetCheckChain returns checks to be run for the config.
+// Checks listed in `checks` of ShieldConfig come first in the listed order, and the others follow in the default order.
+// Disabled checks and unknown names are skipped.
+func GetCheckChain(conf *config.ShieldConfig) []Check {
+	registered := RegisteredChecks()
+	checkMap := map[string]Check{}
+	for _, chk := range registered {
+		checkMap[chk.Name()] = chk
+	}
+
+	chain := []Check{}
+	listed := map[string]bool{}
+	for _, chkConf := range conf.Checks {
+		listed[chkConf.Name] = true
+		if chk, ok := checkMap[chkConf.Name]; ok && !chkConf.Disabled {
+			chain = append(chain, chk)
+		}
+	}
+	for _, chk := range registered {
+		if !listed[chk.Name()] {
+			chain = append(chain, chk)
+		}
+	}
+	return chain
+}
+
+// ValidateCheckConfig returns an error if `checks` in ShieldConfig has a name of unregistered check.
+// protectedCheck and resourceSigningProfileCheck cannot be disabled, and protectedCheck must run before
+// resourceSigningProfileCheck because it finds the RSPs to be evaluated. Otherwise protected resources are allowed without signature.
+func ValidateCheckConfig(conf *config.ShieldConfig) error {
+	registered := map[string]bool{}
+	for _, chk := range RegisteredChecks() {
+		registered[chk.Name()] = true
+	}
+	for _, chkConf := range conf.Checks {
+		if !registered[chkConf.Name] {
+			return fmt.Errorf("unknown check \"%s\" in `checks`", chkConf.Name)
+		}
+		if chkConf.Disabled && (chkConf.Name == protectedCheckName || chkConf.Name == resourceSigningProfileCheckName) {
+			return fmt.Errorf("check \"%s\" cannot be disabled in `checks`", chkConf.Name)
+		}
+	}
+	protectedIndex, rspIndex := -1, -1
+	for i, chk := range GetCheckChain(conf) {
+		switch chk.Name() {
+		case protectedCheckName:
+			protectedIndex = i
+		case resourceSigningProfileCheckName:
+			rspIndex = i
+		}
+	}
+	if protectedIndex > rspIndex {
+		return fmt.Errorf("check \"%s\" must run before \"%s\" in `checks`", protectedCheckName, resourceSigningProfileCheckName)
+	}
+	return nil
+}
+
+// protectedCheckStep finds RSPs which protect the request, and passes them to resourceSigningProfileCheckStep.
+type protectedCheckStep struct{}
+
+func (self *protectedCheckStep) Name() string {
+	return protectedCheckName
+}
+
+func (self *protectedCheckStep) Run(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult {
+	dr, matchedProfiles := protectedCheck(reqc, config, data, ctx)
+	ctx.matchedProfiles = matchedProfiles
+	ctx.matchedRules = map[string]*common.Rule{}
+	if data.ruleTable != nil {
+		ctx.matchedRules = data.ruleTable.MatchedRules(reqc.Map(), reqc.ConditionVars())
+		ctx.conditionErrors = data.ruleTable.ConditionErrors(matchedProfiles)
+	}
+	step := ctx.addTrace(self.Name(), dr)
+	if len(ctx.matchedRules) > 0 {
+		ruleStrs := []string{}
+		for key, rule := range ctx.matchedRules {
+			ruleStrs = append(ruleStrs, fmt.Sprintf("%s %s", key, rule.String()))
+		}
+		sort.Strings(ruleStrs)
+		step.Rule = strings.Join(ruleStrs, ", ")
+	}
+	return dr
+}
+
+// resourceSigningProfileCheckStep evaluates the request with each RSP found by protectedCheckStep.
+type resourceSigningProfileCheckStep struct{}
+
+func (self *resourceSigningProfileCheckStep) Name() string {
+	return resourceSigningProfileCheckName
+}
+
+func (self *resourceSigningProfileCheckStep) Run(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult {
+	dr := undeterminedDescision()
+	var nonEnforcedDr *DecisionResult
+	for _, prof := range ctx.matchedProfiles {
+		ctx.MatchedProfile = profileKey(prof)
+		lastSigResult := ctx.SignatureEvalResult
+		lastMutResult := ctx.MutationEvalResult
+		dr = resourceSigningProfileCheck(prof, reqc, config, data, ctx)
+		step := ctx.addTrace(self.Name(), dr)
+		step.Profile = ctx.MatchedProfile
+		if rule, ok := ctx.matchedRules[ctx.MatchedProfile]; ok {
+			step.Rule = rule.String()
+		}
+		if sigResult := ctx.SignatureEvalResult; sigResult != nil && sigResult != lastSigResult {
+			step.SignatureSource = sigResult.SignatureSource
+			step.Diff = sigResult.Diff
+		}
+		if mutResult := ctx.MutationEvalResult; step.Diff == "" && mutResult != nil && mutResult != lastMutResult && mutResult.IsMutated {
+			step.Diff = mutResult.Diff
+		}
+		if dr.isAllowed() {
+			// this RSP allowed the request. will check next RSP.
+		} else if getEnforcementMode(dr.denyRSP, config) != common.EnforceMode {
+			// this RSP denied the request in detect/warn mode. keep the first result, but will check next RSP which may enforce.
+			if nonEnforcedDr == nil {
+				nonEnforcedDr = dr
+			}
+		} else {
+			// this RSP denied the request. return the result and will make AdmissionResponse.
+			return dr
+		}
+	}
+
+	if nonEnforcedDr != nil {
+		// the result will be overwritten in overwriteDecision() according to the mode of the RSP
+		return nonEnforcedDr
+	}
+	return dr
+}
@@ -17,6 +17,7 @@
 package shield
 
 import (
+	"fmt"
 	"strings"
 
 	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
@@ -73,7 +74,11 @@ func inScopeCheck(reqc *common.ReqContext, config *config.ShieldConfig, data *Ru
 }
 
 func formatCheck(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult {
-	if ok, msg := ValidateResource(reqc, config.Namespace); !ok {
+	var keys []common.VerificationKey
+	if reqc.Kind == common.SignerConfigCustomResourceKind {
+		keys = data.GetVerificationKeys(config)
+	}
+	if ok, msg := ValidateResource(reqc, config.Namespace, keys); !ok {
 		ctx.Allow = false
 		ctx.ReasonCode = common.REASON_VALIDATION_FAIL
 		ctx.Message = msg
@@ -103,7 +108,10 @@ func iShieldResourceCheck(reqc *common.ReqContext, config *config.ShieldConfig,
 	gcReq := checkIfGarbageCollectorRequest(reqc)
 	spSAReq := checkIfSpecialServiceAccountRequest(reqc)
 
-	if (iShieldOperatorResource && (adminReq || operatorReq || gcReq || spSAReq)) || (iShieldServerResource && (operatorReq || serverReq || gcReq || spSAReq)) {
+	// SignatureRevocations are server resources, but they are managed by IShield admins instead of the operator
+	adminManagedReq := adminReq && reqc.Kind == common.SignatureRevocationCustomResourceKind
+
+	if (iShieldOperatorResource && (adminReq || operatorReq || gcReq || spSAReq)) || (iShieldServerResource && (operatorReq || serverReq || gcReq || spSAReq || adminManagedReq)) {
 		ctx.Allow = true
 		ctx.Verified = true
 		ctx.ReasonCode = common.REASON_ISHIELD_ADMIN
@@ -129,7 +137,8 @@ func iShieldResourceCheck(reqc *common.ReqContext, config *config.ShieldConfig,
 }
 
 func deleteCheck(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult {
-	if reqc.IsDeleteRequest() {
+	// DELETE request protected by RSP with `protectDelete` is verified with deletion approval in resourceSigningProfileCheck
+	if reqc.IsDeleteRequest() && !checkIfDeleteProtected(reqc, config, data) {
 		ctx.Allow = true
 		ctx.Verified = true
 		ctx.ReasonCode = common.REASON_SKIP_DELETE
@@ -159,7 +168,7 @@ func protectedCheck(reqc *common.ReqContext, config *config.ShieldConfig, data *
 			Message:    common.ReasonCodeMap[common.REASON_NOT_PROTECTED].Message,
 		}, nil
 	}
-	protected, ignoreMatched, matchedProfiles := ruleTable.CheckIfProtected(reqFields)
+	protected, ignoreMatched, matchedProfiles := ruleTable.CheckIfProtected(reqFields, reqc.ConditionVars())
 	if !protected {
 		ctx.Allow = true
 		ctx.Verified = true
@@ -194,10 +203,42 @@ func resourceSigningProfileCheck(singleProfile rspapi.ResourceSigningProfile, re
 	var sigResult *common.SignatureEvalResult
 	var mutResult *common.MutationEvalResult
 
+	// requests by controllers for resources owned by a verified resource are allowed without signature
+	if owner := findVerifiedOwner(singleProfile, reqc, data); owner != nil {
+		msg := fmt.Sprintf("%s (%s %s)", common.ReasonCodeMap[common.REASON_VERIFIED_OWNER].Message, owner.GetKind(), owner.GetName())
+		ctx.Allow = true
+		ctx.Verified = true
+		ctx.ReasonCode = common.REASON_VERIFIED_OWNER
+		ctx.Message = msg
+		return &DecisionResult{
+			Type:       common.DecisionAllow,
+			Verified:   true,
+			ReasonCode: common.REASON_VERIFIED_OWNER,
+			Message:    msg,
+		}
+	}
+
+	// requests by the service accounts listed in the profile are allowed without signature
+	if saReasonCode, matched := serviceAccountCheck(singleProfile, reqc); matched {
+		msg := fmt.Sprintf("%s (%s)", common.ReasonCodeMap[saReasonCode].Message, reqc.UserName)
+		ctx.Allow = true
+		ctx.Verified = true
+		ctx.ReasonCode = saReasonCode
+		ctx.Message = msg
+		return &DecisionResult{
+			Type:       common.DecisionAllow,
+			Verified:   true,
+			ReasonCode: saReasonCode,
+			Message:    msg,
+		}
+	}
+
 	sigConf := data.GetSignerConfig()
 	rsigList := data.GetResSigList(reqc)
+	revocations, revocationErr := data.GetRevocationList()
+	keys := data.GetVerificationKeys(config)
 
-	allowed, evalReason, evalMessage, sigResult, mutResult = singleProfileCheck(singleProfile, reqc, config, sigConf, rsigList)
+	allowed, evalReason, evalMessage, sigResult, mutResult = singleProfileCheck(singleProfile, reqc, config, sigConf, rsigList, keys, revocations, revocationErr, data.DryRunDisabled())
 
 	ctx.Allow = allowed
 	ctx.ReasonCode = evalReason
@@ -227,10 +268,13 @@ func resourceSigningProfileCheck(singleProfile rspapi.ResourceSigningProfile, re
 	}
 }
 
-func singleProfileCheck(singleProfile rspapi.ResourceSigningProfile, reqc *common.ReqContext, config *config.ShieldConfig, sigConfRes *sigconfapi.SignerConfig, rsigList *rsigapi.ResourceSignatureList) (bool, int, string, *common.SignatureEvalResult, *common.MutationEvalResult) {
+func singleProfileCheck(singleProfile rspapi.ResourceSigningProfile, reqc *common.ReqContext, config *config.ShieldConfig, sigConfRes *sigconfapi.SignerConfig, rsigList *rsigapi.ResourceSignatureList, keys []common.VerificationKey, revocations *common.RevocationList, revocationErr error, dryRunDisabled bool) (bool, int, string, *common.SignatureEvalResult, *common.MutationEvalResult) {
 	var sigResult *common.SignatureEvalResult
 	var mutResult *common.MutationEvalResult
 	var err error
+	if reqc.IsDeleteRequest() && !singleProfile.Spec.ProtectDelete {
+		return true, common.REASON_SKIP_DELETE, common.ReasonCodeMap[common.REASON_SKIP_DELETE].Message, nil, nil
+	}
 	if reqc.IsUpdateRequest() {
 		mutResult, err = NewMutationChecker().Eval(reqc, singleProfile)
 		if err != nil {
@@ -241,9 +285,14 @@ func singleProfileCheck(singleProfile rspapi.ResourceSigningProfile, reqc *commo
 		}
 	}
 
+	// signatures cannot be verified without SignatureRevocations, otherwise revoked signatures would be accepted
+	if revocationErr != nil {
+		return false, common.REASON_ERROR, revocationErr.Error(), nil, mutResult
+	}
+
 	signerConfig := sigConfRes.Spec.Config
 	plugins := config.GetEnabledPlugins()
-	evaluator, err := NewSignatureEvaluator(config, signerConfig, plugins)
+	evaluator, err := NewSignatureEvaluator(config, signerConfig, keys, revocations, plugins, dryRunDisabled)
 	if err != nil {
 		return false, common.REASON_ERROR, err.Error(), nil, mutResult
 	}
@@ -261,6 +310,12 @@ func singleProfileCheck(singleProfile rspapi.ResourceSigningProfile, reqc *commo
 		message = sigResult.Error.MakeMessage()
 		if strings.HasPrefix(message, common.ReasonCodeMap[common.REASON_INVALID_SIG].Message) {
 			reasonCode = common.REASON_INVALID_SIG
+		} else if strings.HasPrefix(message, common.ReasonCodeMap[common.REASON_INVALID_CERT].Message) {
+			reasonCode = common.REASON_INVALID_CERT
+		} else if strings.HasPrefix(message, common.ReasonCodeMap[common.REASON_EXPIRED_SIG].Message) {
+			reasonCode = common.REASON_EXPIRED_SIG
+		} else if strings.HasPrefix(message, common.ReasonCodeMap[common.REASON_REVOKED_SIG].Message) {
+			reasonCode = common.REASON_REVOKED_SIG
 		} else if strings.HasPrefix(message, common.ReasonCodeMap[common.REASON_NO_VALID_KEYRING].Message) {
 			reasonCode = common.REASON_NO_VALID_KEYRING
 		} else if strings.HasPrefix(message, common.ReasonCodeMap[common.REASON_NO_MATCH_SIGNER_CONFIG].Message) {
@@ -24,6 +24,8 @@ import (
 	"strings"
 	"testing"
 
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
 	"github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
 )
 
@@ -150,3 +152,43 @@ func testRSPCheck(t *testing.T, caseNum int) {
 		t.Logf("[Case %s] Test for resourceSigningProfileCheck() passed.", strconv.Itoa(caseNum))
 	}
 }
+
+func TestServiceAccountCheck(t *testing.T) {
+	kind := common.RulePattern("ConfigMap")
+	secretKind := common.RulePattern("Secret")
+	profile := rspapi.ResourceSigningProfile{
+		Spec: rspapi.ResourceSigningProfileSpec{
+			ServiceAccountPatterns: []*common.ServiceAccountPattern{
+				{
+					Match:               &common.RequestPatternWithNamespace{RequestPattern: &common.RequestPattern{Kind: &kind}},
+					ServiceAccountNames: []string{"system:serviceaccount:secure-ns:app-operator"},
+				},
+				{
+					Except:              &common.RequestPatternWithNamespace{RequestPattern: &common.RequestPattern{Kind: &secretKind}},
+					ServiceAccountNames: []string{"system:serviceaccount:ops:*"},
+				},
+			},
+		},
+	}
+	testCases := []struct {
+		userName           string
+		kind               string
+		operation          string
+		expectedMatched    bool
+		expectedReasonCode int
+	}{
+		{"system:serviceaccount:secure-ns:app-operator", "ConfigMap", "CREATE", true, common.REASON_VERIFIED_SA},
+		{"system:serviceaccount:secure-ns:app-operator", "ConfigMap", "UPDATE", true, common.REASON_UPDATE_BY_SA},
+		{"system:serviceaccount:secure-ns:app-operator", "Deployment", "CREATE", false, common.REASON_UNEXPECTED},
+		{"system:serviceaccount:secure-ns:default", "ConfigMap", "CREATE", false, common.REASON_UNEXPECTED},
+		{"system:serviceaccount:ops:deployer", "Deployment", "CREATE", true, common.REASON_VERIFIED_SA},
+		{"system:serviceaccount:ops:deployer", "Secret", "CREATE", false, common.REASON_UNEXPECTED},
+	}
+	for _, tc := range testCases {
+		reqc := &common.ReqContext{UserName: tc.userName, Kind: tc.kind, Operation: tc.operation, Namespace: "secure-ns"}
+		reasonCode, matched := serviceAccountCheck(profile, reqc)
+		if matched != tc.expectedMatched || reasonCode != tc.expectedReasonCode {
+			t.Errorf("serviceAccountCheck() Failed for %s %s by %s\nexpected: %t, %d\nactual: %t, %d", tc.operation, tc.kind, tc.userName, tc.expectedMatched, tc.expectedReasonCode, matched, reasonCode)
+		}
+	}
+}
@@ -26,12 +26,14 @@ import (
 	sigconfapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signerconfig/v1alpha1"
 	rspclient "github.com/IBM/integrity-enforcer/shield/pkg/client/resourcesigningprofile/clientset/versioned/typed/resourcesigningprofile/v1alpha1"
 	"github.com/IBM/integrity-enforcer/shield/pkg/util/kubeutil"
+	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
 
 	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
 	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
 	admv1 "k8s.io/api/admission/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -144,7 +146,7 @@ func createOrUpdateEvent(reqc *common.ReqContext, ctx *CheckContext, sconfig *co
 	return nil
 }
 
-func updateRSPStatus(rsp *rspapi.ResourceSigningProfile, reqc *common.ReqContext, errMsg string) error {
+func updateRSPStatus(rsp *rspapi.ResourceSigningProfile, reqc *common.ReqContext, errMsg string, mode common.IntegrityShieldMode) error {
 	if rsp == nil {
 		return nil
 	}
@@ -166,7 +168,7 @@ func updateRSPStatus(rsp *rspapi.ResourceSigningProfile, reqc *common.ReqContext
 	}
 
 	req := common.NewRequestFromReqContext(reqc)
-	rspNew := rspOrg.UpdateStatus(req, errMsg)
+	rspNew := rspOrg.UpdateStatus(req, errMsg, mode)
 
 	_, err = client.ResourceSigningProfiles(rspNamespace).Update(context.Background(), rspNew, metav1.UpdateOptions{})
 	if err != nil {
@@ -183,6 +185,21 @@ func checkIfProfileTargetNamespace(reqNamespace, shieldNamespace string, data *R
 	return ruleTable.CheckIfTargetNamespace(reqNamespace)
 }
 
+// checkIfDeleteProtected returns true if any RSP which protects the request requires deletion approval
+func checkIfDeleteProtected(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData) bool {
+	ruleTable := data.GetRuleTable(config.Namespace)
+	if ruleTable == nil {
+		return false
+	}
+	_, _, matchedProfiles := ruleTable.CheckIfProtected(reqc.Map(), reqc.ConditionVars())
+	for _, prof := range matchedProfiles {
+		if prof.Spec.ProtectDelete {
+			return true
+		}
+	}
+	return false
+}
+
 func checkIfInScopeNamespace(reqNamespace string, config *config.ShieldConfig) bool {
 	inScopeNSSelector := config.InScopeNamespaceSelector
 	if inScopeNSSelector == nil {
@@ -197,7 +214,7 @@ func checkIfDryRunAdmission(reqc *common.ReqContext) bool {
 
 func checkIfUnprocessedInIShield(reqc *common.ReqContext, config *config.ShieldConfig) bool {
 	for _, d := range config.Ignore {
-		if d.Match(reqc.Map()) {
+		if d.MatchExisting(reqc.Map()) {
 			return true
 		}
 	}
@@ -267,44 +284,111 @@ func checkIfSpecialServiceAccountRequest(reqc *common.ReqContext) bool {
 	return false
 }
 
+// getBreakGlassConditions returns break glass conditions which are not expired yet
 func getBreakGlassConditions(signerConfig *sigconfapi.SignerConfig) []common.BreakGlassCondition {
 	conditions := []common.BreakGlassCondition{}
-	if signerConfig != nil {
-		conditions = append(conditions, signerConfig.Spec.Config.BreakGlass...)
+	if signerConfig != nil && signerConfig.Spec.Config != nil {
+		now := time.Now()
+		for _, d := range signerConfig.Spec.Config.BreakGlass {
+			if !d.IsExpired(now) {
+				conditions = append(conditions, d)
+			}
+		}
 	}
 	return conditions
 }
 
-func checkIfBreakGlassEnabled(reqc *common.ReqContext, signerConfig *sigconfapi.SignerConfig) bool {
+// getBreakGlassCondition returns the break glass condition which is enabled for the request, or nil if not enabled.
+func getBreakGlassCondition(reqc *common.ReqContext, signerConfig *sigconfapi.SignerConfig) *common.BreakGlassCondition {
 
 	conditions := getBreakGlassConditions(signerConfig)
-	breakGlassEnabled := false
 	if reqc.ResourceScope == "Namespaced" {
 		reqNs := reqc.Namespace
-		for _, d := range conditions {
+		for i, d := range conditions {
 			if d.Scope == common.ScopeUndefined || d.Scope == common.ScopeNamespaced {
 				for _, ns := range d.Namespaces {
 					if reqNs == ns {
-						breakGlassEnabled = true
-						break
+						return &conditions[i]
 					}
 				}
 			}
-			if breakGlassEnabled {
-				break
-			}
 		}
 	} else {
-		for _, d := range conditions {
+		for i, d := range conditions {
 			if d.Scope == common.ScopeCluster {
-				breakGlassEnabled = true
-				break
+				return &conditions[i]
 			}
 		}
 	}
-	return breakGlassEnabled
+	return nil
+}
+
+func checkIfBreakGlassEnabled(reqc *common.ReqContext, signerConfig *sigconfapi.SignerConfig) bool {
+	return getBreakGlassCondition(reqc, signerConfig) != nil
 }
 
 func checkIfDetectOnly(sconf *config.ShieldConfig) bool {
 	return (sconf.Mode == config.DetectMode)
 }
+
+// getEnforcementMode returns the mode for the decision made by the profile.
+// `Mode` in the profile is preferred, and ShieldConfig.Mode is used if it is empty or no profile is given.
+func getEnforcementMode(profile *rspapi.ResourceSigningProfile, sconf *config.ShieldConfig) common.IntegrityShieldMode {
+	if profile != nil && profile.Spec.Mode != common.UnknownMode {
+		return profile.Spec.Mode
+	}
+	if checkIfDetectOnly(sconf) {
+		return common.DetectMode
+	}
+	return common.EnforceMode
+}
+
+// findVerifiedOwner returns the owner with `integrityshield.io/resourceIntegrity: verified` label, which is found by following
+// the controller references from the requested resource up to the max depth of `ownerCheck` in the profile.
+// The request must be made by one of the controller service accounts.
+func findVerifiedOwner(profile rspapi.ResourceSigningProfile, reqc *common.ReqContext, data *RunData) *unstructured.Unstructured {
+	ownerCheck := profile.Spec.OwnerCheck
+	if ownerCheck == nil || reqc.IsDeleteRequest() || !ownerCheck.MatchControllerServiceAccount(reqc.UserName) {
+		return nil
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(reqc.RawObject); err != nil {
+		return nil
+	}
+	for depth := 0; depth < ownerCheck.GetMaxDepth(); depth++ {
+		ref := metav1.GetControllerOf(obj)
+		if ref == nil {
+			return nil
+		}
+		owner, err := data.GetOwner(*ref, reqc.Namespace)
+		if err != nil {
+			logger.Debug(fmt.Sprintf("failed to get owner %s %s; %s", ref.Kind, ref.Name, err.Error()))
+			return nil
+		}
+		// the owner in the reference might be deleted and another one might be created with the same name
+		if owner.GetUID() != ref.UID {
+			return nil
+		}
+		if common.NewResourceLabel(owner.GetLabels()).IntegrityVerified() {
+			return owner
+		}
+		obj = owner
+	}
+	return nil
+}
+
+// serviceAccountCheck returns true if the request is made by a service account in `serviceAccountPatterns` of the profile.
+// UPDATE requests are recorded as `updated-by-sa`, and the others are recorded as `verified-sa`.
+func serviceAccountCheck(profile rspapi.ResourceSigningProfile, reqc *common.ReqContext) (int, bool) {
+	reqFields := reqc.Map()
+	for _, pattern := range profile.Spec.ServiceAccountPatterns {
+		if pattern == nil || !pattern.MatchWith(reqFields) {
+			continue
+		}
+		if reqc.IsUpdateRequest() {
+			return common.REASON_UPDATE_BY_SA, true
+		}
+		return common.REASON_VERIFIED_SA, true
+	}
+	return common.REASON_UNEXPECTED, false
+}
@@ -0,0 +1,100 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"reflect"
+	"testing"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+)
+
+func checkNames(checks []Check) []string {
+	names := []string{}
+	for _, chk := range checks {
+		names = append(names, chk.Name())
+	}
+	return names
+}
+
+func TestGetCheckChain(t *testing.T) {
+	orgRegistry := RegisteredChecks()
+	defer func() {
+		checkRegistryLock.Lock()
+		checkRegistry = orgRegistry
+		checkRegistryLock.Unlock()
+	}()
+
+	defaultOrder := []string{inScopeCheckName, formatCheckName, iShieldResourceCheckName, deleteCheckName, protectedCheckName, resourceSigningProfileCheckName}
+	actual := checkNames(GetCheckChain(&config.ShieldConfig{}))
+	if !reflect.DeepEqual(actual, defaultOrder) {
+		t.Errorf("default check chain is wrong\nexpected: %v\nactual: %v", defaultOrder, actual)
+	}
+
+	conf := &config.ShieldConfig{
+		Checks: []config.CheckConfig{
+			{Name: deleteCheckName},
+			{Name: formatCheckName, Disabled: true},
+		},
+	}
+	expected := []string{deleteCheckName, inScopeCheckName, iShieldResourceCheckName, protectedCheckName, resourceSigningProfileCheckName}
+	actual = checkNames(GetCheckChain(conf))
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("configured check chain is wrong\nexpected: %v\nactual: %v", expected, actual)
+	}
+
+	denyAll := func(reqc *common.ReqContext, config *config.ShieldConfig, data *RunData, ctx *CheckContext) *DecisionResult {
+		return &DecisionResult{Type: common.DecisionDeny}
+	}
+	RegisterCheck(NewCheck("imagePolicyCheck", denyAll))
+	conf = &config.ShieldConfig{Checks: []config.CheckConfig{{Name: "imagePolicyCheck"}}}
+	if err := ValidateCheckConfig(conf); err != nil {
+		t.Errorf("registered check should be valid in config; %s", err.Error())
+	}
+	actual = checkNames(GetCheckChain(conf))
+	if len(actual) != len(defaultOrder)+1 || actual[0] != "imagePolicyCheck" {
+		t.Errorf("registered check should be run first; actual: %v", actual)
+	}
+
+	conf = &config.ShieldConfig{Checks: []config.CheckConfig{{Name: "unknownCheck"}}}
+	if err := ValidateCheckConfig(conf); err == nil {
+		t.Errorf("unknown check should be rejected")
+	}
+}
+
+func TestValidateCheckConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		checks   []config.CheckConfig
+		expected bool
+	}{
+		{"default", nil, true},
+		{"reordered", []config.CheckConfig{{Name: deleteCheckName}, {Name: protectedCheckName}, {Name: resourceSigningProfileCheckName}}, true},
+		{"disableFormatCheck", []config.CheckConfig{{Name: formatCheckName, Disabled: true}}, true},
+		{"disableProtectedCheck", []config.CheckConfig{{Name: protectedCheckName, Disabled: true}}, false},
+		{"disableRSPCheck", []config.CheckConfig{{Name: resourceSigningProfileCheckName, Disabled: true}}, false},
+		{"rspCheckFirst", []config.CheckConfig{{Name: resourceSigningProfileCheckName}}, false},
+		{"protectedCheckAfterRSPCheck", []config.CheckConfig{{Name: resourceSigningProfileCheckName}, {Name: protectedCheckName}}, false},
+	}
+	for _, tc := range testCases {
+		err := ValidateCheckConfig(&config.ShieldConfig{Checks: tc.checks})
+		if actual := err == nil; actual != tc.expected {
+			t.Errorf("ValidateCheckConfig() Failed (%s)\nexpected: %v\nactual: %v (%v)", tc.name, tc.expected, actual, err)
+		}
+	}
+}
@@ -17,9 +17,12 @@
 package shield
 
 import (
+	"fmt"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
 	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
 	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
 )
@@ -32,6 +35,7 @@ import (
 
 type CheckContext struct {
 	DetectOnlyModeEnabled bool   `json:"detectOnly"`
+	WarnOnlyModeEnabled   bool   `json:"warnOnly"`
 	BreakGlassModeEnabled bool   `json:"breakGlass"`
 	IgnoredSA             bool   `json:"ignoredSA"`
 	Protected             bool   `json:"protected"`
@@ -42,11 +46,35 @@ type CheckContext struct {
 	AbortReason           string `json:"abortReason"`
 	Error                 error  `json:"error"`
 	Message               string `json:"msg"`
+	MatchedProfile        string `json:"matchedProfile"`
 
 	SignatureEvalResult *common.SignatureEvalResult `json:"signature"`
 	MutationEvalResult  *common.MutationEvalResult  `json:"mutation"`
 
 	ReasonCode int `json:"reasonCode"`
+
+	Trace []*CheckStep `json:"trace"`
+
+	// passed from protectedCheck to resourceSigningProfileCheck
+	matchedProfiles []rspapi.ResourceSigningProfile
+	matchedRules    map[string]*common.Rule
+	// errors of conditions which cannot be compiled in the matched RSPs
+	conditionErrors []string
+
+	// the original deny reason of a request allowed by detect mode, warn mode or break glass
+	denyMessage string
+}
+
+// CheckStep is a record of a single check in Handler.Check(), which is used for explaining the decision.
+type CheckStep struct {
+	Name            string `json:"name"`
+	Result          string `json:"result"`
+	ReasonCode      string `json:"reasonCode,omitempty"`
+	Message         string `json:"message,omitempty"`
+	Profile         string `json:"profile,omitempty"`
+	Rule            string `json:"rule,omitempty"`
+	SignatureSource string `json:"signatureSource,omitempty"`
+	Diff            string `json:"diff,omitempty"`
 }
 
 func InitCheckContext(config *config.ShieldConfig) *CheckContext {
@@ -68,6 +96,65 @@ func InitCheckContext(config *config.ShieldConfig) *CheckContext {
 	return cc
 }
 
+func (self *CheckContext) addTrace(name string, dr *DecisionResult) *CheckStep {
+	step := &CheckStep{
+		Name:   name,
+		Result: string(dr.Type),
+	}
+	if !dr.isUndetermined() {
+		step.ReasonCode = common.ReasonCodeMap[dr.ReasonCode].Code
+		step.Message = dr.Message
+	}
+	self.Trace = append(self.Trace, step)
+	return step
+}
+
+// max length of a single warning; apiserver may truncate a longer one
+const maxWarningLength = 256
+
+// summarizeTrace makes short messages of the trace which can be returned as admission warnings.
+// undetermined steps are skipped because they have nothing to explain.
+func (self *CheckContext) summarizeTrace() []string {
+	summary := []string{}
+	for _, step := range self.Trace {
+		if step.Result == string(common.DecisionUndetermined) && step.Rule == "" {
+			continue
+		}
+		name := step.Name
+		if step.Profile != "" {
+			name = fmt.Sprintf("%s(%s)", name, step.Profile)
+		}
+		msg := fmt.Sprintf("[IntegrityShield] %s: %s", name, step.Result)
+		if step.ReasonCode != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, step.ReasonCode)
+		}
+		if step.Rule != "" {
+			msg = fmt.Sprintf("%s, rule: %s", msg, step.Rule)
+		}
+		if step.SignatureSource != "" {
+			msg = fmt.Sprintf("%s, signature: %s", msg, step.SignatureSource)
+		}
+		if step.Diff != "" {
+			msg = fmt.Sprintf("%s, diff: %s", msg, step.Diff)
+		}
+		summary = append(summary, trimWarning(msg))
+	}
+	return summary
+}
+
+// trimWarning truncates the message to maxWarningLength bytes on a rune boundary, so that the warning is valid UTF-8.
+func trimWarning(msg string) string {
+	if len(msg) <= maxWarningLength {
+		return msg
+	}
+	suffix := " ..."
+	cut := maxWarningLength - len(suffix)
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + suffix
+}
+
 func (self *CheckContext) convertToLogRecord(reqc *common.ReqContext) map[string]interface{} {
 
 	// cc := self
@@ -99,6 +186,9 @@ func (self *CheckContext) convertToLogRecord(reqc *common.ReqContext) map[string
 		"msg":             self.Message,
 		"breakglass":      self.BreakGlassModeEnabled,
 		"detectOnly":      self.DetectOnlyModeEnabled,
+		"warnOnly":        self.WarnOnlyModeEnabled,
+		"matchedProfile":  self.MatchedProfile,
+		"trace":           self.Trace,
 
 		//reason code
 		"reasonCode": common.ReasonCodeMap[self.ReasonCode].Code,
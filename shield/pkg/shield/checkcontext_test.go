@@ -0,0 +1,62 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+)
+
+func TestSummarizeTrace(t *testing.T) {
+	ctx := &CheckContext{}
+	ctx.addTrace("inScopeCheck", undeterminedDescision())
+	step := ctx.addTrace("resourceSigningProfileCheck", &DecisionResult{
+		Type:       common.DecisionDeny,
+		ReasonCode: common.REASON_INVALID_SIG,
+		Message:    "invalid signature",
+	})
+	step.Profile = "secure-ns/sample-rsp"
+	step.SignatureSource = SignatureSourceAnnotation
+	step.Diff = strings.Repeat("x", 500)
+
+	summary := ctx.summarizeTrace()
+	if len(summary) != 1 {
+		t.Errorf("undetermined step should be skipped; actual: %v", summary)
+		return
+	}
+	expectedPrefix := "[IntegrityShield] resourceSigningProfileCheck(secure-ns/sample-rsp): deny (invalid-signature), signature: annotation, diff: "
+	if !strings.HasPrefix(summary[0], expectedPrefix) {
+		t.Errorf("\nexpected prefix: %s\nactual: %s", expectedPrefix, summary[0])
+	}
+	if len(summary[0]) > maxWarningLength {
+		t.Errorf("warning should be trimmed to %d chars; actual: %d", maxWarningLength, len(summary[0]))
+	}
+}
+
+func TestTrimWarning(t *testing.T) {
+	msg := strings.Repeat("あ", maxWarningLength)
+	trimmed := trimWarning(msg)
+	if len(trimmed) > maxWarningLength || !utf8.ValidString(trimmed) || !strings.HasSuffix(trimmed, " ...") {
+		t.Errorf("warning should be trimmed on a rune boundary within %d bytes; actual: %d bytes, %s", maxWarningLength, len(trimmed), trimmed)
+	}
+	if short := "[IntegrityShield] short message"; trimWarning(short) != short {
+		t.Errorf("short warning should not be trimmed; actual: %s", trimWarning(short))
+	}
+}
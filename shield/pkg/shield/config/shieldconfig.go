@@ -17,6 +17,10 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"time"
+
 	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
 	"github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
 	"github.com/jinzhu/copier"
@@ -49,6 +53,8 @@ type ShieldConfig struct {
 	Ignore                   []common.RequestPattern   `json:"ignore,omitempty"`
 	Mode                     IntegrityShieldMode       `json:"mode,omitempty"`
 	Plugin                   []PluginConfig            `json:"plugin,omitempty"`
+	Checks                   []CheckConfig             `json:"checks,omitempty"`
+	SignatureStores          []SignatureStoreConfig    `json:"signatureStores,omitempty"`
 	CommonProfile            *common.CommonProfile     `json:"commonProfile,omitempty"`
 
 	Namespace          string   `json:"namespace,omitempty"`
@@ -85,6 +91,38 @@ type PluginConfig struct {
 	Enabled bool   `json:"enabled,omitempty"`
 }
 
+// CheckConfig changes the order of the checks in IShield server or disables one.
+// Listed checks run first in the listed order, and the others run after them in the default order.
+type CheckConfig struct {
+	Name     string `json:"name,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+const (
+	SignatureStoreTypeHTTP      = "http"
+	SignatureStoreTypeConfigMap = "configmap"
+)
+
+// SignatureStoreConfig is an external store of signatures, which is looked up when a request has
+// neither signature annotations nor ResourceSignature.
+type SignatureStoreConfig struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+	// for `http` store, signatures are looked up at `<url>?apiVersion=<>&kind=<>&namespace=<>&name=<>`
+	URL     string `json:"url,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+	// for `configmap` store, the default namespace is the signature namespace
+	ConfigMapName      string `json:"configMapName,omitempty"`
+	ConfigMapNamespace string `json:"configMapNamespace,omitempty"`
+}
+
+func (self SignatureStoreConfig) GetName() string {
+	if self.Name != "" {
+		return self.Name
+	}
+	return self.Type
+}
+
 func (self *IShieldResourceCondition) IsOperatorResource(ref *common.ResourceRef) bool {
 	for _, refi := range self.OperatorResources {
 		if refi.EqualsWithoutVersionCheck(ref) {
@@ -96,7 +134,7 @@ func (self *IShieldResourceCondition) IsOperatorResource(ref *common.ResourceRef
 
 func (self *IShieldResourceCondition) IsServerResource(ref *common.ResourceRef) bool {
 	for _, refi := range self.ServerResources {
-		if refi.EqualsWithoutVersionCheck(ref) {
+		if refi.MatchWithoutVersionCheck(ref) {
 			return true
 		}
 	}
@@ -160,6 +198,7 @@ func (ec *ShieldConfig) PatchEnabled(reqc *common.ReqContext) bool {
 	return ec.Patch.Enabled
 }
 
+// LogConfig fills in the default values of `log` which are not specified, and returns it.
 func (ec *ShieldConfig) LogConfig() *LoggingScopeConfig {
 	conf := ec.Log
 
@@ -173,6 +212,7 @@ func (ec *ShieldConfig) LogConfig() *LoggingScopeConfig {
 			IncludeRequest: false,
 			IncludeRelease: false,
 		}
+		ec.Log = lc
 	}
 
 	if lc.ConsoleLog == nil {
@@ -239,6 +279,47 @@ func (ec *ShieldConfig) ContextLogEnabled(reqc *common.ReqContext) bool {
 	return enabled
 }
 
+// Validate checks if this config can be used by IShield server.
+// A config which does not pass this check should not replace the running one.
+// `log` is not checked here because LogConfig() fills in the default values.
+func (ec *ShieldConfig) Validate() error {
+	if ec.Namespace == "" {
+		return fmt.Errorf("`namespace` must be specified")
+	}
+	if ec.Mode != UnknownMode && ec.Mode != EnforceMode && ec.Mode != DetectMode {
+		return fmt.Errorf("`mode` must be either \"%s\" or \"%s\", but got \"%s\"", EnforceMode, DetectMode, ec.Mode)
+	}
+	checkNames := map[string]bool{}
+	for _, chk := range ec.Checks {
+		if chk.Name == "" {
+			return fmt.Errorf("`checks[].name` must be specified")
+		}
+		if checkNames[chk.Name] {
+			return fmt.Errorf("check \"%s\" is listed more than once in `checks`", chk.Name)
+		}
+		checkNames[chk.Name] = true
+	}
+	for _, store := range ec.SignatureStores {
+		if store.Type == SignatureStoreTypeHTTP {
+			if u, err := url.Parse(store.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("`signatureStores[].url` must be http(s) URL for \"%s\" store, but got \"%s\"", store.GetName(), store.URL)
+			}
+		} else if store.Type == SignatureStoreTypeConfigMap {
+			if store.ConfigMapName == "" {
+				return fmt.Errorf("`signatureStores[].configMapName` must be specified for \"%s\" store", store.GetName())
+			}
+		} else {
+			return fmt.Errorf("`signatureStores[].type` must be either \"%s\" or \"%s\", but got \"%s\"", SignatureStoreTypeHTTP, SignatureStoreTypeConfigMap, store.Type)
+		}
+		if store.Timeout != "" {
+			if _, err := time.ParseDuration(store.Timeout); err != nil {
+				return fmt.Errorf("`signatureStores[].timeout` must be a duration like \"5s\" for \"%s\" store; %s", store.GetName(), err.Error())
+			}
+		}
+	}
+	return nil
+}
+
 func (ec *ShieldConfig) GetEnabledPlugins() map[string]bool {
 	plugins := map[string]bool{}
 	for _, plg := range ec.Plugin {
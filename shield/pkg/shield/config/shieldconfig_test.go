@@ -0,0 +1,121 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"testing"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+)
+
+func TestValidate(t *testing.T) {
+	validConfig := func() *ShieldConfig {
+		return &ShieldConfig{
+			Namespace: "integrity-shield-operator-system",
+			Mode:      EnforceMode,
+			Log: &LoggingScopeConfig{
+				ConsoleLog: &LogScopeConfig{Enabled: true},
+				ContextLog: &LogScopeConfig{Enabled: false},
+			},
+		}
+	}
+
+	if err := validConfig().Validate(); err != nil {
+		t.Errorf("valid config should pass validation; %s", err.Error())
+	}
+
+	noNamespace := validConfig()
+	noNamespace.Namespace = ""
+	if err := noNamespace.Validate(); err == nil {
+		t.Errorf("config without namespace should be rejected")
+	}
+
+	badMode := validConfig()
+	badMode.Mode = IntegrityShieldMode("audit")
+	if err := badMode.Validate(); err == nil {
+		t.Errorf("config with unknown mode should be rejected")
+	}
+
+	dupCheck := validConfig()
+	dupCheck.Checks = []CheckConfig{{Name: "formatCheck"}, {Name: "formatCheck", Disabled: true}}
+	if err := dupCheck.Validate(); err == nil {
+		t.Errorf("config with duplicated check should be rejected")
+	}
+
+	stores := validConfig()
+	stores.SignatureStores = []SignatureStoreConfig{
+		{Type: SignatureStoreTypeHTTP, URL: "https://signatures.enterprise.com/api/v1/signatures", Timeout: "5s"},
+		{Type: SignatureStoreTypeConfigMap, ConfigMapName: "signature-store"},
+	}
+	if err := stores.Validate(); err != nil {
+		t.Errorf("config with signature stores should pass validation; %s", err.Error())
+	}
+
+	badStore := validConfig()
+	badStore.SignatureStores = []SignatureStoreConfig{{Type: SignatureStoreTypeHTTP, URL: "signatures.enterprise.com"}}
+	if err := badStore.Validate(); err == nil {
+		t.Errorf("http signature store without valid url should be rejected")
+	}
+
+	// `log` is optional because the default values are used
+	noLog := validConfig()
+	noLog.Log = nil
+	if err := noLog.Validate(); err != nil {
+		t.Errorf("config without log should pass validation; %s", err.Error())
+	}
+	noConsoleLog := validConfig()
+	noConsoleLog.Log.ConsoleLog = nil
+	if err := noConsoleLog.Validate(); err != nil {
+		t.Errorf("config without log.consoleLog should pass validation; %s", err.Error())
+	}
+}
+
+func TestLogConfigDefault(t *testing.T) {
+	ec := &ShieldConfig{Namespace: "integrity-shield-operator-system"}
+	lc := ec.LogConfig()
+	if ec.Log != lc || lc.ConsoleLog == nil || !lc.ConsoleLog.Enabled || lc.ContextLog == nil || lc.ContextLog.Enabled {
+		t.Errorf("default log config should be set; actual: %v", ec.Log)
+		return
+	}
+	if enabled, _ := ec.ConsoleLogEnabled(&common.ReqContext{}); enabled {
+		t.Errorf("console log without inScope should not be enabled for any request")
+	}
+}
+
+func TestIsServerResource(t *testing.T) {
+	ns := "integrity-shield-operator-system"
+	cond := &IShieldResourceCondition{
+		ServerResources: []*common.ResourceRef{
+			{Kind: "SignerConfig", Name: "signer-config", Namespace: ns},
+			{Kind: common.SignatureRevocationCustomResourceKind, Name: common.AnyResourceName, Namespace: ns},
+		},
+	}
+	testCases := []struct {
+		ref      *common.ResourceRef
+		expected bool
+	}{
+		{&common.ResourceRef{Kind: "SignerConfig", Name: "signer-config", Namespace: ns}, true},
+		{&common.ResourceRef{Kind: "SignerConfig", Name: "another-config", Namespace: ns}, false},
+		{&common.ResourceRef{Kind: common.SignatureRevocationCustomResourceKind, Name: "incident-123", Namespace: ns}, true},
+		{&common.ResourceRef{Kind: common.SignatureRevocationCustomResourceKind, Name: "incident-123", Namespace: "default"}, false},
+	}
+	for _, tc := range testCases {
+		if actual := cond.IsServerResource(tc.ref); actual != tc.expected {
+			t.Errorf("IsServerResource() Failed for %s/%s/%s\nexpected: %v\nactual: %v", tc.ref.Kind, tc.ref.Namespace, tc.ref.Name, tc.expected, actual)
+		}
+	}
+}
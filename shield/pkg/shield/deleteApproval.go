@@ -0,0 +1,112 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"time"
+
+	vrsig "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	mapnode "github.com/IBM/integrity-enforcer/shield/pkg/util/mapnode"
+	ishieldyaml "github.com/IBM/integrity-enforcer/shield/pkg/util/yaml"
+)
+
+/**********************************************
+
+                DeleteApproval
+
+***********************************************/
+
+// the message of deletion approval is a YAML like below, and `expiry` must be RFC3339 format.
+//
+//	apiVersion: v1
+//	kind: ConfigMap
+//	metadata:
+//	  name: sample-cm
+//	  namespace: secure-ns
+//	expiry: "2020-12-31T00:00:00Z"
+const deleteApprovalExpiryKey = "expiry"
+
+// GetDeleteApproval finds a signed deletion approval for the target of DELETE request.
+func (self *ConcreteSignatureEvaluator) GetDeleteApproval(ref *common.ResourceRef, reqc *common.ReqContext, resSigList *vrsig.ResourceSignatureList) *GeneralSignature {
+
+	//1. pick deletion approval from metadata.annotations of the live object
+	approval := reqc.OrgMetadata.Annotations.DeleteApprovalAnnotations()
+	if approval.Signature != "" {
+		message := ishieldyaml.Decompress(ishieldyaml.Base64decode(approval.Message))
+		if expiry, ok := matchDeleteApproval(message, ref); ok {
+			return &GeneralSignature{
+				SignType: SignedResourceTypeDelete,
+				data:     map[string]string{"signature": ishieldyaml.Base64decode(approval.Signature), "message": message, "certificate": ishieldyaml.Base64decode(approval.Certificate), "expiry": expiry},
+				option:   map[string]bool{"matchRequired": false, "scopedSignature": false},
+			}
+		}
+	}
+
+	//2. pick deletion approval from ResourceSignature with `delete` type
+	if resSigList != nil {
+		for _, rsig := range resSigList.Items {
+			for _, si := range rsig.Spec.Data {
+				if si.Type != vrsig.SignatureTypeDelete {
+					continue
+				}
+				message := ishieldyaml.Decompress(ishieldyaml.Base64decode(si.Message))
+				if expiry, ok := matchDeleteApproval(message, ref); ok {
+					return &GeneralSignature{
+						SignType: SignedResourceTypeDelete,
+						data:     map[string]string{"signature": ishieldyaml.Base64decode(si.Signature), "message": message, "certificate": ishieldyaml.Base64decode(si.Certificate), "expiry": expiry, "resourceSignatureUID": string(rsig.GetUID())},
+						option:   map[string]bool{"matchRequired": false, "scopedSignature": false},
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchDeleteApproval returns the expiry if the approval message is for the resource.
+// unlike signatures for resource, name and namespace must be identical with the target.
+func matchDeleteApproval(message string, ref *common.ResourceRef) (string, bool) {
+	node, err := mapnode.NewFromYamlBytes([]byte(message))
+	if err != nil || node == nil {
+		return "", false
+	}
+	if node.GetString("apiVersion") != ref.ApiVersion ||
+		node.GetString("kind") != ref.Kind ||
+		node.GetString("metadata.name") != ref.Name ||
+		node.GetString("metadata.namespace") != ref.Namespace {
+		return "", false
+	}
+	return node.GetString(deleteApprovalExpiryKey), true
+}
+
+// checkDeleteApprovalExpiry returns the reason if the deletion approval cannot be used anymore.
+func checkDeleteApprovalExpiry(sig *GeneralSignature, now time.Time) string {
+	expiryStr := sig.data["expiry"]
+	if expiryStr == "" {
+		return fmt.Sprintf("the deletion approval in %s has no `%s`", sig.Source(), deleteApprovalExpiryKey)
+	}
+	expiry, err := time.Parse(time.RFC3339, expiryStr)
+	if err != nil {
+		return fmt.Sprintf("failed to parse `%s` of the deletion approval in %s; %s", deleteApprovalExpiryKey, sig.Source(), err.Error())
+	}
+	if now.After(expiry) {
+		return fmt.Sprintf("the deletion approval in %s expired at %s", sig.Source(), expiryStr)
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	vrsig "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testDeleteApprovalMessage = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: sample-cm
+  namespace: secure-ns
+expiry: "2020-12-31T00:00:00Z"
+`
+
+func newTestDeleteRequest(annotations map[string]string) *common.ReqContext {
+	oldObj := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":        "sample-cm",
+			"namespace":   "secure-ns",
+			"annotations": annotations,
+		},
+	}
+	oldObjBytes, _ := json.Marshal(oldObj)
+	dryRun := false
+	req := &admv1.AdmissionRequest{
+		UID:       types.UID("test-uid"),
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		Name:      "sample-cm",
+		Namespace: "secure-ns",
+		Operation: admv1.Delete,
+		OldObject: runtime.RawExtension{Raw: oldObjBytes},
+		DryRun:    &dryRun,
+	}
+	return common.NewReqContext(req)
+}
+
+func TestGetDeleteApproval(t *testing.T) {
+	evaluator := &ConcreteSignatureEvaluator{}
+	encodedMsg := base64.StdEncoding.EncodeToString([]byte(testDeleteApprovalMessage))
+	encodedSig := base64.StdEncoding.EncodeToString([]byte("dummy-signature"))
+
+	// approval annotated on the live object
+	reqc := newTestDeleteRequest(map[string]string{
+		common.DeleteMessageAnnotationKey:   encodedMsg,
+		common.DeleteSignatureAnnotationKey: encodedSig,
+	})
+	sig := evaluator.GetResourceSignature(reqc.ResourceRef(), reqc, nil)
+	if sig == nil || sig.SignType != SignedResourceTypeDelete || sig.Source() != SignatureSourceAnnotation {
+		t.Errorf("deletion approval in annotation is not found; actual: %v", sig)
+	} else if sig.data["signature"] != "dummy-signature" || sig.data["expiry"] != "2020-12-31T00:00:00Z" {
+		t.Errorf("deletion approval is not loaded correctly; actual: %v", sig.data)
+	}
+
+	// approval in ResourceSignature
+	reqc = newTestDeleteRequest(nil)
+	rsig := &vrsig.ResourceSignature{}
+	rsig.SetUID(types.UID("rsig-uid"))
+	rsig.Spec.Data = []*vrsig.SignItem{{Message: encodedMsg, Signature: encodedSig, Type: vrsig.SignatureTypeDelete}}
+	rsigList := &vrsig.ResourceSignatureList{Items: []*vrsig.ResourceSignature{rsig}}
+	sig = evaluator.GetResourceSignature(reqc.ResourceRef(), reqc, rsigList)
+	if sig == nil || sig.Source() != SignatureSourceResourceSignature || sig.data["resourceSignatureUID"] != "rsig-uid" {
+		t.Errorf("deletion approval in ResourceSignature is not found; actual: %v", sig)
+	}
+
+	// deletion approval must not be used as a signature for the resource itself
+	if found, _, _, _ := rsigList.FindSignItem("v1", "ConfigMap", "sample-cm", "secure-ns"); found {
+		t.Errorf("deletion approval should not be found as a resource signature")
+	}
+
+	// approval for another namespace
+	ref := reqc.ResourceRef()
+	ref.Namespace = "another-ns"
+	if sig = evaluator.GetResourceSignature(ref, reqc, rsigList); sig != nil {
+		t.Errorf("deletion approval for another namespace should not be found; actual: %v", sig)
+	}
+}
+
+func TestCheckDeleteApprovalExpiry(t *testing.T) {
+	sig := &GeneralSignature{SignType: SignedResourceTypeDelete, data: map[string]string{"expiry": "2020-12-31T00:00:00Z"}}
+	if reason := checkDeleteApprovalExpiry(sig, time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)); reason != "" {
+		t.Errorf("deletion approval should be valid before expiry; %s", reason)
+	}
+	if reason := checkDeleteApprovalExpiry(sig, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)); reason == "" {
+		t.Errorf("deletion approval should be expired after expiry")
+	}
+	noExpiry := &GeneralSignature{SignType: SignedResourceTypeDelete, data: map[string]string{}}
+	if reason := checkDeleteApprovalExpiry(noExpiry, time.Now()); reason == "" {
+		t.Errorf("deletion approval without expiry should be rejected")
+	}
+}
@@ -0,0 +1,210 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
+	sigrevokeapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signaturerevocation/v1alpha1"
+	sigconfapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signerconfig/v1alpha1"
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	v1 "k8s.io/api/core/v1"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+/**********************************************
+
+				FileLoader
+
+***********************************************/
+
+// FileResources is a set of resources read from local YAML/JSON files.
+// It is used instead of the API server when requests are processed offline.
+type FileResources struct {
+	RSPList      []rspapi.ResourceSigningProfile
+	NSList       []v1.Namespace
+	SignerConfig *sigconfapi.SignerConfig
+	ResSigList   []*rsigapi.ResourceSignature
+	Revocations  *common.RevocationList
+}
+
+func LoadFileResources(paths []string) (*FileResources, error) {
+	res := &FileResources{}
+	for _, fpath := range paths {
+		data, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		dec := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		for {
+			var obj map[string]interface{}
+			if err := dec.Decode(&obj); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to decode %s; %s", fpath, err.Error())
+			}
+			if len(obj) == 0 {
+				continue
+			}
+			if err := res.Add(obj); err != nil {
+				return nil, fmt.Errorf("failed to load %s; %s", fpath, err.Error())
+			}
+		}
+	}
+	return res, nil
+}
+
+// Add adds a resource to the set. Only the kinds used by Handler are supported.
+func (self *FileResources) Add(obj map[string]interface{}) error {
+	objBytes, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	kind, _ := obj["kind"].(string)
+	switch kind {
+	case common.ProfileCustomResourceKind:
+		var rsp rspapi.ResourceSigningProfile
+		if err := json.Unmarshal(objBytes, &rsp); err != nil {
+			return err
+		}
+		self.RSPList = append(self.RSPList, rsp)
+	case "Namespace":
+		var ns v1.Namespace
+		if err := json.Unmarshal(objBytes, &ns); err != nil {
+			return err
+		}
+		self.NSList = append(self.NSList, ns)
+	case common.SignerConfigCustomResourceKind:
+		var sigConf sigconfapi.SignerConfig
+		if err := json.Unmarshal(objBytes, &sigConf); err != nil {
+			return err
+		}
+		self.SignerConfig = &sigConf
+	case common.SignatureCustomResourceKind:
+		var rsig rsigapi.ResourceSignature
+		if err := json.Unmarshal(objBytes, &rsig); err != nil {
+			return err
+		}
+		self.ResSigList = append(self.ResSigList, &rsig)
+	case common.SignatureRevocationCustomResourceKind:
+		var sigRevoke sigrevokeapi.SignatureRevocation
+		if err := json.Unmarshal(objBytes, &sigRevoke); err != nil {
+			return err
+		}
+		self.Revocations = self.Revocations.Merge(&sigRevoke.Spec.RevocationList)
+	default:
+		return fmt.Errorf("unsupported kind `%s`", kind)
+	}
+	return nil
+}
+
+// NewFileLoaderFunc returns a LoaderFunc which serves the given resources to Handler.
+func NewFileLoaderFunc(res *FileResources) LoaderFunc {
+	return func(cfg *config.ShieldConfig, reqNamespace string) *Loader {
+		nsList := res.NSList
+		if len(nsList) == 0 && reqNamespace != "" {
+			// no Namespace is given, so assume the request namespace exists without any labels
+			ns := v1.Namespace{}
+			ns.SetName(reqNamespace)
+			nsList = []v1.Namespace{ns}
+		}
+		return &Loader{
+			SignerConfig:        &FileSignerConfigLoader{Data: res.SignerConfig},
+			RSP:                 &FileRSPLoader{Data: res.RSPList},
+			Namespace:           &FileNamespaceLoader{Data: nsList},
+			ResourceSignature:   &FileResSigLoader{signatureNamespace: cfg.SignatureNamespace, requestNamespace: reqNamespace, items: res.ResSigList},
+			SignatureRevocation: &FileSignatureRevocationLoader{Data: res.Revocations},
+			Keys:                &FileKeyProvider{KeyPathList: cfg.KeyPathList},
+			DryRunDisabled:      true,
+		}
+	}
+}
+
+type FileRSPLoader struct {
+	Data []rspapi.ResourceSigningProfile
+}
+
+func (self *FileRSPLoader) GetData(doK8sApiCall bool) ([]rspapi.ResourceSigningProfile, bool) {
+	return self.Data, false
+}
+
+func (self *FileRSPLoader) ClearCache() {}
+
+type FileNamespaceLoader struct {
+	Data []v1.Namespace
+}
+
+func (self *FileNamespaceLoader) GetData(doK8sApiCall bool) ([]v1.Namespace, bool) {
+	return self.Data, false
+}
+
+func (self *FileNamespaceLoader) ClearCache() {}
+
+type FileSignerConfigLoader struct {
+	Data *sigconfapi.SignerConfig
+}
+
+func (self *FileSignerConfigLoader) GetData(doK8sApiCall bool) *sigconfapi.SignerConfig {
+	if self.Data == nil {
+		return &sigconfapi.SignerConfig{}
+	}
+	return self.Data
+}
+
+type FileSignatureRevocationLoader struct {
+	Data *common.RevocationList
+}
+
+func (self *FileSignatureRevocationLoader) GetData(doK8sApiCall bool) (*common.RevocationList, error) {
+	if self.Data == nil {
+		return &common.RevocationList{}, nil
+	}
+	return self.Data, nil
+}
+
+type FileResSigLoader struct {
+	signatureNamespace string
+	requestNamespace   string
+	items              []*rsigapi.ResourceSignature
+}
+
+// GetData returns ResourceSignatures in the same way as K8sResSigLoader,
+// i.e. ones in signature namespace or request namespace with the labels of the requested apiVersion and kind.
+func (self *FileResSigLoader) GetData(reqc *common.ReqContext, doK8sApiCall bool) *rsigapi.ResourceSignatureList {
+	reqApiVersion := strings.ReplaceAll(reqc.GroupVersion(), "/", "_")
+	data := []*rsigapi.ResourceSignature{}
+	for _, rsig := range self.items {
+		ns := rsig.GetNamespace()
+		if ns != self.signatureNamespace && ns != self.requestNamespace {
+			continue
+		}
+		labels := rsig.GetLabels()
+		if labels[common.ResSigLabelApiVer] != reqApiVersion || labels[common.ResSigLabelKind] != reqc.Kind {
+			continue
+		}
+		data = append(data, rsig)
+	}
+	return &rsigapi.ResourceSignatureList{Items: sortByTimestamp(data)}
+}
@@ -0,0 +1,52 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	"github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+)
+
+const testFileResources = "testdata/file_resources.yaml"
+
+func TestFileLoader(t *testing.T) {
+	res, err := LoadFileResources([]string{testFileResources})
+	if err != nil {
+		t.Errorf("failed to load file resources; %s", err.Error())
+		return
+	}
+	if len(res.RSPList) != 1 || len(res.NSList) != 1 || res.SignerConfig == nil || len(res.ResSigList) != 2 {
+		t.Errorf("unexpected number of resources; rsp: %d, ns: %d, signerConfig: %v, rsig: %d", len(res.RSPList), len(res.NSList), res.SignerConfig != nil, len(res.ResSigList))
+		return
+	}
+
+	cfg := &config.ShieldConfig{Namespace: "integrity-shield-operator-system", SignatureNamespace: "integrity-shield-operator-system"}
+	loader := NewFileLoaderFunc(res)(cfg, "secure-ns")
+
+	sigConf := loader.SignerConfig.GetData(true)
+	if sigConf.Spec.Config == nil || len(sigConf.Spec.Config.Signers) != 1 {
+		t.Errorf("SignerConfig is not loaded correctly")
+	}
+
+	reqc := &common.ReqContext{ApiVersion: "v1", Kind: "ConfigMap", Namespace: "secure-ns"}
+	rsigList := loader.ResourceSignature.GetData(reqc, true)
+	if len(rsigList.Items) != 1 || rsigList.Items[0].GetName() != "rsig-configmap-sample-cm" {
+		t.Errorf("ResourceSignature for ConfigMap should be found; actual: %d items", len(rsigList.Items))
+	}
+}
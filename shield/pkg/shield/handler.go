@@ -19,9 +19,11 @@ package shield
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
 	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	metrics "github.com/IBM/integrity-enforcer/shield/pkg/util/metrics"
 	log "github.com/sirupsen/logrus"
 
 	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
@@ -44,22 +46,25 @@ type Handler struct {
 	requestLog    *log.Entry
 	contextLogger *logger.ContextLogger
 	logInScope    bool
+	loaderFunc    LoaderFunc
+	warnings      []string
 }
 
 func NewHandler(config *config.ShieldConfig, metaLogger *log.Logger, reqLog *log.Entry) *Handler {
-	return &Handler{config: config, data: &RunData{}, serverLogger: metaLogger, requestLog: reqLog}
+	return NewHandlerWithLoader(config, metaLogger, reqLog, NewLoader)
 }
 
-func (self *Handler) Run(req *admv1.AdmissionRequest) *admv1.AdmissionResponse {
-
-	// init ctx, reqc and data & init logger
-	self.initialize(req)
+// NewHandlerWithLoader creates a Handler which loads RSPs, SignerConfig etc. via the given LoaderFunc.
+// This is used for processing requests without API server (e.g. replaying recorded requests).
+func NewHandlerWithLoader(config *config.ShieldConfig, metaLogger *log.Logger, reqLog *log.Entry, loaderFunc LoaderFunc) *Handler {
+	return &Handler{config: config, data: &RunData{}, serverLogger: metaLogger, requestLog: reqLog, loaderFunc: loaderFunc}
+}
 
-	// make DecisionResult based on reqc, config and data
-	dr := self.Check()
+func (self *Handler) Run(req *admv1.AdmissionRequest) *admv1.AdmissionResponse {
+	start := time.Now()
 
-	// overwrite DecisionResult if needed (DetectMode & BreakGlass)
-	dr = self.overwriteDecision(dr)
+	// make DecisionResult based on the request, config and data
+	dr, _ := self.Evaluate(req)
 
 	// make AdmissionResponse based on DecisionResult
 	resp := &admv1.AdmissionResponse{}
@@ -72,6 +77,16 @@ func (self *Handler) Run(req *admv1.AdmissionRequest) *admv1.AdmissionResponse {
 		resp = createAdmissionResponse(dr.isAllowed(), dr.Message, self.reqc, self.ctx, self.config)
 	}
 
+	// invalid conditions in the matched RSPs are evaluated as errors, so the requester should know them
+	for _, msg := range self.ctx.conditionErrors {
+		self.warnings = append(self.warnings, trimWarning("[IntegrityShield] "+msg))
+	}
+
+	// explain the decision to the requester if denied
+	if !resp.Allowed {
+		self.warnings = append(self.warnings, self.ctx.summarizeTrace()...)
+	}
+
 	// log results
 	self.logResponse(req, resp)
 	self.logContext()
@@ -79,53 +94,52 @@ func (self *Handler) Run(req *admv1.AdmissionRequest) *admv1.AdmissionResponse {
 	// create Event & update RSP status
 	_ = self.Report(dr.denyRSP)
 
+	// export metrics
+	self.recordMetrics(dr, start)
+
 	// clear some cache if needed
 	self.finalize(resp)
 
 	return resp
 }
 
-func (self *Handler) Check() *DecisionResult {
-	var dr *DecisionResult
-	dr = undeterminedDescision()
-
-	dr = inScopeCheck(self.reqc, self.config, self.data, self.ctx)
-	if !dr.isUndetermined() {
-		return dr
-	}
-	self.logInScope = true
+// Warnings returns messages which should be returned to the requester as admission warnings.
+func (self *Handler) Warnings() []string {
+	return self.warnings
+}
 
-	dr = formatCheck(self.reqc, self.config, self.data, self.ctx)
-	if !dr.isUndetermined() {
+// Evaluate makes a decision for the request without creating any response, event or status update.
+func (self *Handler) Evaluate(req *admv1.AdmissionRequest) (*DecisionResult, *CheckContext) {
+	// init ctx, reqc and data & init logger
+	self.initialize(req)
 
-		return dr
-	}
+	// make DecisionResult based on reqc, config and data
+	dr := self.Check()
 
-	dr = iShieldResourceCheck(self.reqc, self.config, self.data, self.ctx)
-	if !dr.isUndetermined() {
-		return dr
-	}
+	// overwrite DecisionResult if needed (DetectMode, WarnMode & BreakGlass)
+	dr = self.overwriteDecision(dr)
 
-	dr = deleteCheck(self.reqc, self.config, self.data, self.ctx)
-	if !dr.isUndetermined() {
-		return dr
-	}
+	return dr, self.ctx
+}
 
-	var matchedProfiles []rspapi.ResourceSigningProfile
-	dr, matchedProfiles = protectedCheck(self.reqc, self.config, self.data, self.ctx)
-	if !dr.isUndetermined() {
-		return dr
-	}
+func (self *Handler) Check() *DecisionResult {
+	var dr *DecisionResult
+	dr = undeterminedDescision()
 
-	for _, prof := range matchedProfiles {
-		dr = resourceSigningProfileCheck(prof, self.reqc, self.config, self.data, self.ctx)
-		if dr.isAllowed() {
-			// this RSP allowed the request. will check next RSP.
-		} else {
-			// this RSP denied the request. return the result and will make AdmissionResponse.
-			return dr
+	decidedBy := ""
+	for _, chk := range GetCheckChain(self.config) {
+		traceLen := len(self.ctx.Trace)
+		dr = chk.Run(self.reqc, self.config, self.data, self.ctx)
+		if len(self.ctx.Trace) == traceLen {
+			self.ctx.addTrace(chk.Name(), dr)
+		}
+		if !dr.isUndetermined() {
+			decidedBy = chk.Name()
+			break
 		}
 	}
+	// request allowed by inScopeCheck is not logged in context log
+	self.logInScope = (decidedBy != inScopeCheckName)
 
 	if dr.isUndetermined() {
 		dr = &DecisionResult{
@@ -137,10 +151,17 @@ func (self *Handler) Check() *DecisionResult {
 	return dr
 }
 
+func (self *Handler) recordMetrics(dr *DecisionResult, start time.Time) {
+	decision := string(dr.Type)
+	reason := common.ReasonCodeMap[dr.ReasonCode].Code
+	metrics.RecordAdmissionRequest(decision, reason, self.reqc.Operation, self.reqc.Kind, self.reqc.Namespace, self.ctx.MatchedProfile)
+	metrics.ObserveHandlerRun(decision, self.reqc.Operation, start)
+}
+
 func (self *Handler) Report(denyRSP *rspapi.ResourceSigningProfile) error {
-	// report only for denying request or for IShield resource request by IShield Admin
+	// report only for denying request (including one allowed by detect mode, warn mode or break glass) or for IShield resource request by IShield Admin
 	shouldReport := false
-	if !self.ctx.Allow {
+	if !self.ctx.Allow || self.ctx.DetectOnlyModeEnabled || self.ctx.WarnOnlyModeEnabled || self.ctx.BreakGlassModeEnabled {
 		shouldReport = true
 	}
 	iShieldAdmin := checkIfIShieldAdminRequest(self.reqc, self.config)
@@ -160,8 +181,14 @@ func (self *Handler) Report(denyRSP *rspapi.ResourceSigningProfile) error {
 		return err
 	}
 
+	// requests allowed by break glass are reported only by Event
+	if self.ctx.BreakGlassModeEnabled {
+		return nil
+	}
+
 	// update RSP status
-	err = updateRSPStatus(denyRSP, self.reqc, self.ctx.Message)
+	mode, errMsg := self.statusUpdate()
+	err = updateRSPStatus(denyRSP, self.reqc, errMsg, mode)
 	if err != nil {
 		self.requestLog.Error("Failed to update status; ", err)
 	}
@@ -169,6 +196,21 @@ func (self *Handler) Report(denyRSP *rspapi.ResourceSigningProfile) error {
 	return nil
 }
 
+// statusUpdate returns the mode and the original deny reason to be recorded in RSP status
+func (self *Handler) statusUpdate() (common.IntegrityShieldMode, string) {
+	mode := common.EnforceMode
+	if self.ctx.DetectOnlyModeEnabled {
+		mode = common.DetectMode
+	} else if self.ctx.WarnOnlyModeEnabled {
+		mode = common.WarnMode
+	}
+	errMsg := self.ctx.Message
+	if self.ctx.denyMessage != "" {
+		errMsg = self.ctx.denyMessage
+	}
+	return mode, errMsg
+}
+
 // load resoruces / set default values
 func (self *Handler) initialize(req *admv1.AdmissionRequest) *DecisionResult {
 
@@ -182,7 +224,7 @@ func (self *Handler) initialize(req *admv1.AdmissionRequest) *DecisionResult {
 	// Note: logEntry() calls ShieldConfig.ConsoleLogEnabled() internally, and this requires ReqContext.
 	self.logEntry()
 
-	runDataLoader := NewLoader(self.config, reqNamespace)
+	runDataLoader := self.loaderFunc(self.config, reqNamespace)
 	self.data.loader = runDataLoader
 	self.data.Init(self.reqc, self.config)
 
@@ -191,14 +233,18 @@ func (self *Handler) initialize(req *admv1.AdmissionRequest) *DecisionResult {
 
 func (self *Handler) overwriteDecision(dr *DecisionResult) *DecisionResult {
 	sigConf := self.data.GetSignerConfig()
-	isBreakGlass := checkIfBreakGlassEnabled(self.reqc, sigConf)
-	isDetectMode := checkIfDetectOnly(self.config)
+	breakGlass := getBreakGlassCondition(self.reqc, sigConf)
+	isBreakGlass := (breakGlass != nil)
+	mode := getEnforcementMode(dr.denyRSP, self.config)
+	isDetectMode := (mode == common.DetectMode)
+	isWarnMode := (mode == common.WarnMode)
 
-	if !isBreakGlass && !isDetectMode {
+	if !isBreakGlass && !isDetectMode && !isWarnMode {
 		return dr
 	}
 
 	if !dr.isAllowed() && isDetectMode {
+		self.addOverwriteWarning("detect mode", dr)
 		self.ctx.Allow = true
 		self.ctx.DetectOnlyModeEnabled = true
 		self.ctx.ReasonCode = common.REASON_DETECTION
@@ -207,19 +253,40 @@ func (self *Handler) overwriteDecision(dr *DecisionResult) *DecisionResult {
 		dr.Verified = false
 		dr.Message = common.ReasonCodeMap[common.REASON_DETECTION].Message
 		dr.ReasonCode = common.REASON_DETECTION
+	} else if !dr.isAllowed() && isWarnMode {
+		self.addOverwriteWarning("warn mode", dr)
+		self.ctx.Allow = true
+		self.ctx.WarnOnlyModeEnabled = true
+		self.ctx.ReasonCode = common.REASON_WARN
+		self.ctx.Message = common.ReasonCodeMap[common.REASON_WARN].Message
+		dr.Type = common.DecisionAllow
+		dr.Verified = false
+		dr.Message = common.ReasonCodeMap[common.REASON_WARN].Message
+		dr.ReasonCode = common.REASON_WARN
 	} else if !dr.isAllowed() && isBreakGlass {
+		self.addOverwriteWarning("break glass mode", dr)
 		self.ctx.Allow = true
+		// break glass condition is included in the message for auditing
+		msg := fmt.Sprintf("%s (%s)", common.ReasonCodeMap[common.REASON_BREAK_GLASS].Message, breakGlass.String())
 		self.ctx.BreakGlassModeEnabled = true
 		self.ctx.ReasonCode = common.REASON_BREAK_GLASS
-		self.ctx.Message = common.ReasonCodeMap[common.REASON_BREAK_GLASS].Message
+		self.ctx.Message = msg
 		dr.Type = common.DecisionAllow
 		dr.Verified = false
-		dr.Message = common.ReasonCodeMap[common.REASON_BREAK_GLASS].Message
+		dr.Message = msg
 		dr.ReasonCode = common.REASON_BREAK_GLASS
 	}
 	return dr
 }
 
+// addOverwriteWarning tells the requester the original deny reason, because the request is allowed silently otherwise.
+// The reason is kept in CheckContext for RSP status too.
+func (self *Handler) addOverwriteWarning(mode string, dr *DecisionResult) {
+	self.ctx.denyMessage = dr.Message
+	msg := fmt.Sprintf("[IntegrityShield] allowed by %s, but this request would be denied; reason: %s (%s)", mode, dr.Message, common.ReasonCodeMap[dr.ReasonCode].Code)
+	self.warnings = append(self.warnings, trimWarning(msg))
+}
+
 func (self *Handler) finalize(resp *admv1.AdmissionResponse) {
 	if resp.Allowed {
 		resetRuleTableCache := false
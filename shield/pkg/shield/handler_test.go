@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -492,3 +493,134 @@ var _ = Describe("Test integrity shield", func() {
 	})
 
 })
+
+func TestStatusUpdate(t *testing.T) {
+	testCases := []struct {
+		rspMode  common.IntegrityShieldMode
+		expected common.IntegrityShieldMode
+	}{
+		{common.UnknownMode, common.EnforceMode},
+		{common.DetectMode, common.DetectMode},
+		{common.WarnMode, common.WarnMode},
+	}
+	for _, tc := range testCases {
+		handler := &Handler{
+			config: &config.ShieldConfig{Mode: config.EnforceMode},
+			ctx:    &CheckContext{},
+			reqc:   &common.ReqContext{Namespace: "secure-ns", ResourceScope: "Namespaced"},
+			data:   &RunData{SignerConfig: &sigconf.SignerConfig{}},
+		}
+		denyRSP := &rspapi.ResourceSigningProfile{}
+		denyRSP.Spec.Mode = tc.rspMode
+		dr := &DecisionResult{
+			Type:       common.DecisionDeny,
+			ReasonCode: common.REASON_NO_SIG,
+			Message:    common.ReasonCodeMap[common.REASON_NO_SIG].Message,
+			denyRSP:    denyRSP,
+		}
+		handler.ctx.Message = dr.Message
+		handler.overwriteDecision(dr)
+		mode, errMsg := handler.statusUpdate()
+		if mode != tc.expected || errMsg != common.ReasonCodeMap[common.REASON_NO_SIG].Message {
+			t.Errorf("profile mode: %s\nexpected: %s, %s\nactual: %s, %s", tc.rspMode, tc.expected, common.ReasonCodeMap[common.REASON_NO_SIG].Message, mode, errMsg)
+		}
+	}
+}
+
+func TestOverwriteDecisionWarning(t *testing.T) {
+	handler := &Handler{
+		config: &config.ShieldConfig{Mode: config.DetectMode},
+		ctx:    &CheckContext{},
+		reqc:   &common.ReqContext{Namespace: "secure-ns", ResourceScope: "Namespaced"},
+		data:   &RunData{SignerConfig: &sigconf.SignerConfig{}},
+	}
+	dr := &DecisionResult{
+		Type:       common.DecisionDeny,
+		ReasonCode: common.REASON_NO_SIG,
+		Message:    common.ReasonCodeMap[common.REASON_NO_SIG].Message,
+	}
+	dr = handler.overwriteDecision(dr)
+	if !dr.isAllowed() || dr.ReasonCode != common.REASON_DETECTION {
+		t.Errorf("request should be allowed in detect mode; actual: %v", dr)
+	}
+	warnings := handler.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "(no-signature)") {
+		t.Errorf("original deny reason should be returned as a warning; actual: %v", warnings)
+	}
+}
+
+func TestOverwriteDecisionProfileMode(t *testing.T) {
+	testCases := []struct {
+		globalMode config.IntegrityShieldMode
+		rspMode    common.IntegrityShieldMode
+		expected   int
+	}{
+		{config.EnforceMode, common.UnknownMode, common.REASON_NO_SIG},
+		{config.DetectMode, common.UnknownMode, common.REASON_DETECTION},
+		{config.EnforceMode, common.DetectMode, common.REASON_DETECTION},
+		{config.EnforceMode, common.WarnMode, common.REASON_WARN},
+		{config.DetectMode, common.EnforceMode, common.REASON_NO_SIG},
+	}
+	for _, tc := range testCases {
+		handler := &Handler{
+			config: &config.ShieldConfig{Mode: tc.globalMode},
+			ctx:    &CheckContext{},
+			reqc:   &common.ReqContext{Namespace: "secure-ns", ResourceScope: "Namespaced"},
+			data:   &RunData{SignerConfig: &sigconf.SignerConfig{}},
+		}
+		rsp := &rspapi.ResourceSigningProfile{}
+		rsp.Spec.Mode = tc.rspMode
+		dr := &DecisionResult{
+			Type:       common.DecisionDeny,
+			ReasonCode: common.REASON_NO_SIG,
+			Message:    common.ReasonCodeMap[common.REASON_NO_SIG].Message,
+			denyRSP:    rsp,
+		}
+		dr = handler.overwriteDecision(dr)
+		if dr.ReasonCode != tc.expected {
+			t.Errorf("global mode: %s, profile mode: %s\nexpected: %s\nactual: %s", tc.globalMode, tc.rspMode, common.ReasonCodeMap[tc.expected].Code, common.ReasonCodeMap[dr.ReasonCode].Code)
+		}
+	}
+}
+
+func TestOverwriteDecisionBreakGlassExpiry(t *testing.T) {
+	now := time.Now().UTC()
+	testCases := []struct {
+		expiresAt string
+		expected  int
+	}{
+		{"", common.REASON_BREAK_GLASS},
+		{now.Add(time.Hour).Format(time.RFC3339), common.REASON_BREAK_GLASS},
+		{now.Add(-time.Hour).Format(time.RFC3339), common.REASON_NO_SIG},
+		{"tomorrow", common.REASON_NO_SIG},
+	}
+	for _, tc := range testCases {
+		sigConf := &sigconf.SignerConfig{}
+		sigConf.Spec.Config = &common.SignerConfig{
+			BreakGlass: []common.BreakGlassCondition{{
+				Namespaces:  []string{"secure-ns"},
+				ExpiresAt:   tc.expiresAt,
+				Reason:      "incident-123",
+				RequestedBy: "admin@enterprise.com",
+			}},
+		}
+		handler := &Handler{
+			config: &config.ShieldConfig{Mode: config.EnforceMode},
+			ctx:    &CheckContext{},
+			reqc:   &common.ReqContext{Namespace: "secure-ns", ResourceScope: "Namespaced"},
+			data:   &RunData{SignerConfig: sigConf},
+		}
+		dr := &DecisionResult{
+			Type:       common.DecisionDeny,
+			ReasonCode: common.REASON_NO_SIG,
+			Message:    common.ReasonCodeMap[common.REASON_NO_SIG].Message,
+		}
+		dr = handler.overwriteDecision(dr)
+		if dr.ReasonCode != tc.expected {
+			t.Errorf("expiresAt: %s\nexpected: %s\nactual: %s", tc.expiresAt, common.ReasonCodeMap[tc.expected].Code, common.ReasonCodeMap[dr.ReasonCode].Code)
+		}
+		if dr.ReasonCode == common.REASON_BREAK_GLASS && !strings.Contains(dr.Message, "requestedBy: admin@enterprise.com") {
+			t.Errorf("break glass condition should be included in the message; actual: %s", dr.Message)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	x509 "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// KeyProvider provides keys for signature verification. Signers are matched with the keys by the name of keyConfig,
+// so the keys can be loaded from anywhere. Every key returned by a KeyProvider is trusted, so the source of keys
+// must be writable only by IShield admins.
+type KeyProvider interface {
+	GetKeys() []common.VerificationKey
+}
+
+// NewKeyProvider returns a KeyProvider for the mounted keys in `keyPathList` of ShieldConfig and the keys in
+// labeled Secrets and ConfigMaps if the cluster key provider is started.
+func NewKeyProvider(cfg *config.ShieldConfig) KeyProvider {
+	providers := MultiKeyProvider{&FileKeyProvider{KeyPathList: cfg.KeyPathList}}
+	if provider := GetClusterKeyProvider(); provider != nil {
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+type MultiKeyProvider []KeyProvider
+
+func (self MultiKeyProvider) GetKeys() []common.VerificationKey {
+	keys := []common.VerificationKey{}
+	for _, provider := range self {
+		keys = append(keys, provider.GetKeys()...)
+	}
+	return keys
+}
+
+/**********************************************
+
+				FileKeyProvider
+
+***********************************************/
+
+// FileKeyProvider loads keys from the paths like `/<keyConfig>/pgp/pubring.gpg` and `/<keyConfig>/x509/`.
+// The files are read every time, so updates of mounted Secrets are used without restarting the server.
+type FileKeyProvider struct {
+	KeyPathList []string
+}
+
+func (self *FileKeyProvider) GetKeys() []common.VerificationKey {
+	keys := []common.VerificationKey{}
+	for _, keyPath := range self.KeyPathList {
+		keyConfig, sigType, ok := ParseKeyPath(keyPath)
+		if !ok {
+			logger.Warn(fmt.Sprintf("key path `%s` does not have keyConfig name and signature type, so it is not used", keyPath))
+			continue
+		}
+		var files map[string][]byte
+		var err error
+		if sigType == common.SignatureTypePGP && !strings.HasSuffix(keyPath, "/") {
+			var data []byte
+			if data, err = ioutil.ReadFile(filepath.Clean(keyPath)); err == nil {
+				files = map[string][]byte{filepath.Base(keyPath): data}
+			}
+		} else {
+			files, err = x509.ReadKeyDir(keyPath)
+		}
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to load key `%s`; %s", keyPath, err.Error()))
+			files = map[string][]byte{}
+		}
+		keys = append(keys, common.VerificationKey{KeyConfig: keyConfig, Type: sigType, Source: keyPath, Files: files})
+	}
+	return keys
+}
+
+// ParseKeyPath returns the name of keyConfig and the signature type from the key path `/<keyConfig>/<signatureType>/...`
+func ParseKeyPath(keyPath string) (string, common.SignatureType, bool) {
+	parts := strings.Split(filepath.ToSlash(keyPath), "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		sigType := common.SignatureType(parts[i])
+		if (sigType == common.SignatureTypePGP || sigType == common.SignatureTypeX509 || sigType == common.SignatureTypeSigstore) && parts[i-1] != "" {
+			return parts[i-1], sigType, true
+		}
+	}
+	return "", "", false
+}
+
+/**********************************************
+
+				K8sKeyProvider
+
+***********************************************/
+
+var clusterKeyProvider KeyProvider
+var clusterKeyProviderLock sync.RWMutex
+
+// GetClusterKeyProvider returns the key provider for Secrets and ConfigMaps started by StartClusterKeyProvider(), or nil.
+func GetClusterKeyProvider() KeyProvider {
+	clusterKeyProviderLock.RLock()
+	defer clusterKeyProviderLock.RUnlock()
+	return clusterKeyProvider
+}
+
+// StartClusterKeyProvider starts watching labeled Secrets and ConfigMaps in the namespace, and the keys in them
+// are used by all requests after this.
+func StartClusterKeyProvider(client kubernetes.Interface, namespace string, resync time.Duration, stopCh <-chan struct{}) error {
+	provider := NewK8sKeyProvider(client, namespace, resync)
+	if err := provider.Start(stopCh); err != nil {
+		return err
+	}
+	clusterKeyProviderLock.Lock()
+	clusterKeyProvider = provider
+	clusterKeyProviderLock.Unlock()
+	return nil
+}
+
+// K8sKeyProvider provides keys in Secrets and ConfigMaps which have `integrityshield.io/keyConfig` label.
+// The label value is the name of keyConfig, and `integrityshield.io/signatureType` label is the signature type (default: pgp).
+// Each data item is a key file such as `pubring.gpg`, `ca.crt` or `rekor.pub`.
+// Anyone who can create a labeled Secret or ConfigMap in the namespace adds a trust anchor,
+// so RBAC on the shield namespace is security-critical.
+type K8sKeyProvider struct {
+	factory         informers.SharedInformerFactory
+	secretLister    corelisters.SecretLister
+	configMapLister corelisters.ConfigMapLister
+	secretSynced    cache.InformerSynced
+	configMapSynced cache.InformerSynced
+}
+
+func NewK8sKeyProvider(client kubernetes.Interface, namespace string, resync time.Duration) *K8sKeyProvider {
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resync,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = common.KeyConfigLabelKey
+		}),
+	)
+	secretInformer := factory.Core().V1().Secrets()
+	configMapInformer := factory.Core().V1().ConfigMaps()
+	return &K8sKeyProvider{
+		factory:         factory,
+		secretLister:    secretInformer.Lister(),
+		configMapLister: configMapInformer.Lister(),
+		secretSynced:    secretInformer.Informer().HasSynced,
+		configMapSynced: configMapInformer.Informer().HasSynced,
+	}
+}
+
+func (self *K8sKeyProvider) Start(stopCh <-chan struct{}) error {
+	self.factory.Start(stopCh)
+	if !cache.WaitForCacheSync(stopCh, self.secretSynced, self.configMapSynced) {
+		return fmt.Errorf("failed to sync Secrets and ConfigMaps for verification keys")
+	}
+	return nil
+}
+
+func (self *K8sKeyProvider) GetKeys() []common.VerificationKey {
+	keys := []common.VerificationKey{}
+	secrets, err := self.secretLister.List(labels.Everything())
+	if err != nil {
+		logger.Error("failed to list Secrets for verification keys; ", err.Error())
+	}
+	for _, secret := range secrets {
+		if secret.GetLabels()[common.KeyConfigLabelKey] == "" {
+			continue
+		}
+		files := map[string][]byte{}
+		for name, data := range secret.Data {
+			files[name] = data
+		}
+		keys = append(keys, newKeyFromLabels(secret.GetLabels(), fmt.Sprintf("secret/%s", secret.GetName()), files))
+	}
+	configMaps, err := self.configMapLister.List(labels.Everything())
+	if err != nil {
+		logger.Error("failed to list ConfigMaps for verification keys; ", err.Error())
+	}
+	for _, cm := range configMaps {
+		if cm.GetLabels()[common.KeyConfigLabelKey] == "" {
+			continue
+		}
+		keys = append(keys, newKeyFromConfigMap(cm))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Source < keys[j].Source })
+	return keys
+}
+
+func newKeyFromConfigMap(cm *v1.ConfigMap) common.VerificationKey {
+	files := map[string][]byte{}
+	for name, data := range cm.Data {
+		files[name] = []byte(data)
+	}
+	for name, data := range cm.BinaryData {
+		files[name] = data
+	}
+	return newKeyFromLabels(cm.GetLabels(), fmt.Sprintf("configmap/%s", cm.GetName()), files)
+}
+
+func newKeyFromLabels(labels map[string]string, source string, files map[string][]byte) common.VerificationKey {
+	sigType := common.SignatureType(labels[common.SignatureTypeLabelKey])
+	if sigType == common.SignatureTypeDefault {
+		sigType = common.SignatureTypePGP
+	}
+	return common.VerificationKey{KeyConfig: labels[common.KeyConfigLabelKey], Type: sigType, Source: source, Files: files}
+}
@@ -0,0 +1,95 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFileKeyProvider(t *testing.T) {
+	keyPath := "./testdata/sample-signer-keyconfig/pgp/pubring"
+	provider := &FileKeyProvider{KeyPathList: []string{keyPath, "./testdata/no-keyconfig"}}
+	keys := provider.GetKeys()
+	if len(keys) != 1 {
+		t.Errorf("FileKeyProvider Failed\nexpected: %d keys\nactual: %d keys", 1, len(keys))
+		return
+	}
+	key := keys[0]
+	if key.KeyConfig != "sample-signer-keyconfig" || key.Type != common.SignatureTypePGP || key.Source != keyPath {
+		t.Errorf("FileKeyProvider Failed\nexpected: %s\nactual: %s", "sample-signer-keyconfig/pgp (./testdata/sample-signer-keyconfig/pgp/pubring)", key.String())
+	}
+	expected, _ := ioutil.ReadFile(keyPath)
+	if string(key.Files["pubring"]) != string(expected) {
+		t.Errorf("FileKeyProvider Failed\nexpected: the content of %s\nactual: %v", keyPath, key.FileNames())
+	}
+}
+
+func TestK8sKeyProvider(t *testing.T) {
+	ns := "integrity-shield-operator-system"
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "keyring-secret",
+			Namespace: ns,
+			Labels:    map[string]string{common.KeyConfigLabelKey: "team-keys"},
+		},
+		Data: map[string][]byte{"pubring.gpg": []byte("keyring")},
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-certs",
+			Namespace: ns,
+			Labels:    map[string]string{common.KeyConfigLabelKey: "x509-keys", common.SignatureTypeLabelKey: "x509"},
+		},
+		Data: map[string]string{"ca.crt": "cert"},
+	}
+	unlabeled := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "other-secret", Namespace: ns},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	otherNs := secret.DeepCopy()
+	otherNs.SetNamespace("default")
+	client := fake.NewSimpleClientset(secret, cm, unlabeled, otherNs)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	provider := NewK8sKeyProvider(client, ns, time.Minute)
+	if err := provider.Start(stopCh); err != nil {
+		t.Error(err)
+		return
+	}
+
+	keys := provider.GetKeys()
+	actual := []string{}
+	for _, key := range keys {
+		actual = append(actual, key.String())
+	}
+	expected := []string{"x509-keys/x509 (configmap/ca-certs)", "team-keys/pgp (secret/keyring-secret)"}
+	if len(actual) != len(expected) || actual[0] != expected[0] || actual[1] != expected[1] {
+		t.Errorf("K8sKeyProvider Failed\nexpected: %v\nactual: %v", expected, actual)
+		return
+	}
+	if string(keys[1].Files["pubring.gpg"]) != "keyring" {
+		t.Errorf("K8sKeyProvider Failed\nexpected: %s\nactual: %s", "keyring", string(keys[1].Files["pubring.gpg"]))
+	}
+}
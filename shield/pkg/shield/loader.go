@@ -27,22 +27,34 @@ import (
 ***********************************************/
 
 type Loader struct {
-	SignerConfig      *SignerConfigLoader
-	RSP               *RSPLoader
-	Namespace         *NamespaceLoader
-	ResourceSignature *ResSigLoader
+	SignerConfig        SignerConfigLoader
+	RSP                 RSPLoader
+	Namespace           NamespaceLoader
+	ResourceSignature   ResSigLoader
+	SignatureRevocation SignatureRevocationLoader
+	Keys                KeyProvider
+	Owner               OwnerLoader
+	// DryRunDisabled is true if the resources are not loaded from the cluster, so dry-run is not available either.
+	DryRunDisabled bool
 }
 
+// LoaderFunc creates a Loader for a single request.
+// Handler uses NewLoader by default, which loads resources from the cluster.
+type LoaderFunc func(cfg *config.ShieldConfig, reqNamespace string) *Loader
+
 func NewLoader(cfg *config.ShieldConfig, reqNamespace string) *Loader {
 	shieldNamespace := cfg.Namespace
 	requestNamespace := reqNamespace
 	signatureNamespace := cfg.SignatureNamespace // for non-existing namespace / cluster scope
 	profileNamespace := cfg.ProfileNamespace     // for non-existing namespace / cluster scope
 	loader := &Loader{
-		SignerConfig:      NewSignerConfigLoader(shieldNamespace),
-		RSP:               NewRSPLoader(shieldNamespace, profileNamespace, requestNamespace, cfg.CommonProfile),
-		Namespace:         NewNamespaceLoader(),
-		ResourceSignature: NewResSigLoader(signatureNamespace, requestNamespace),
+		SignerConfig:        NewSignerConfigLoader(shieldNamespace),
+		RSP:                 NewRSPLoader(shieldNamespace, profileNamespace, requestNamespace, cfg.CommonProfile),
+		Namespace:           NewNamespaceLoader(),
+		ResourceSignature:   NewResSigLoader(signatureNamespace, requestNamespace),
+		SignatureRevocation: NewSignatureRevocationLoader(shieldNamespace),
+		Keys:                NewKeyProvider(cfg),
+		Owner:               NewOwnerLoader(),
 	}
 	return loader
 }
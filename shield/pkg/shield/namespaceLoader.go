@@ -32,24 +32,29 @@ import (
 
 // Namespace
 
-type NamespaceLoader struct {
+type NamespaceLoader interface {
+	GetData(doK8sApiCall bool) ([]v1.Namespace, bool)
+	ClearCache()
+}
+
+type K8sNamespaceLoader struct {
 	interval time.Duration
 	Client   *v1client.CoreV1Client
 	Data     []v1.Namespace
 }
 
-func NewNamespaceLoader() *NamespaceLoader {
+func NewNamespaceLoader() NamespaceLoader {
 	interval := time.Second * 30
 	config, _ := kubeutil.GetKubeConfig()
 	client, _ := v1client.NewForConfig(config)
 
-	return &NamespaceLoader{
+	return &K8sNamespaceLoader{
 		interval: interval,
 		Client:   client,
 	}
 }
 
-func (self *NamespaceLoader) GetData(doK8sApiCall bool) ([]v1.Namespace, bool) {
+func (self *K8sNamespaceLoader) GetData(doK8sApiCall bool) ([]v1.Namespace, bool) {
 	reloaded := false
 	if len(self.Data) == 0 {
 		reloaded = self.Load(doK8sApiCall)
@@ -57,7 +62,7 @@ func (self *NamespaceLoader) GetData(doK8sApiCall bool) ([]v1.Namespace, bool) {
 	return self.Data, reloaded
 }
 
-func (self *NamespaceLoader) Load(doK8sApiCall bool) bool {
+func (self *K8sNamespaceLoader) Load(doK8sApiCall bool) bool {
 	var err error
 	var list1 *v1.NamespaceList
 	var keyName string
@@ -92,6 +97,6 @@ func (self *NamespaceLoader) Load(doK8sApiCall bool) bool {
 	return reloaded
 }
 
-func (self *NamespaceLoader) ClearCache() {
+func (self *K8sNamespaceLoader) ClearCache() {
 	cache.Unset("NamespaceLoader/list")
 }
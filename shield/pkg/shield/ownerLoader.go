@@ -0,0 +1,93 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cache "github.com/IBM/integrity-enforcer/shield/pkg/util/cache"
+	"github.com/IBM/integrity-enforcer/shield/pkg/util/kubeutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Owner
+
+// OwnerLoader gets an owner of the requested resource, which is used for `ownerCheck` of RSP
+type OwnerLoader interface {
+	GetOwner(ref metav1.OwnerReference, namespace string) (*unstructured.Unstructured, error)
+}
+
+type K8sOwnerLoader struct {
+	interval time.Duration
+	Client   dynamic.Interface
+}
+
+func NewOwnerLoader() OwnerLoader {
+	interval := time.Second * 10
+	config, _ := kubeutil.GetKubeConfig()
+	var client dynamic.Interface
+	if config != nil {
+		client, _ = dynamic.NewForConfig(config)
+	}
+
+	return &K8sOwnerLoader{
+		interval: interval,
+		Client:   client,
+	}
+}
+
+// GetOwner gets the owner in the namespace. If it is not found, the owner is looked up in cluster scope
+// because a namespaced resource can be owned by a cluster scope resource.
+func (self *K8sOwnerLoader) GetOwner(ref metav1.OwnerReference, namespace string) (*unstructured.Unstructured, error) {
+	keyName := fmt.Sprintf("OwnerLoader/%s/%s/%s/%s", ref.APIVersion, ref.Kind, namespace, ref.Name)
+	if cached := cache.GetString(keyName); cached != "" {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON([]byte(cached)); err == nil {
+			return obj, nil
+		}
+	}
+	if self.Client == nil {
+		return nil, fmt.Errorf("client for owner resources is not available")
+	}
+
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gv.WithKind(ref.Kind))
+	var obj *unstructured.Unstructured
+	if namespace != "" {
+		obj, err = self.Client.Resource(gvr).Namespace(namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+	}
+	if namespace == "" || errors.IsNotFound(err) {
+		obj, err = self.Client.Resource(gvr).Get(context.Background(), ref.Name, metav1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tmp, err := obj.MarshalJSON(); err == nil {
+		cache.SetString(keyName, string(tmp), &(self.interval))
+	}
+	return obj, nil
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newTestOwnedObject(apiVersion, kind, name, uid string, labels map[string]string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace("owner-test-ns")
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	obj.SetLabels(labels)
+	if owner != nil {
+		controller := true
+		obj.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: owner.GetAPIVersion(),
+			Kind:       owner.GetKind(),
+			Name:       owner.GetName(),
+			UID:        owner.GetUID(),
+			Controller: &controller,
+		}})
+	}
+	return obj
+}
+
+func TestFindVerifiedOwner(t *testing.T) {
+	verified := map[string]string{common.ResourceIntegrityLabelKey: common.LabelValueVerified}
+	deploy := newTestOwnedObject("apps/v1", "Deployment", "owner-test-app", "deploy-uid", verified, nil)
+	rs := newTestOwnedObject("apps/v1", "ReplicaSet", "owner-test-app-rs", "rs-uid", nil, deploy)
+	unverifiedDeploy := newTestOwnedObject("apps/v1", "Deployment", "owner-test-app2", "deploy2-uid", nil, nil)
+	rs2 := newTestOwnedObject("apps/v1", "ReplicaSet", "owner-test-app2-rs", "rs2-uid", nil, unverifiedDeploy)
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deploy, rs, unverifiedDeploy, rs2)
+	data := &RunData{loader: &Loader{Owner: &K8sOwnerLoader{Client: client}}}
+
+	controllerSA := "system:serviceaccount:kube-system:replicaset-controller"
+	testCases := []struct {
+		name       string
+		ownerCheck *common.OwnerCheck
+		owner      *unstructured.Unstructured
+		userName   string
+		operation  string
+		expected   string
+	}{
+		{"owned by verified deployment", &common.OwnerCheck{}, rs, controllerSA, "CREATE", "owner-test-app"},
+		{"ownerCheck disabled", nil, rs, controllerSA, "CREATE", ""},
+		{"not a controller", &common.OwnerCheck{}, rs, "user@enterprise.com", "CREATE", ""},
+		{"custom controller", &common.OwnerCheck{ControllerServiceAccounts: []string{"system:serviceaccount:ops:*"}}, rs, controllerSA, "CREATE", ""},
+		{"depth exceeded", &common.OwnerCheck{MaxDepth: 1}, rs, controllerSA, "CREATE", ""},
+		{"unverified deployment", &common.OwnerCheck{}, rs2, controllerSA, "CREATE", ""},
+		{"delete request", &common.OwnerCheck{}, rs, controllerSA, "DELETE", ""},
+		{"no owner", &common.OwnerCheck{}, nil, controllerSA, "CREATE", ""},
+	}
+	for _, tc := range testCases {
+		pod := newTestOwnedObject("v1", "Pod", "owner-test-pod", "pod-uid", nil, tc.owner)
+		podBytes, _ := pod.MarshalJSON()
+		reqc := &common.ReqContext{Operation: tc.operation, Namespace: "owner-test-ns", UserName: tc.userName, RawObject: podBytes}
+		profile := rspapi.ResourceSigningProfile{Spec: rspapi.ResourceSigningProfileSpec{OwnerCheck: tc.ownerCheck}}
+
+		actual := ""
+		if owner := findVerifiedOwner(profile, reqc, data); owner != nil {
+			actual = owner.GetName()
+		}
+		if actual != tc.expected {
+			t.Errorf("[%s] findVerifiedOwner() Failed\nexpected: %s\nactual: %s", tc.name, tc.expected, actual)
+		}
+	}
+}
@@ -37,7 +37,11 @@ import (
 
 // ResourceSignature
 
-type ResSigLoader struct {
+type ResSigLoader interface {
+	GetData(reqc *common.ReqContext, doK8sApiCall bool) *rsigapi.ResourceSignatureList
+}
+
+type K8sResSigLoader struct {
 	interval           time.Duration
 	signatureNamespace string
 	requestNamespace   string
@@ -48,12 +52,12 @@ type ResSigLoader struct {
 	Data   *rsigapi.ResourceSignatureList
 }
 
-func NewResSigLoader(signatureNamespace, requestNamespace string) *ResSigLoader {
+func NewResSigLoader(signatureNamespace, requestNamespace string) ResSigLoader {
 	interval := time.Second * 0
 	config, _ := kubeutil.GetKubeConfig()
 	client, _ := rsigclient.NewForConfig(config)
 
-	return &ResSigLoader{
+	return &K8sResSigLoader{
 		interval:           interval,
 		signatureNamespace: signatureNamespace,
 		requestNamespace:   requestNamespace,
@@ -61,14 +65,14 @@ func NewResSigLoader(signatureNamespace, requestNamespace string) *ResSigLoader
 	}
 }
 
-func (self *ResSigLoader) GetData(reqc *common.ReqContext, doK8sApiCall bool) *rsigapi.ResourceSignatureList {
+func (self *K8sResSigLoader) GetData(reqc *common.ReqContext, doK8sApiCall bool) *rsigapi.ResourceSignatureList {
 	if self.Data == nil {
 		self.Load(reqc, doK8sApiCall)
 	}
 	return self.Data
 }
 
-func (self *ResSigLoader) Load(reqc *common.ReqContext, doK8sApiCall bool) {
+func (self *K8sResSigLoader) Load(reqc *common.ReqContext, doK8sApiCall bool) {
 	var err error
 	var list1, list2 *rsigapi.ResourceSignatureList
 	var keyName string
@@ -33,7 +33,12 @@ import (
 
 // ResourceSigningProfile
 
-type RSPLoader struct {
+type RSPLoader interface {
+	GetData(doK8sApiCall bool) ([]rspapi.ResourceSigningProfile, bool)
+	ClearCache()
+}
+
+type K8sRSPLoader struct {
 	shieldNamespace        string
 	profileNamespace       string
 	requestNamespace       string
@@ -44,12 +49,12 @@ type RSPLoader struct {
 	Data   []rspapi.ResourceSigningProfile
 }
 
-func NewRSPLoader(shieldNamespace, profileNamespace, requestNamespace string, commonProfile *common.CommonProfile) *RSPLoader {
+func NewRSPLoader(shieldNamespace, profileNamespace, requestNamespace string, commonProfile *common.CommonProfile) RSPLoader {
 	defaultProfileInterval := time.Second * 60
 	config, _ := kubeutil.GetKubeConfig()
 	client, _ := rspclient.NewForConfig(config)
 
-	return &RSPLoader{
+	return &K8sRSPLoader{
 		shieldNamespace:        shieldNamespace,
 		profileNamespace:       profileNamespace,
 		requestNamespace:       requestNamespace,
@@ -59,7 +64,7 @@ func NewRSPLoader(shieldNamespace, profileNamespace, requestNamespace string, co
 	}
 }
 
-func (self *RSPLoader) GetData(doK8sApiCall bool) ([]rspapi.ResourceSigningProfile, bool) {
+func (self *K8sRSPLoader) GetData(doK8sApiCall bool) ([]rspapi.ResourceSigningProfile, bool) {
 	reloaded := false
 	if len(self.Data) == 0 {
 		reloaded = self.Load(doK8sApiCall)
@@ -67,7 +72,7 @@ func (self *RSPLoader) GetData(doK8sApiCall bool) ([]rspapi.ResourceSigningProfi
 	return self.Data, reloaded
 }
 
-func (self *RSPLoader) Load(doK8sApiCall bool) bool {
+func (self *K8sRSPLoader) Load(doK8sApiCall bool) bool {
 	var err error
 	var list1 *rspapi.ResourceSigningProfileList
 	var keyName string
@@ -103,7 +108,7 @@ func (self *RSPLoader) Load(doK8sApiCall bool) bool {
 	return reloaded
 }
 
-func (self *RSPLoader) UpdateStatus(rsp *rspapi.ResourceSigningProfile, reqc *common.ReqContext, errMsg string) error {
+func (self *K8sRSPLoader) UpdateStatus(rsp *rspapi.ResourceSigningProfile, reqc *common.ReqContext, errMsg string, mode common.IntegrityShieldMode) error {
 	rspNamespace := rsp.GetNamespace()
 	rspName := rsp.GetName()
 	rspOrg, err := self.Client.ResourceSigningProfiles(rspNamespace).Get(context.Background(), rspName, metav1.GetOptions{})
@@ -112,7 +117,7 @@ func (self *RSPLoader) UpdateStatus(rsp *rspapi.ResourceSigningProfile, reqc *co
 	}
 
 	req := common.NewRequestFromReqContext(reqc)
-	rspNew := rspOrg.UpdateStatus(req, errMsg)
+	rspNew := rspOrg.UpdateStatus(req, errMsg, mode)
 
 	_, err = self.Client.ResourceSigningProfiles(rspNamespace).Update(context.Background(), rspNew, metav1.UpdateOptions{})
 	if err != nil {
@@ -121,6 +126,6 @@ func (self *RSPLoader) UpdateStatus(rsp *rspapi.ResourceSigningProfile, reqc *co
 	return nil
 }
 
-func (self *RSPLoader) ClearCache() {
+func (self *K8sRSPLoader) ClearCache() {
 	cache.Unset("RSPLoader/list")
 }
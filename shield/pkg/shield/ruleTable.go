@@ -17,14 +17,20 @@
 package shield
 
 import (
+	"fmt"
+
 	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
 	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 )
 
 type RuleItem struct {
 	Profile          rspapi.ResourceSigningProfile `json:"profile,omitempty"`
 	TargetNamespaces []string                      `json:"targetNamespaces,omitempty"`
+	// errors of conditions which cannot be compiled, reported to the requesters while the profile is used
+	ConditionErrors []string `json:"conditionErrors,omitempty"`
 }
 
 type RuleTable struct {
@@ -57,7 +63,8 @@ func NewRuleTable(profiles []rspapi.ResourceSigningProfile, namespaces []v1.Name
 			targetNamespaces = append(targetNamespaces, pNamespace)
 		}
 		pWithCommon := p.Merge(commonProfileRSP)
-		items = append(items, RuleItem{Profile: pWithCommon, TargetNamespaces: targetNamespaces})
+		conditionErrors := compileConditions(pWithCommon)
+		items = append(items, RuleItem{Profile: pWithCommon, TargetNamespaces: targetNamespaces, ConditionErrors: conditionErrors})
 		allTargetNamespaces = common.GetUnionOfArrays(allTargetNamespaces, targetNamespaces)
 	}
 	return &RuleTable{
@@ -86,7 +93,7 @@ func (self *RuleTable) CheckIfTargetNamespace(nsName string) bool {
 	return common.ExactMatchWithPatternArray(nsName, self.Namespaces)
 }
 
-func (self *RuleTable) CheckIfProtected(reqFields map[string]string) (bool, bool, []rspapi.ResourceSigningProfile) {
+func (self *RuleTable) CheckIfProtected(reqFields map[string]string, vars *common.ConditionVars) (bool, bool, []rspapi.ResourceSigningProfile) {
 	matchedProfiles := []rspapi.ResourceSigningProfile{}
 	reqNs := reqFields["Namespace"]
 	reqScope := reqFields["ResourceScope"]
@@ -96,7 +103,7 @@ func (self *RuleTable) CheckIfProtected(reqFields map[string]string) (bool, bool
 		if reqScope == "Namespaced" && !common.ExactMatchWithPatternArray(reqNs, item.TargetNamespaces) {
 			continue
 		}
-		if tmpProtected, matchedRule := item.Profile.Match(reqFields, self.ShieldNamespace); tmpProtected {
+		if tmpProtected, matchedRule := item.Profile.Match(reqFields, vars, self.ShieldNamespace); tmpProtected {
 			protected = true
 			matchedProfiles = append(matchedProfiles, item.Profile)
 		} else if !tmpProtected && matchedRule != nil {
@@ -106,6 +113,76 @@ func (self *RuleTable) CheckIfProtected(reqFields map[string]string) (bool, bool
 	return protected, ignoreMatched, matchedProfiles
 }
 
+// MatchedRules returns the first matched rule of each profile for the request. (key: "<namespace>/<name>")
+// This is used only for explaining a decision, so the result is not used for the decision itself.
+func (self *RuleTable) MatchedRules(reqFields map[string]string, vars *common.ConditionVars) map[string]*common.Rule {
+	rules := map[string]*common.Rule{}
+	reqNs := reqFields["Namespace"]
+	reqScope := reqFields["ResourceScope"]
+	for _, item := range self.Items {
+		if reqScope == "Namespaced" && !common.ExactMatchWithPatternArray(reqNs, item.TargetNamespaces) {
+			continue
+		}
+		if _, matchedRule := item.Profile.Match(reqFields, vars, self.ShieldNamespace); matchedRule != nil {
+			rules[profileKey(item.Profile)] = matchedRule
+		}
+	}
+	return rules
+}
+
+// ConditionErrors returns the errors of conditions which cannot be compiled in the profiles.
+func (self *RuleTable) ConditionErrors(profiles []rspapi.ResourceSigningProfile) []string {
+	keys := map[string]bool{}
+	for _, prof := range profiles {
+		keys[profileKey(prof)] = true
+	}
+	errs := []string{}
+	for _, item := range self.Items {
+		if !keys[profileKey(item.Profile)] {
+			continue
+		}
+		for _, err := range item.ConditionErrors {
+			errs = append(errs, fmt.Sprintf("ResourceSigningProfile %s has an invalid condition; %s", profileKey(item.Profile), err))
+		}
+	}
+	return errs
+}
+
+func profileKey(profile rspapi.ResourceSigningProfile) string {
+	return fmt.Sprintf("%s/%s", profile.GetNamespace(), profile.GetName())
+}
+
+// compileConditions compiles the conditions in the profile in advance, so that they are not compiled for each request.
+// A condition which cannot be compiled is handled as an error when it is evaluated, so the errors are returned
+// to be reported to the requesters.
+func compileConditions(profile rspapi.ResourceSigningProfile) []string {
+	conditions := []string{}
+	for _, rules := range [][]*common.Rule{profile.Spec.ForceCheckRules, profile.Spec.IgnoreRules, profile.Spec.ProtectRules} {
+		for _, rule := range rules {
+			conditions = append(conditions, rule.Condition)
+		}
+	}
+	for _, attrs := range [][]*common.AttrsPattern{profile.Spec.ProtectAttrs, profile.Spec.IgnoreAttrs, profile.Spec.UnprotectAttrs} {
+		for _, attr := range attrs {
+			conditions = append(conditions, attr.Condition)
+		}
+	}
+	errs := []string{}
+	for _, condition := range conditions {
+		if condition == "" {
+			continue
+		}
+		if _, err := common.CompileCondition(condition); err != nil {
+			logger.WithFields(log.Fields{
+				"profile": profileKey(profile),
+				"err":     err,
+			}).Warn("Error when compiling condition in ResourceSigningProfile")
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
 func matchNamespaceListWithSelector(namespaces []v1.Namespace, nsSelector *common.NamespaceSelector) []string {
 	matched := []string{}
 
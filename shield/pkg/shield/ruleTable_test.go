@@ -0,0 +1,148 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+	"testing"
+
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
+	"github.com/IBM/integrity-enforcer/shield/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRuleTableCondition(t *testing.T) {
+	kind := common.RulePattern("ConfigMap")
+	hpa := common.RulePattern("system:serviceaccount:kube-system:horizontal-pod-autoscaler")
+	profile := rspapi.ResourceSigningProfile{
+		Spec: rspapi.ResourceSigningProfileSpec{
+			ProtectRules: []*common.Rule{{Match: []*common.RequestPattern{{Kind: &kind}}, Condition: "has(object.data.protected)"}},
+			IgnoreRules:  []*common.Rule{{Match: []*common.RequestPattern{{UserName: &hpa}}, Condition: "object.data.invalid == 'true'"}},
+			IgnoreAttrs:  []*common.AttrsPattern{{Match: []*common.RequestPattern{{Kind: &kind}}, Attrs: []string{"data.replicas"}, Condition: "request.userInfo.username == 'hpa'"}},
+		},
+	}
+	profile.SetName("sample-rsp")
+	profile.SetNamespace("secure-ns")
+	ruleTable := NewRuleTable([]rspapi.ResourceSigningProfile{profile}, nil, nil, "integrity-shield-operator-system")
+
+	testCases := []struct {
+		name      string
+		userName  string
+		request   string
+		protected bool
+	}{
+		{"withKey", "alice", `{"userInfo":{"username":"alice"},"object":{"data":{"protected":"true"}}}`, true},
+		{"withoutKey", "alice", `{"userInfo":{"username":"alice"},"object":{"data":{"other":"true"}}}`, false},
+		// the condition of the ignore rule cannot be evaluated, so the request is not ignored
+		{"ignoreError", string(hpa), `{"userInfo":{"username":"hpa"},"object":{"data":{"protected":"true"}}}`, true},
+	}
+	for _, tc := range testCases {
+		reqFields := map[string]string{
+			"Kind":                     "ConfigMap",
+			"Namespace":                "secure-ns",
+			"ResourceScope":            "Namespaced",
+			"UserName":                 tc.userName,
+			common.ReqFieldRequestJson: tc.request,
+		}
+		protected, _, _ := ruleTable.CheckIfProtected(reqFields, common.NewConditionVars(reqFields[common.ReqFieldRequestJson]))
+		if protected != tc.protected {
+			t.Errorf("TestRuleTableCondition() Failed (%s)\nexpected: %v\nactual: %v", tc.name, tc.protected, protected)
+		}
+	}
+
+	for userName, expected := range map[string]int{"hpa": 1, "alice": 0} {
+		reqFields := map[string]string{"Kind": "ConfigMap", common.ReqFieldRequestJson: `{"userInfo":{"username":"` + userName + `"}}`}
+		if actual := len(profile.IgnoreAttrs(reqFields, common.NewConditionVars(reqFields[common.ReqFieldRequestJson]))); actual != expected {
+			t.Errorf("TestRuleTableCondition() Failed (ignoreAttrs by %s)\nexpected: %v\nactual: %v", userName, expected, actual)
+		}
+	}
+}
+
+func TestRuleTableIgnoreSelector(t *testing.T) {
+	kind := common.RulePattern("ConfigMap")
+	skip := &metav1.LabelSelector{MatchLabels: map[string]string{"example.com/skip-check": "true"}}
+	profile := rspapi.ResourceSigningProfile{
+		Spec: rspapi.ResourceSigningProfileSpec{
+			ProtectRules: []*common.Rule{{Match: []*common.RequestPattern{{Kind: &kind}}}},
+			IgnoreRules:  []*common.Rule{{Match: []*common.RequestPattern{{Kind: &kind, LabelSelector: skip}}}},
+			IgnoreAttrs:  []*common.AttrsPattern{{Match: []*common.RequestPattern{{Kind: &kind, LabelSelector: skip}}, Attrs: []string{"data.key1"}}},
+		},
+	}
+	profile.SetName("sample-rsp")
+	profile.SetNamespace("secure-ns")
+	ruleTable := NewRuleTable([]rspapi.ResourceSigningProfile{profile}, nil, nil, "integrity-shield-operator-system")
+
+	skipLabels := map[string]string{"example.com/skip-check": "true"}
+	testCases := []struct {
+		name      string
+		operation string
+		labels    map[string]string
+		oldLabels map[string]string
+		protected bool
+	}{
+		// the label is added by the request itself, so the request must not be ignored
+		{"labelAdded", "UPDATE", skipLabels, nil, true},
+		{"labelRemoved", "UPDATE", nil, skipLabels, true},
+		{"labelKept", "UPDATE", skipLabels, skipLabels, false},
+		{"deleteLabeled", "DELETE", nil, skipLabels, false},
+		// labels for CREATE are controlled by the requester, so they cannot make the request ignored
+		{"createLabeled", "CREATE", skipLabels, nil, true},
+	}
+	for _, tc := range testCases {
+		reqFields := map[string]string{
+			"Kind":          "ConfigMap",
+			"Namespace":     "secure-ns",
+			"ResourceScope": "Namespaced",
+			"Operation":     tc.operation,
+		}
+		common.SetMetadataFields(reqFields,
+			&common.ObjectMetadata{Labels: common.NewResourceLabel(tc.labels), Annotations: common.NewResourceAnnotation(nil)},
+			&common.ObjectMetadata{Labels: common.NewResourceLabel(tc.oldLabels), Annotations: common.NewResourceAnnotation(nil)})
+		protected, _, _ := ruleTable.CheckIfProtected(reqFields, common.NewConditionVars(reqFields[common.ReqFieldRequestJson]))
+		if protected != tc.protected {
+			t.Errorf("TestRuleTableIgnoreSelector() Failed (%s)\nexpected: %v\nactual: %v", tc.name, tc.protected, protected)
+		}
+		ignored := len(profile.IgnoreAttrs(reqFields, common.NewConditionVars(reqFields[common.ReqFieldRequestJson]))) > 0
+		if ignored == tc.protected {
+			t.Errorf("TestRuleTableIgnoreSelector() Failed (ignoreAttrs, %s)\nexpected: %v\nactual: %v", tc.name, !tc.protected, ignored)
+		}
+	}
+}
+
+func TestRuleTableConditionErrors(t *testing.T) {
+	kind := common.RulePattern("ConfigMap")
+	profile := rspapi.ResourceSigningProfile{
+		Spec: rspapi.ResourceSigningProfileSpec{
+			ProtectRules: []*common.Rule{{Match: []*common.RequestPattern{{Kind: &kind}}, Condition: "object.data"}},
+		},
+	}
+	profile.SetName("sample-rsp")
+	profile.SetNamespace("secure-ns")
+	ruleTable := NewRuleTable([]rspapi.ResourceSigningProfile{profile}, nil, nil, "integrity-shield-operator-system")
+
+	reqFields := map[string]string{"Kind": "ConfigMap", "Namespace": "secure-ns", "ResourceScope": "Namespaced"}
+	// the condition cannot be compiled, so the rule to protect resources is regarded as matched
+	protected, _, matchedProfiles := ruleTable.CheckIfProtected(reqFields, common.NewConditionVars(""))
+	if !protected {
+		t.Errorf("TestRuleTableConditionErrors() Failed\nexpected: protected\nactual: %v", protected)
+		return
+	}
+	errs := ruleTable.ConditionErrors(matchedProfiles)
+	if len(errs) != 1 || !strings.Contains(errs[0], "secure-ns/sample-rsp") {
+		t.Errorf("TestRuleTableConditionErrors() Failed\nexpected: an error of secure-ns/sample-rsp\nactual: %v", errs)
+	}
+}
@@ -18,6 +18,7 @@ package shield
 
 import (
 	"encoding/json"
+	"fmt"
 
 	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
 	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
@@ -27,6 +28,8 @@ import (
 
 	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 /**********************************************
@@ -40,10 +43,12 @@ type RunData struct {
 	NSList       []v1.Namespace                  `json:"nsList,omitempty"`
 	SignerConfig *sigconfapi.SignerConfig        `json:"signerConfig,omitempty"`
 	ResSigList   *rsigapi.ResourceSignatureList  `json:"resSigList,omitempty"`
+	Revocations  *common.RevocationList          `json:"revocations,omitempty"`
 
-	loader        *Loader               `json:"-"`
-	commonProfile *common.CommonProfile `json:"-"`
-	ruleTable     *RuleTable            `json:"-"`
+	keys          []common.VerificationKey `json:"-"`
+	loader        *Loader                  `json:"-"`
+	commonProfile *common.CommonProfile    `json:"-"`
+	ruleTable     *RuleTable               `json:"-"`
 }
 
 func (self *RunData) GetSignerConfig() *sigconfapi.SignerConfig {
@@ -60,6 +65,43 @@ func (self *RunData) GetResSigList(reqc *common.ReqContext) *rsigapi.ResourceSig
 	return self.ResSigList
 }
 
+func (self *RunData) GetRevocationList() (*common.RevocationList, error) {
+	if self.Revocations == nil && self.loader != nil && self.loader.SignatureRevocation != nil {
+		revocations, err := self.loader.SignatureRevocation.GetData(true)
+		if err != nil {
+			return nil, err
+		}
+		self.Revocations = revocations
+	}
+	return self.Revocations, nil
+}
+
+// GetVerificationKeys returns the keys for signature verification. The keys are loaded for every request,
+// so rotated keys are used without restarting the server.
+func (self *RunData) GetVerificationKeys(conf *config.ShieldConfig) []common.VerificationKey {
+	if self.keys == nil {
+		if self.loader != nil && self.loader.Keys != nil {
+			self.keys = self.loader.Keys.GetKeys()
+		} else {
+			self.keys = (&FileKeyProvider{KeyPathList: conf.KeyPathList}).GetKeys()
+		}
+	}
+	return self.keys
+}
+
+// GetOwner returns the owner of the requested resource. Owners are not cached in RunData because they are looked up only for `ownerCheck`.
+func (self *RunData) GetOwner(ref metav1.OwnerReference, namespace string) (*unstructured.Unstructured, error) {
+	if self.loader == nil || self.loader.Owner == nil {
+		return nil, fmt.Errorf("owner resources are not available")
+	}
+	return self.loader.Owner.GetOwner(ref, namespace)
+}
+
+// DryRunDisabled returns true if the request is processed without a cluster, e.g. by ishieldctl
+func (self *RunData) DryRunDisabled() bool {
+	return self.loader != nil && self.loader.DryRunDisabled
+}
+
 func (self *RunData) setRuleTable(shieldNamespace string) bool {
 	updated := false
 	ruleTable := NewRuleTable(self.RSPList, self.NSList, self.commonProfile, shieldNamespace)
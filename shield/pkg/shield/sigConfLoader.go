@@ -32,7 +32,11 @@ import (
 
 // SignerConfig
 
-type SignerConfigLoader struct {
+type SignerConfigLoader interface {
+	GetData(doK8sApiCall bool) *sigconfapi.SignerConfig
+}
+
+type K8sSignerConfigLoader struct {
 	interval        time.Duration
 	shieldNamespace string
 
@@ -40,26 +44,26 @@ type SignerConfigLoader struct {
 	Data   *sigconfapi.SignerConfig
 }
 
-func NewSignerConfigLoader(shieldNamespace string) *SignerConfigLoader {
+func NewSignerConfigLoader(shieldNamespace string) SignerConfigLoader {
 	interval := time.Second * 10
 	config, _ := kubeutil.GetKubeConfig()
 	client, _ := sigconfclient.NewForConfig(config)
 
-	return &SignerConfigLoader{
+	return &K8sSignerConfigLoader{
 		interval:        interval,
 		shieldNamespace: shieldNamespace,
 		Client:          client,
 	}
 }
 
-func (self *SignerConfigLoader) GetData(doK8sApiCall bool) *sigconfapi.SignerConfig {
+func (self *K8sSignerConfigLoader) GetData(doK8sApiCall bool) *sigconfapi.SignerConfig {
 	if self.Data == nil {
 		self.Load(doK8sApiCall)
 	}
 	return self.Data
 }
 
-func (self *SignerConfigLoader) Load(doK8sApiCall bool) {
+func (self *K8sSignerConfigLoader) Load(doK8sApiCall bool) {
 	var err error
 	var list1 *sigconfapi.SignerConfigList
 	var keyName string
@@ -0,0 +1,96 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sigrevokeapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signaturerevocation/v1alpha1"
+	sigrevokeclient "github.com/IBM/integrity-enforcer/shield/pkg/client/signaturerevocation/clientset/versioned/typed/signaturerevocation/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	cache "github.com/IBM/integrity-enforcer/shield/pkg/util/cache"
+	"github.com/IBM/integrity-enforcer/shield/pkg/util/kubeutil"
+	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SignatureRevocation
+
+type SignatureRevocationLoader interface {
+	GetData(doK8sApiCall bool) (*common.RevocationList, error)
+}
+
+type K8sSignatureRevocationLoader struct {
+	interval        time.Duration
+	shieldNamespace string
+
+	Client sigrevokeclient.ApisV1alpha1Interface
+	Data   *common.RevocationList
+}
+
+func NewSignatureRevocationLoader(shieldNamespace string) SignatureRevocationLoader {
+	interval := time.Second * 10
+	config, _ := kubeutil.GetKubeConfig()
+	client, _ := sigrevokeclient.NewForConfig(config)
+
+	return &K8sSignatureRevocationLoader{
+		interval:        interval,
+		shieldNamespace: shieldNamespace,
+		Client:          client,
+	}
+}
+
+// GetData returns an error if SignatureRevocations cannot be loaded, because an empty list would accept revoked signatures.
+func (self *K8sSignatureRevocationLoader) GetData(doK8sApiCall bool) (*common.RevocationList, error) {
+	if self.Data == nil {
+		if err := self.Load(doK8sApiCall); err != nil {
+			return nil, err
+		}
+	}
+	return self.Data, nil
+}
+
+// Load merges all SignatureRevocations in IShield namespace. Unlike SignerConfig, an empty list is cached too
+// because usually there is no SignatureRevocation.
+func (self *K8sSignatureRevocationLoader) Load(doK8sApiCall bool) error {
+	var err error
+	var list1 *sigrevokeapi.SignatureRevocationList
+	var keyName string
+
+	keyName = fmt.Sprintf("SignatureRevocationLoader/%s/list", self.shieldNamespace)
+	if cached := cache.GetString(keyName); cached == "" && doK8sApiCall {
+		list1, err = self.Client.SignatureRevocations(self.shieldNamespace).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			logger.Error("failed to get SignatureRevocation:", err)
+			return fmt.Errorf("failed to get SignatureRevocation: %s", err.Error())
+		}
+		logger.Debug("SignatureRevocation reloaded.")
+		tmp, _ := json.Marshal(list1)
+		cache.SetString(keyName, string(tmp), &(self.interval))
+	} else if cached != "" {
+		err = json.Unmarshal([]byte(cached), &list1)
+		if err != nil {
+			logger.Error("failed to Unmarshal cached SignatureRevocation:", err)
+			return fmt.Errorf("failed to Unmarshal cached SignatureRevocation: %s", err.Error())
+		}
+	}
+	self.Data = list1.GetRevocationList()
+	return nil
+}
@@ -0,0 +1,67 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
+	sigrevokeapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/signaturerevocation/v1alpha1"
+	"github.com/IBM/integrity-enforcer/shield/pkg/client/signaturerevocation/clientset/versioned/fake"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestK8sSignatureRevocationLoader(t *testing.T) {
+	ns := "revocation-loader-test"
+	revocation := &sigrevokeapi.SignatureRevocation{
+		ObjectMeta: metav1.ObjectMeta{Name: "incident-123", Namespace: ns},
+		Spec: sigrevokeapi.SignatureRevocationSpec{
+			RevocationList: common.RevocationList{SignerFingerprints: []string{"5B6D7E"}},
+		},
+	}
+	client := fake.NewSimpleClientset(revocation)
+	loader := &K8sSignatureRevocationLoader{interval: time.Second, shieldNamespace: ns, Client: client.ApisV1alpha1()}
+	revocations, err := loader.GetData(true)
+	if err != nil || revocations == nil || len(revocations.SignerFingerprints) != 1 {
+		t.Errorf("K8sSignatureRevocationLoader Failed\nexpected: 1 revoked signer\nactual: %v, %v", revocations, err)
+	}
+}
+
+func TestK8sSignatureRevocationLoaderError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "signaturerevocations", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("the server is currently unable to handle the request")
+	})
+	loader := &K8sSignatureRevocationLoader{interval: time.Second, shieldNamespace: "revocation-loader-error-test", Client: client.ApisV1alpha1()}
+	data := &RunData{loader: &Loader{SignatureRevocation: loader}}
+
+	// signatures must not be accepted without revocations, so the error is returned instead of an empty list
+	revocations, err := data.GetRevocationList()
+	if err == nil || revocations != nil {
+		t.Errorf("K8sSignatureRevocationLoader Failed\nexpected: error\nactual: %v, %v", revocations, err)
+	}
+
+	allowed, reasonCode, _, _, _ := singleProfileCheck(rspapi.ResourceSigningProfile{}, newTestCreateRequest(), nil, nil, nil, nil, revocations, err, false)
+	if allowed || reasonCode != common.REASON_ERROR {
+		t.Errorf("singleProfileCheck() Failed\nexpected: denied with %d\nactual: %v, %d", common.REASON_ERROR, allowed, reasonCode)
+	}
+}
@@ -0,0 +1,221 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	rsigapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	cache "github.com/IBM/integrity-enforcer/shield/pkg/util/cache"
+	"github.com/IBM/integrity-enforcer/shield/pkg/util/kubeutil"
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const defaultSignatureStoreTimeout = time.Second * 5
+
+// SignatureStore is an external store of signatures, which is used instead of ResourceSignature in the cluster.
+// Signatures are stored in the same format as ResourceSignature.
+type SignatureStore interface {
+	Name() string
+	GetSignItems(ref *common.ResourceRef) ([]*rsigapi.SignItem, error)
+}
+
+func NewSignatureStores(cfg *config.ShieldConfig) ([]SignatureStore, error) {
+	stores := []SignatureStore{}
+	if cfg == nil {
+		return stores, nil
+	}
+	for _, storeCfg := range cfg.SignatureStores {
+		store, err := NewSignatureStore(storeCfg, cfg.SignatureNamespace)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+	return stores, nil
+}
+
+func NewSignatureStore(storeCfg config.SignatureStoreConfig, signatureNamespace string) (SignatureStore, error) {
+	switch storeCfg.Type {
+	case config.SignatureStoreTypeHTTP:
+		return NewHTTPSignatureStore(storeCfg)
+	case config.SignatureStoreTypeConfigMap:
+		return NewConfigMapSignatureStore(storeCfg, signatureNamespace), nil
+	}
+	return nil, fmt.Errorf("unsupported signature store type `%s`", storeCfg.Type)
+}
+
+// unmarshalResourceSignature reads signatures from a ResourceSignature in YAML or JSON.
+func unmarshalResourceSignature(data []byte) ([]*rsigapi.SignItem, error) {
+	var rsig *rsigapi.ResourceSignature
+	if err := yaml.Unmarshal(data, &rsig); err != nil {
+		return nil, err
+	}
+	if rsig == nil {
+		return nil, nil
+	}
+	return rsig.Spec.Data, nil
+}
+
+/**********************************************
+
+				HTTPSignatureStore
+
+***********************************************/
+
+// HTTPSignatureStore looks up signatures by apiVersion, kind, namespace and name of the resource.
+// The server returns a ResourceSignature for the resource, or 404 if no signature is found.
+type HTTPSignatureStore struct {
+	name     string
+	url      string
+	interval time.Duration
+	client   *http.Client
+}
+
+func NewHTTPSignatureStore(storeCfg config.SignatureStoreConfig) (*HTTPSignatureStore, error) {
+	if _, err := url.Parse(storeCfg.URL); err != nil {
+		return nil, err
+	}
+	timeout := defaultSignatureStoreTimeout
+	if storeCfg.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(storeCfg.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return &HTTPSignatureStore{
+		name:     storeCfg.GetName(),
+		url:      storeCfg.URL,
+		interval: time.Second * 10,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (self *HTTPSignatureStore) Name() string {
+	return self.name
+}
+
+func (self *HTTPSignatureStore) GetSignItems(ref *common.ResourceRef) ([]*rsigapi.SignItem, error) {
+	u, err := url.Parse(self.url)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("apiVersion", ref.ApiVersion)
+	query.Set("kind", ref.Kind)
+	query.Set("namespace", ref.Namespace)
+	query.Set("name", ref.Name)
+	u.RawQuery = query.Encode()
+	reqURL := u.String()
+
+	keyName := fmt.Sprintf("SignatureStore/%s/%s", self.name, reqURL)
+	var body []byte
+	if cache.KeyExists(keyName) {
+		body, _ = cache.Get(keyName).([]byte)
+	} else {
+		resp, err := self.client.Get(reqURL)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			body = []byte{}
+		} else if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("signature store `%s` returned status %d", self.name, resp.StatusCode)
+		} else if body, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+		cache.Set(keyName, body, &(self.interval))
+	}
+	if len(body) == 0 {
+		return nil, nil
+	}
+	return unmarshalResourceSignature(body)
+}
+
+/**********************************************
+
+				ConfigMapSignatureStore
+
+***********************************************/
+
+// ConfigMapSignatureStore reads signatures from a ConfigMap, each value of which is a ResourceSignature.
+type ConfigMapSignatureStore struct {
+	name      string
+	namespace string
+	cmName    string
+	interval  time.Duration
+
+	Client *v1client.CoreV1Client
+}
+
+func NewConfigMapSignatureStore(storeCfg config.SignatureStoreConfig, signatureNamespace string) *ConfigMapSignatureStore {
+	namespace := storeCfg.ConfigMapNamespace
+	if namespace == "" {
+		namespace = signatureNamespace
+	}
+	kubeconf, _ := kubeutil.GetKubeConfig()
+	client, _ := v1client.NewForConfig(kubeconf)
+	return &ConfigMapSignatureStore{
+		name:      storeCfg.GetName(),
+		namespace: namespace,
+		cmName:    storeCfg.ConfigMapName,
+		interval:  time.Second * 10,
+		Client:    client,
+	}
+}
+
+func (self *ConfigMapSignatureStore) Name() string {
+	return self.name
+}
+
+func (self *ConfigMapSignatureStore) GetSignItems(ref *common.ResourceRef) ([]*rsigapi.SignItem, error) {
+	keyName := fmt.Sprintf("SignatureStore/%s/%s/%s", self.name, self.namespace, self.cmName)
+	var data map[string]string
+	if cache.KeyExists(keyName) {
+		data, _ = cache.Get(keyName).(map[string]string)
+	} else {
+		cm, err := self.Client.ConfigMaps(self.namespace).Get(context.Background(), self.cmName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		data = cm.Data
+		cache.Set(keyName, data, &(self.interval))
+	}
+	return signItemsFromConfigMapData(data)
+}
+
+func signItemsFromConfigMapData(data map[string]string) ([]*rsigapi.SignItem, error) {
+	signItems := []*rsigapi.SignItem{}
+	for key, val := range data {
+		items, err := unmarshalResourceSignature([]byte(val))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ResourceSignature in `%s`; %s", key, err.Error())
+		}
+		signItems = append(signItems, items...)
+	}
+	return signItems, nil
+}
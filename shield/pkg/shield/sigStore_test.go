@@ -0,0 +1,138 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const testStoredMessage = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: sample-cm
+  namespace: secure-ns
+data:
+  key: value
+`
+
+func newTestStoredResourceSignature() string {
+	encodedMsg := base64.StdEncoding.EncodeToString([]byte(testStoredMessage))
+	encodedSig := base64.StdEncoding.EncodeToString([]byte("dummy-signature"))
+	return fmt.Sprintf(`apiVersion: apis.integrityshield.io/v1alpha1
+kind: ResourceSignature
+metadata:
+  name: rsig-sample-cm
+spec:
+  data:
+  - message: %s
+    signature: %s
+    type: resource
+`, encodedMsg, encodedSig)
+}
+
+func newTestCreateRequest() *common.ReqContext {
+	obj := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "sample-cm",
+			"namespace": "secure-ns",
+		},
+		"data": map[string]interface{}{
+			"key": "value",
+		},
+	}
+	objBytes, _ := json.Marshal(obj)
+	dryRun := false
+	req := &admv1.AdmissionRequest{
+		UID:       types.UID("test-uid"),
+		Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+		Name:      "sample-cm",
+		Namespace: "secure-ns",
+		Operation: admv1.Create,
+		Object:    runtime.RawExtension{Raw: objBytes},
+		DryRun:    &dryRun,
+	}
+	return common.NewReqContext(req)
+}
+
+func TestHTTPSignatureStore(t *testing.T) {
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested++
+		query := r.URL.Query()
+		if query.Get("apiVersion") != "v1" || query.Get("kind") != "ConfigMap" || query.Get("namespace") != "secure-ns" || query.Get("name") != "sample-cm" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, newTestStoredResourceSignature())
+	}))
+	defer server.Close()
+
+	store, err := NewHTTPSignatureStore(config.SignatureStoreConfig{Name: "artifact-service", Type: config.SignatureStoreTypeHTTP, URL: server.URL + "/signatures"})
+	if err != nil {
+		t.Errorf("failed to create signature store; %s", err.Error())
+		return
+	}
+	evaluator := &ConcreteSignatureEvaluator{stores: []SignatureStore{store}}
+
+	reqc := newTestCreateRequest()
+	sig := evaluator.GetResourceSignature(reqc.ResourceRef(), reqc, nil)
+	if sig == nil || sig.Source() != SignatureSourceStore || sig.data["signatureStore"] != "artifact-service" {
+		t.Errorf("signature in the store is not found; actual: %v", sig)
+	} else if sig.data["signature"] != "dummy-signature" {
+		t.Errorf("TestHTTPSignatureStore() Failed\nexpected: dummy-signature\nactual: %s", sig.data["signature"])
+	}
+
+	// the response is cached
+	_ = evaluator.GetResourceSignature(reqc.ResourceRef(), reqc, nil)
+	if requested != 1 {
+		t.Errorf("TestHTTPSignatureStore() Failed\nexpected: 1 request\nactual: %d requests", requested)
+	}
+
+	// no signature for another resource
+	ref := reqc.ResourceRef()
+	ref.Name = "another-cm"
+	if signItems, err := store.GetSignItems(ref); err != nil || len(signItems) != 0 {
+		t.Errorf("signature for another resource should not be found; actual: %v, %v", signItems, err)
+	}
+}
+
+func TestConfigMapSignatureStore(t *testing.T) {
+	data := map[string]string{"rsig-sample-cm.yaml": newTestStoredResourceSignature()}
+	signItems, err := signItemsFromConfigMapData(data)
+	if err != nil || len(signItems) != 1 {
+		t.Errorf("TestConfigMapSignatureStore() Failed\nexpected: 1 signature\nactual: %v, %v", signItems, err)
+	}
+
+	data["broken.yaml"] = "spec: ["
+	if _, err := signItemsFromConfigMapData(data); err == nil {
+		t.Errorf("broken ResourceSignature in ConfigMap should be an error")
+	}
+}
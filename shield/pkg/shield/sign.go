@@ -19,6 +19,8 @@ package shield
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	vrsig "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
 	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
@@ -26,7 +28,10 @@ import (
 	helm "github.com/IBM/integrity-enforcer/shield/pkg/plugins/helm"
 	config "github.com/IBM/integrity-enforcer/shield/pkg/shield/config"
 	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	mapnode "github.com/IBM/integrity-enforcer/shield/pkg/util/mapnode"
+	metrics "github.com/IBM/integrity-enforcer/shield/pkg/util/metrics"
 	pgp "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/pgp"
+	sigstore "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/sigstore"
 	x509 "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
 	ishieldyaml "github.com/IBM/integrity-enforcer/shield/pkg/util/yaml"
 )
@@ -39,6 +44,7 @@ const (
 	SignedResourceTypeApplyingResource SignedResourceType = "ApplyingResource"
 	SignedResourceTypePatch            SignedResourceType = "Patch"
 	SignedResourceTypeHelm             SignedResourceType = "Helm"
+	SignedResourceTypeDelete           SignedResourceType = "Delete"
 )
 
 /**********************************************
@@ -53,6 +59,25 @@ type GeneralSignature struct {
 	option   map[string]bool
 }
 
+const (
+	SignatureSourceAnnotation        = "annotation"
+	SignatureSourceResourceSignature = "ResourceSignature"
+	SignatureSourceHelm              = "helm"
+	SignatureSourceStore             = "signatureStore"
+)
+
+// Source returns where this signature is found
+func (self *GeneralSignature) Source() string {
+	if self.SignType == SignedResourceTypeHelm {
+		return SignatureSourceHelm
+	} else if self.data["resourceSignatureUID"] != "" {
+		return SignatureSourceResourceSignature
+	} else if self.data["signatureStore"] != "" {
+		return SignatureSourceStore
+	}
+	return SignatureSourceAnnotation
+}
+
 /**********************************************
 
                 Signature
@@ -66,83 +91,90 @@ type SignatureEvaluator interface {
 type ConcreteSignatureEvaluator struct {
 	config       *config.ShieldConfig
 	signerConfig *common.SignerConfig
+	keys         []common.VerificationKey
+	revocations  *common.RevocationList
+	stores       []SignatureStore
 	plugins      map[string]bool
+	// skip dry-run in matching the message with the request if the cluster is not available
+	dryRunDisabled bool
 }
 
-func NewSignatureEvaluator(config *config.ShieldConfig, signerConfig *common.SignerConfig, plugins map[string]bool) (SignatureEvaluator, error) {
+func NewSignatureEvaluator(config *config.ShieldConfig, signerConfig *common.SignerConfig, keys []common.VerificationKey, revocations *common.RevocationList, plugins map[string]bool, dryRunDisabled bool) (SignatureEvaluator, error) {
+	stores, err := NewSignatureStores(config)
+	if err != nil {
+		return nil, err
+	}
 	return &ConcreteSignatureEvaluator{
-		config:       config,
-		signerConfig: signerConfig,
-		plugins:      plugins,
+		config:         config,
+		signerConfig:   signerConfig,
+		keys:           keys,
+		revocations:    revocations,
+		stores:         stores,
+		plugins:        plugins,
+		dryRunDisabled: dryRunDisabled,
 	}, nil
 }
 
+// GetResourceSignature returns the first signature for the request
 func (self *ConcreteSignatureEvaluator) GetResourceSignature(ref *common.ResourceRef, reqc *common.ReqContext, resSigList *vrsig.ResourceSignatureList) *GeneralSignature {
+	sigs := self.GetResourceSignatures(ref, reqc, resSigList)
+	if len(sigs) == 0 {
+		return nil
+	}
+	return sigs[0]
+}
 
-	sigAnnotations := reqc.ClaimedMetadata.Annotations.SignatureAnnotations()
+// GetResourceSignatures returns all signatures for the request. Multiple signatures are found
+// in annotations with numbered suffix or in multiple SignItems for the same object.
+func (self *ConcreteSignatureEvaluator) GetResourceSignatures(ref *common.ResourceRef, reqc *common.ReqContext, resSigList *vrsig.ResourceSignatureList) []*GeneralSignature {
+
+	// DELETE request is verified with a deletion approval instead of the signature for the resource
+	if reqc.IsDeleteRequest() {
+		if sig := self.GetDeleteApproval(ref, reqc, resSigList); sig != nil {
+			return []*GeneralSignature{sig}
+		}
+		return nil
+	}
+
+	sigs := []*GeneralSignature{}
 
 	//1. pick ResourceSignature from metadata.annotation if available
-	if sigAnnotations.Signature != "" {
-		found, yamlBytes := ishieldyaml.FindSingleYaml([]byte(sigAnnotations.Message), ref.ApiVersion, ref.Kind, ref.Name, ref.Namespace)
-		if found {
-			message := ishieldyaml.Base64decode(sigAnnotations.Message)
-			message = ishieldyaml.Decompress(message)
-			messageScope := sigAnnotations.MessageScope
-			mutableAttrs := sigAnnotations.MutableAttrs
-			matchRequired := true
-			scopedSignature := false
-			if message == "" && messageScope != "" {
-				message = GenerateMessageFromRawObj(reqc.RawObject, messageScope, mutableAttrs)
-				matchRequired = false  // skip matching because the message is generated from Requested Object
-				scopedSignature = true // enable checking if the signature is for patch
-			}
-			signature := ishieldyaml.Base64decode(sigAnnotations.Signature)
-			certificate := ishieldyaml.Base64decode(sigAnnotations.Certificate)
-			signType := SignedResourceTypeResource
-			if sigAnnotations.SignatureType == vrsig.SignatureTypeApplyingResource {
-				signType = SignedResourceTypeApplyingResource
-			} else if sigAnnotations.SignatureType == vrsig.SignatureTypePatch {
-				signType = SignedResourceTypePatch
-			}
-			return &GeneralSignature{
-				SignType: signType,
-				data:     map[string]string{"signature": signature, "message": message, "certificate": certificate, "yamlBytes": string(yamlBytes), "scope": messageScope},
-				option:   map[string]bool{"matchRequired": matchRequired, "scopedSignature": scopedSignature},
-			}
+	for _, sigAnnotations := range reqc.ClaimedMetadata.Annotations.MultiSignatureAnnotations() {
+		if sig := newSignatureFromAnnotation(ref, reqc, sigAnnotations); sig != nil {
+			sigs = append(sigs, sig)
 		}
 	}
+	if len(sigs) > 0 {
+		return sigs
+	}
 
 	//2. pick ResourceSignature from custom resource if available
 	if resSigList != nil && len(resSigList.Items) > 0 {
-		found, si, yamlBytes, resSigUID := resSigList.FindSignItem(ref.ApiVersion, ref.Kind, ref.Name, ref.Namespace)
-		if found {
-			signature := ishieldyaml.Base64decode(si.Signature)
-			certificate := ishieldyaml.Base64decode(si.Certificate)
-			message := ishieldyaml.Base64decode(si.Message)
-			message = ishieldyaml.Decompress(message)
-			mutableAttrs := si.MutableAttrs
-			matchRequired := true
-			scopedSignature := false
-			if si.Message == "" && si.MessageScope != "" {
-				message = GenerateMessageFromRawObj(reqc.RawObject, si.MessageScope, mutableAttrs)
-				matchRequired = false  // skip matching because the message is generated from Requested Object
-				scopedSignature = true // enable checking if the signature is for patch
-			}
-			signType := SignedResourceTypeResource
-			if si.Type == vrsig.SignatureTypeApplyingResource {
-				signType = SignedResourceTypeApplyingResource
-			} else if si.Type == vrsig.SignatureTypePatch {
-				signType = SignedResourceTypePatch
-			}
-			return &GeneralSignature{
-				SignType: signType,
-				data:     map[string]string{"signature": signature, "message": message, "certificate": certificate, "yamlBytes": string(yamlBytes), "scope": si.MessageScope, "resourceSignatureUID": resSigUID},
-				option:   map[string]bool{"matchRequired": matchRequired, "scopedSignature": scopedSignature},
-			}
+		for _, found := range resSigList.FindSignItems(ref.ApiVersion, ref.Kind, ref.Name, ref.Namespace) {
+			sigs = append(sigs, newSignatureFromSignItem(reqc, found))
 		}
 	}
+	if len(sigs) > 0 {
+		return sigs
+	}
 
 	//3. pick ResourceSignature from external store if available
+	for _, store := range self.stores {
+		signItems, err := store.GetSignItems(ref)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Error occured in getting signature from signature store `%s`; %s", store.Name(), err.Error()))
+			continue
+		}
+		storedList := &vrsig.ResourceSignatureList{Items: []*vrsig.ResourceSignature{{Spec: vrsig.ResourceSignatureSpec{Data: signItems}}}}
+		for _, found := range storedList.FindSignItems(ref.ApiVersion, ref.Kind, ref.Name, ref.Namespace) {
+			sig := newSignatureFromSignItem(reqc, found)
+			sig.data["signatureStore"] = store.Name()
+			sigs = append(sigs, sig)
+		}
+		if len(sigs) > 0 {
+			return sigs
+		}
+	}
 
 	//4. helm resource (release secret, helm cahrt resources)
 	if ok := self.plugins["helm"]; ok {
@@ -158,11 +190,11 @@ func (self *ConcreteSignatureEvaluator) GetResourceSignature(ref *common.Resourc
 				hrm := hrmSigs[1]
 				eCfg := true
 
-				return &GeneralSignature{
+				return []*GeneralSignature{{
 					SignType: SignedResourceTypeHelm,
 					data:     map[string]string{"releaseSecret": rls, "helmReleaseMetadata": hrm},
 					option:   map[string]bool{"emptyConfig": eCfg, "matchRequired": true},
-				}
+				}}
 			} else {
 				logger.Error(fmt.Sprintf("Error occured in getting signature from helm release metadata; %s", err.Error()))
 				return nil
@@ -176,6 +208,96 @@ func (self *ConcreteSignatureEvaluator) GetResourceSignature(ref *common.Resourc
 	// return nil
 }
 
+func newSignatureFromAnnotation(ref *common.ResourceRef, reqc *common.ReqContext, sigAnnotations *common.SignatureAnnotation) *GeneralSignature {
+	var found bool
+	var yamlBytes []byte
+	if sigAnnotations.Message == "" && sigAnnotations.MessageScope != "" {
+		// scoped signature is attached to the requested object itself, so there is no message to find the resource
+		found, yamlBytes = true, reqc.RawObject
+	} else {
+		found, yamlBytes = ishieldyaml.FindSingleYaml([]byte(sigAnnotations.Message), ref.ApiVersion, ref.Kind, ref.Name, ref.Namespace)
+	}
+	if !found {
+		return nil
+	}
+	message := ishieldyaml.Base64decode(sigAnnotations.Message)
+	message = ishieldyaml.Decompress(message)
+	messageScope := sigAnnotations.MessageScope
+	mutableAttrs := sigAnnotations.MutableAttrs
+	matchRequired := true
+	scopedSignature := false
+	if message == "" && messageScope != "" {
+		message = GenerateMessageFromRawObj(reqc.RawObject, messageScope, mutableAttrs)
+		matchRequired = false  // skip matching because the message is generated from Requested Object
+		scopedSignature = true // enable checking if the signature is for patch
+	}
+	signature := ishieldyaml.Base64decode(sigAnnotations.Signature)
+	certificate := ishieldyaml.Base64decode(sigAnnotations.Certificate)
+	bundle := ishieldyaml.Base64decode(sigAnnotations.Bundle)
+	validity := getSignedValidity(yamlBytes, sigAnnotations.Validity, scopedSignature, messageScope)
+	signType := SignedResourceTypeResource
+	if sigAnnotations.SignatureType == vrsig.SignatureTypeApplyingResource {
+		signType = SignedResourceTypeApplyingResource
+	} else if sigAnnotations.SignatureType == vrsig.SignatureTypePatch {
+		signType = SignedResourceTypePatch
+	}
+	return &GeneralSignature{
+		SignType: signType,
+		data:     map[string]string{"signature": signature, "message": message, "certificate": certificate, "bundle": bundle, "yamlBytes": string(yamlBytes), "scope": messageScope, "notBefore": validity.NotBefore, "notAfter": validity.NotAfter},
+		option:   map[string]bool{"matchRequired": matchRequired, "scopedSignature": scopedSignature},
+	}
+}
+
+func newSignatureFromSignItem(reqc *common.ReqContext, found vrsig.FoundSignItem) *GeneralSignature {
+	si := found.SignItem
+	signature := ishieldyaml.Base64decode(si.Signature)
+	certificate := ishieldyaml.Base64decode(si.Certificate)
+	bundle := ishieldyaml.Base64decode(si.Bundle)
+	message := ishieldyaml.Base64decode(si.Message)
+	message = ishieldyaml.Decompress(message)
+	mutableAttrs := si.MutableAttrs
+	matchRequired := true
+	scopedSignature := false
+	if si.Message == "" && si.MessageScope != "" {
+		message = GenerateMessageFromRawObj(reqc.RawObject, si.MessageScope, mutableAttrs)
+		matchRequired = false  // skip matching because the message is generated from Requested Object
+		scopedSignature = true // enable checking if the signature is for patch
+	}
+	validity := getSignedValidity(found.YamlBytes, common.GetSignatureValidity(reqc.RawObject), scopedSignature, si.MessageScope)
+	signType := SignedResourceTypeResource
+	if si.Type == vrsig.SignatureTypeApplyingResource {
+		signType = SignedResourceTypeApplyingResource
+	} else if si.Type == vrsig.SignatureTypePatch {
+		signType = SignedResourceTypePatch
+	}
+	return &GeneralSignature{
+		SignType: signType,
+		data:     map[string]string{"signature": signature, "message": message, "certificate": certificate, "bundle": bundle, "yamlBytes": string(found.YamlBytes), "scope": si.MessageScope, "resourceSignatureUID": found.ResourceSignatureUID, "notBefore": validity.NotBefore, "notAfter": validity.NotAfter},
+		option:   map[string]bool{"matchRequired": matchRequired, "scopedSignature": scopedSignature},
+	}
+}
+
+// getSignedValidity returns the validity of the signature. It is read from the signed message, and for scoped signature,
+// the validity annotations of the requested object are used only if they are included in the message scope.
+func getSignedValidity(yamlBytes []byte, reqValidity common.SignatureValidity, scopedSignature bool, messageScope string) common.SignatureValidity {
+	if !scopedSignature {
+		return common.GetSignatureValidity(yamlBytes)
+	}
+	validity := common.SignatureValidity{}
+	for _, key := range mapnode.SplitCommaSeparatedKeys(messageScope) {
+		if key == "metadata" || key == "metadata.annotations" {
+			return reqValidity
+		}
+		annotationKey := strings.Trim(strings.TrimPrefix(key, "metadata.annotations."), "\"")
+		if annotationKey == common.NotBeforeAnnotationKey {
+			validity.NotBefore = reqValidity.NotBefore
+		} else if annotationKey == common.NotAfterAnnotationKey {
+			validity.NotAfter = reqValidity.NotAfter
+		}
+	}
+	return validity
+}
+
 func (self *ConcreteSignatureEvaluator) Eval(reqc *common.ReqContext, resSigList *vrsig.ResourceSignatureList, signingProfile rspapi.ResourceSigningProfile) (*common.SignatureEvalResult, error) {
 
 	// eval sign policy
@@ -187,9 +309,9 @@ func (self *ConcreteSignatureEvaluator) Eval(reqc *common.ReqContext, resSigList
 		ref = kustPatterns[0].Override(ref)
 	}
 
-	// find signature
-	rsig := self.GetResourceSignature(ref, reqc, resSigList)
-	if rsig == nil {
+	// find signatures
+	rsigs := self.GetResourceSignatures(ref, reqc, resSigList)
+	if len(rsigs) == 0 {
 		return &common.SignatureEvalResult{
 			Allow:   false,
 			Checked: true,
@@ -198,44 +320,196 @@ func (self *ConcreteSignatureEvaluator) Eval(reqc *common.ReqContext, resSigList
 			},
 		}, nil
 	}
-	rsigUID := rsig.data["resourceSignatureUID"] // this will be empty string if annotation signature
 
-	candidatePubkeys := self.signerConfig.GetCandidatePubkeys(self.config.KeyPathList, reqc.Namespace)
+	candidatePubkeys := self.signerConfig.GetCandidatePubkeys(self.keys, reqc.Namespace)
+	keyLoadingError := checkKeyLoadingError(candidatePubkeys)
+
+	// verify all signatures. if none of them is verified, the result of the first one is returned.
+	verifiedSigners := []common.VerifiedSigner{}
+	var firstVerified *GeneralSignature
+	var firstFailure *common.SignatureEvalResult
+	for _, rsig := range rsigs {
+		verifiedSigner, failure := self.verifySignature(rsig, reqc, signingProfile, candidatePubkeys, keyLoadingError)
+		if failure != nil {
+			if firstFailure == nil {
+				firstFailure = failure
+			}
+			continue
+		}
+		if firstVerified == nil {
+			firstVerified = rsig
+		}
+		verifiedSigners = append(verifiedSigners, *verifiedSigner)
+	}
+	if len(verifiedSigners) == 0 {
+		return firstFailure, nil
+	}
+
+	rsigUID := firstVerified.data["resourceSignatureUID"] // this will be empty string if annotation signature
+	rsigSource := firstVerified.Source()
+
+	// signer
+	signer := verifiedSigners[0].Signer
+	signerNames := []string{}
+	signerNamesWithFingerprint := []string{}
+	for _, vs := range verifiedSigners {
+		signerNames = append(signerNames, vs.Signer.GetName())
+		signerNamesWithFingerprint = append(signerNamesWithFingerprint, vs.Signer.GetNameWithFingerprint())
+	}
+
+	// check signer config
+	signerMatched, matchedSignerConfig, matchedSignerNames := self.signerConfig.MatchMultiSigners(reqc.Namespace, verifiedSigners)
+	if signerMatched {
+		matchedSignerConfigStr := ""
+		if matchedSignerConfig != nil {
+			tmpMatchedConfig, _ := json.Marshal(matchedSignerConfig)
+			matchedSignerConfigStr = string(tmpMatchedConfig)
+		}
+		return &common.SignatureEvalResult{
+			Signer:               signer,
+			SignerName:           strings.Join(signerNames, ","),
+			Allow:                true,
+			Checked:              true,
+			MatchedSignerConfig:  matchedSignerConfigStr,
+			Error:                nil,
+			ResourceSignatureUID: rsigUID,
+			SignatureSource:      rsigSource,
+		}, nil
+	} else {
+		if tooOld := self.findTooOldSigners(reqc.Namespace, matchedSignerConfig, verifiedSigners, time.Now()); len(tooOld) > 0 {
+			reasonFail := fmt.Sprintf("%s; the signature by %s is older than maxSignatureAge (%s) of the signer config", common.ReasonCodeMap[common.REASON_EXPIRED_SIG].Message, strings.Join(tooOld, ","), matchedSignerConfig.MaxSignatureAge)
+			return &common.SignatureEvalResult{
+				Signer:     signer,
+				SignerName: strings.Join(signerNames, ","),
+				Allow:      false,
+				Checked:    true,
+				Error: &common.CheckError{
+					Reason: reasonFail,
+				},
+				ResourceSignatureUID: rsigUID,
+				SignatureSource:      rsigSource,
+			}, nil
+		}
+		reasonFail := common.ReasonCodeMap[common.REASON_NO_MATCH_SIGNER_CONFIG].Message
+		reasonFail = fmt.Sprintf("%s; This resource is signed by %s", reasonFail, strings.Join(signerNamesWithFingerprint, ", "))
+		if matchedSignerConfig != nil && len(matchedSignerNames) > 0 {
+			reasonFail = fmt.Sprintf("%s; only %s matched, but %d signers are required", reasonFail, strings.Join(matchedSignerNames, ","), matchedSignerConfig.NumOfRequiredSigners())
+			if len(matchedSignerConfig.RequiredSigners) > 0 {
+				reasonFail = fmt.Sprintf("%s including %s", reasonFail, strings.Join(matchedSignerConfig.RequiredSigners, ","))
+			}
+		}
+		return &common.SignatureEvalResult{
+			Signer:     signer,
+			SignerName: strings.Join(signerNames, ","),
+			Allow:      false,
+			Checked:    true,
+			Error: &common.CheckError{
+				Reason: reasonFail,
+			},
+			ResourceSignatureUID: rsigUID,
+			SignatureSource:      rsigSource,
+		}, nil
+	}
+}
+
+// findTooOldSigners returns the names of signers whose signatures are rejected by `maxSignatureAge` of the policy.
+// It returns nothing if the signers do not satisfy the signer config even if the signatures are new.
+func (self *ConcreteSignatureEvaluator) findTooOldSigners(namespace string, spc *common.SignerConfigCondition, signers []common.VerifiedSigner, now time.Time) []string {
+	if spc == nil || spc.MaxSignatureAge == "" {
+		return nil
+	}
+	tooOld := []string{}
+	renewed := []common.VerifiedSigner{}
+	for _, vs := range signers {
+		if spc.IsTooOld(vs, now) {
+			tooOld = append(tooOld, vs.Signer.GetName())
+			vs.SignedAt = now
+		}
+		renewed = append(renewed, vs)
+	}
+	if len(tooOld) == 0 {
+		return nil
+	}
+	if matched, _, _ := self.signerConfig.MatchMultiSigners(namespace, renewed); !matched {
+		return nil
+	}
+	return tooOld
+}
+
+// checkKeyLoadingError returns true if there are candidate keys but none of them can be loaded
+func checkKeyLoadingError(candidatePubkeys map[common.SignatureType][]common.VerificationKey) bool {
 	pgpPubkeys := candidatePubkeys[common.SignatureTypePGP]
 	x509Pubkeys := candidatePubkeys[common.SignatureTypeX509]
+	sigstorePubkeys := candidatePubkeys[common.SignatureTypeSigstore]
 
-	keyLoadingError := false
-	candidateKeyCount := len(pgpPubkeys) + len(x509Pubkeys)
-	if candidateKeyCount > 0 {
-		validKeyCount := 0
-		for _, keyPath := range pgpPubkeys {
-			if loaded, _ := pgp.LoadKeyRing(keyPath); len(loaded) > 0 {
-				validKeyCount += 1
-			}
+	candidateKeyCount := len(pgpPubkeys) + len(x509Pubkeys) + len(sigstorePubkeys)
+	if candidateKeyCount == 0 {
+		return false
+	}
+	validKeyCount := 0
+	for _, key := range pgpPubkeys {
+		if loaded, _ := pgp.ReadKeyRings(key.Files); len(loaded) > 0 {
+			validKeyCount += 1
 		}
+	}
 
-		for _, certDir := range x509Pubkeys {
-			if loaded, _ := x509.LoadCertDir(certDir); len(loaded) > 0 {
-				validKeyCount += 1
-			}
+	for _, key := range x509Pubkeys {
+		if loaded, _ := x509.ParseCertFiles(key.Files); len(loaded) > 0 {
+			validKeyCount += 1
 		}
-		if validKeyCount == 0 {
-			keyLoadingError = true
+	}
+
+	for _, key := range sigstorePubkeys {
+		if _, err := sigstore.NewKeyDir(key.Files); err == nil {
+			validKeyCount += 1
 		}
 	}
+	return validKeyCount == 0
+}
+
+// verifySignature verifies a single signature. It returns the verified signer, or the result for failure.
+func (self *ConcreteSignatureEvaluator) verifySignature(rsig *GeneralSignature, reqc *common.ReqContext, signingProfile rspapi.ResourceSigningProfile, candidatePubkeys map[common.SignatureType][]common.VerificationKey, keyLoadingError bool) (*common.VerifiedSigner, *common.SignatureEvalResult) {
+	rsigUID := rsig.data["resourceSignatureUID"] // this will be empty string if annotation signature
+	rsigSource := rsig.Source()
+
+	if rsig.SignType == SignedResourceTypeDelete {
+		if expiryErr := checkDeleteApprovalExpiry(rsig, time.Now()); expiryErr != "" {
+			reasonFail := fmt.Sprintf("%s; %s", common.ReasonCodeMap[common.REASON_INVALID_SIG].Message, expiryErr)
+			return nil, &common.SignatureEvalResult{
+				Allow:   false,
+				Checked: true,
+				Error: &common.CheckError{
+					Reason: reasonFail,
+				},
+				ResourceSignatureUID: rsigUID,
+				SignatureSource:      rsigSource,
+			}
+		}
+	}
+
+	// revoked signature is rejected before verification
+	if revoked, reason := self.revocations.IsSignatureRevoked([]byte(rsig.data["signature"]), rsigUID); revoked {
+		return nil, newRevokedSignatureResult(reason, nil, rsigUID, rsigSource)
+	}
+
+	pgpPubkeys := candidatePubkeys[common.SignatureTypePGP]
+	x509Pubkeys := candidatePubkeys[common.SignatureTypeX509]
+	sigstorePubkeys := candidatePubkeys[common.SignatureTypeSigstore]
 
 	// create verifier
 	dryRunNamespace := ""
 	if reqc.ResourceScope == string(common.ScopeNamespaced) {
 		dryRunNamespace = self.config.Namespace
 	}
-	verifier := NewVerifier(rsig.SignType, dryRunNamespace, pgpPubkeys, x509Pubkeys, self.config.KeyPathList)
+	verifier := NewVerifier(rsig.SignType, dryRunNamespace, self.dryRunDisabled, pgpPubkeys, x509Pubkeys, sigstorePubkeys, self.keys)
 
 	// verify signature
-	sigVerifyResult, verifiedKeyPathList, err := verifier.Verify(rsig, reqc, signingProfile)
+	verifyStart := time.Now()
+	sigVerifyResult, verifiedKeyConfigs, err := verifier.Verify(rsig, reqc, signingProfile)
+	metrics.ObserveSignatureVerification(string(rsig.SignType), err == nil && sigVerifyResult != nil && sigVerifyResult.Error == nil, verifyStart)
 	if err != nil {
 		reasonFail := fmt.Sprintf("Error during signature verification; %s; %s", sigVerifyResult.Error.Reason, err.Error())
-		return &common.SignatureEvalResult{
+		return nil, &common.SignatureEvalResult{
 			Allow:   false,
 			Checked: true,
 			Error: &common.CheckError{
@@ -243,72 +517,86 @@ func (self *ConcreteSignatureEvaluator) Eval(reqc *common.ReqContext, resSigList
 				Reason: reasonFail,
 			},
 			ResourceSignatureUID: rsigUID,
-		}, nil
+			SignatureSource:      rsigSource,
+		}
 	}
 
 	if keyLoadingError {
 		reasonFail := common.ReasonCodeMap[common.REASON_NO_VALID_KEYRING].Message
-		return &common.SignatureEvalResult{
+		return nil, &common.SignatureEvalResult{
 			Allow:   false,
 			Checked: true,
 			Error: &common.CheckError{
 				Reason: reasonFail,
 			},
 			ResourceSignatureUID: rsigUID,
-		}, nil
+			SignatureSource:      rsigSource,
+		}
 	}
 
 	if sigVerifyResult == nil || sigVerifyResult.Signer == nil {
 		reasonFail := common.ReasonCodeMap[common.REASON_INVALID_SIG].Message
+		if sigVerifyResult != nil && sigVerifyResult.InvalidCert {
+			reasonFail = common.ReasonCodeMap[common.REASON_INVALID_CERT].Message
+		}
+		diff := ""
 		if sigVerifyResult != nil && sigVerifyResult.Error != nil {
 			reasonFail = fmt.Sprintf("%s; %s", reasonFail, sigVerifyResult.Error.Reason)
+			diff = sigVerifyResult.Diff
 		}
-		return &common.SignatureEvalResult{
+		return nil, &common.SignatureEvalResult{
 			Allow:   false,
 			Checked: true,
 			Error: &common.CheckError{
 				Reason: reasonFail,
 			},
 			ResourceSignatureUID: rsigUID,
-		}, nil
+			SignatureSource:      rsigSource,
+			Diff:                 diff,
+			DryRunSkipped:        sigVerifyResult != nil && sigVerifyResult.DryRunSkipped,
+		}
 	}
 
-	// signer
-	signer := sigVerifyResult.Signer
+	// signer is known only after the signature is verified
+	if revoked, reason := self.revocations.IsSignerRevoked(sigVerifyResult.Signer); revoked {
+		return nil, newRevokedSignatureResult(reason, sigVerifyResult.Signer, rsigUID, rsigSource)
+	}
 
-	// check signer config
-	signerMatched, matchedSignerConfig := self.signerConfig.Match(reqc.Namespace, signer, verifiedKeyPathList)
-	if signerMatched {
-		matchedSignerConfigStr := ""
-		if matchedSignerConfig != nil {
-			tmpMatchedConfig, _ := json.Marshal(matchedSignerConfig)
-			matchedSignerConfigStr = string(tmpMatchedConfig)
-		}
-		return &common.SignatureEvalResult{
-			Signer:               signer,
-			SignerName:           signer.GetName(),
-			Allow:                true,
-			Checked:              true,
-			MatchedSignerConfig:  matchedSignerConfigStr,
-			Error:                nil,
-			ResourceSignatureUID: rsigUID,
-		}, nil
-	} else {
-		reasonFail := common.ReasonCodeMap[common.REASON_NO_MATCH_SIGNER_CONFIG].Message
-		if signer != nil {
-			reasonFail = fmt.Sprintf("%s; This resource is signed by %s", reasonFail, signer.GetNameWithFingerprint())
-		}
-		return &common.SignatureEvalResult{
-			Signer:     signer,
-			SignerName: signer.GetName(),
+	// the validity is checked after the signature is verified, because it is a part of the signed message
+	validity := common.SignatureValidity{NotBefore: rsig.data["notBefore"], NotAfter: rsig.data["notAfter"]}
+	if err := validity.Check(time.Now()); err != nil {
+		reasonFail := fmt.Sprintf("%s; %s", common.ReasonCodeMap[common.REASON_EXPIRED_SIG].Message, err.Error())
+		return nil, &common.SignatureEvalResult{
+			Signer:     sigVerifyResult.Signer,
+			SignerName: sigVerifyResult.Signer.GetName(),
 			Allow:      false,
 			Checked:    true,
 			Error: &common.CheckError{
 				Reason: reasonFail,
 			},
 			ResourceSignatureUID: rsigUID,
-		}, nil
+			SignatureSource:      rsigSource,
+		}
+	}
+
+	return &common.VerifiedSigner{Signer: sigVerifyResult.Signer, VerifiedKeyConfigs: verifiedKeyConfigs, SignedAt: validity.SignedAt()}, nil
+}
+
+func newRevokedSignatureResult(reason string, signer *common.SignerInfo, rsigUID, rsigSource string) *common.SignatureEvalResult {
+	result := &common.SignatureEvalResult{
+		Allow:   false,
+		Checked: true,
+		Error: &common.CheckError{
+			Reason: fmt.Sprintf("%s; %s", common.ReasonCodeMap[common.REASON_REVOKED_SIG].Message, reason),
+		},
+		ResourceSignatureUID: rsigUID,
+		SignatureSource:      rsigSource,
+	}
+	if signer != nil {
+		result.Signer = signer
+		result.SignerName = signer.GetName()
 	}
+	return result
 }
 
 func findAttrsPattern(reqc *common.ReqContext, attrs []*common.AttrsPattern) []string {
@@ -29,6 +29,7 @@ import (
 	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
 	mapnode "github.com/IBM/integrity-enforcer/shield/pkg/util/mapnode"
 	pgp "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/pgp"
+	sigstore "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/sigstore"
 	x509 "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
 )
 
@@ -49,17 +50,20 @@ type VerifierInterface interface {
 ***********************************************/
 
 type ResourceVerifier struct {
-	PGPKeyPathList        []string
-	X509KeyPathList       []string
-	AllMountedKeyPathList []string
-	dryRunNamespace       string // namespace for dryrun; should be empty for cluster scope request
+	PGPKeys         []common.VerificationKey
+	X509Keys        []common.VerificationKey
+	SigstoreKeys    []common.VerificationKey
+	AllKeys         []common.VerificationKey
+	dryRunNamespace string // namespace for dryrun; should be empty for cluster scope request
+	dryRunDisabled  bool   // true if dryrun is not available, e.g. verification without cluster
+	dryRunSkipped   bool
 }
 
-func NewVerifier(signType SignedResourceType, dryRunNamespace string, pgpKeyPathList, x509KeyPathList, allKeyPathList []string) VerifierInterface {
-	if signType == SignedResourceTypeResource || signType == SignedResourceTypeApplyingResource || signType == SignedResourceTypePatch {
-		return &ResourceVerifier{dryRunNamespace: dryRunNamespace, PGPKeyPathList: pgpKeyPathList, X509KeyPathList: x509KeyPathList, AllMountedKeyPathList: allKeyPathList}
+func NewVerifier(signType SignedResourceType, dryRunNamespace string, dryRunDisabled bool, pgpKeys, x509Keys, sigstoreKeys, allKeys []common.VerificationKey) VerifierInterface {
+	if signType == SignedResourceTypeResource || signType == SignedResourceTypeApplyingResource || signType == SignedResourceTypePatch || signType == SignedResourceTypeDelete {
+		return &ResourceVerifier{dryRunNamespace: dryRunNamespace, dryRunDisabled: dryRunDisabled, PGPKeys: pgpKeys, X509Keys: x509Keys, SigstoreKeys: sigstoreKeys, AllKeys: allKeys}
 	} else if signType == SignedResourceTypeHelm {
-		return &HelmVerifier{Namespace: dryRunNamespace, KeyPathList: pgpKeyPathList}
+		return &HelmVerifier{Namespace: dryRunNamespace, Keys: pgpKeys}
 	}
 	return nil
 }
@@ -71,19 +75,14 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 
 	excludeDiffValue := reqc.ExcludeDiffValue()
 
-	kustomizeList := signingProfile.Kustomize(reqc.Map())
+	reqFields := reqc.Map()
+	kustomizeList := signingProfile.Kustomize(reqFields)
 	allowDiffPatterns := makeAllowDiffPatterns(reqc, kustomizeList)
 
-	protectAttrsList := signingProfile.ProtectAttrs(reqc.Map())
-	ignoreAttrsList := signingProfile.IgnoreAttrs(reqc.Map())
+	protectAttrsList := signingProfile.ProtectAttrs(reqFields, reqc.ConditionVars())
+	ignoreAttrsList := signingProfile.IgnoreAttrs(reqFields, reqc.ConditionVars())
 
-	resSigUID := sig.data["resourceSignatureUID"]
-	sigFrom := ""
-	if resSigUID == "" {
-		sigFrom = "annotation"
-	} else {
-		sigFrom = "ResourceSignature"
-	}
+	sigFrom := sig.Source()
 
 	if sig.option["matchRequired"] {
 		message, _ := sig.data["message"]
@@ -101,7 +100,9 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 					Reason: msg,
 					Error:  nil,
 				},
-				Signer: nil,
+				Signer:        nil,
+				Diff:          diffStr,
+				DryRunSkipped: self.dryRunSkipped,
 			}, []string{}, nil
 		}
 	}
@@ -143,11 +144,14 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 	message := sig.data["message"]
 	signature := sig.data["signature"]
 	certificateStr, certFound := sig.data["certificate"]
-
-	verifiedKeyPathList := []string{}
-	if len(self.PGPKeyPathList) > 0 {
-		for _, keyPath := range self.PGPKeyPathList {
-			ok, reasonFail, signer, fingerprint, err := pgp.VerifySignature(keyPath, message, signature)
+	bundleStr := sig.data["bundle"]
+
+	verifiedKeyConfigs := []string{}
+	var certErr *common.CheckError
+	if len(self.PGPKeys) > 0 {
+		for _, key := range self.PGPKeys {
+			keyRing, _ := pgp.ReadKeyRings(key.Files)
+			ok, reasonFail, signer, fingerprint, err := pgp.VerifySignatureWithKeyRing(keyRing, message, signature)
 			if err != nil {
 				vcerr = &common.CheckError{
 					Msg:    fmt.Sprintf("Error occured while verifying signature in %s", sigFrom),
@@ -163,7 +167,7 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 					Comment:     signer.Comment,
 					Fingerprint: fingerprint,
 				}
-				verifiedKeyPathList = append(verifiedKeyPathList, keyPath)
+				verifiedKeyConfigs = append(verifiedKeyConfigs, key.KeyConfig)
 			} else {
 				vcerr = &common.CheckError{
 					Msg:    fmt.Sprintf("Failed to verify signature in %s", sigFrom),
@@ -173,10 +177,54 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 			}
 		}
 	}
-	if len(self.X509KeyPathList) > 0 && certFound {
-		for _, caCertPath := range self.X509KeyPathList {
+	if len(self.SigstoreKeys) > 0 && certFound && bundleStr != "" {
+		// a malformed key in a labeled Secret or ConfigMap must not block the verification with the other keys,
+		// so errors are reported only when no key verifies the signature.
+		var sigstoreErr error
+		var sigstoreErrResult *common.CheckError
+		for _, key := range self.SigstoreKeys {
+			keyDir, err := sigstore.NewKeyDir(key.Files)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("Failed to load sigstore keys in %s; %s", key.Source, err.Error()))
+				sigstoreErr = err
+				sigstoreErrResult = &common.CheckError{
+					Msg:    fmt.Sprintf("Error occured while verifying keyless signature in %s", sigFrom),
+					Reason: fmt.Sprintf("failed to load sigstore keys: %s", err.Error()),
+					Error:  err,
+				}
+				continue
+			}
+			sigOk, reasonFail, cert, err := sigstore.VerifySignatureWithKeys([]byte(message), []byte(signature), []byte(certificateStr), []byte(bundleStr), keyDir)
+			if err != nil {
+				sigstoreErr = err
+				sigstoreErrResult = &common.CheckError{
+					Msg:    fmt.Sprintf("Error occured while verifying keyless signature in %s", sigFrom),
+					Reason: reasonFail,
+					Error:  err,
+				}
+				continue
+			} else if sigOk {
+				vcerr = nil
+				vsinfo = x509.NewSignerInfoFromCert(cert)
+				vsinfo.Identity, vsinfo.Issuer = sigstore.GetIdentity(cert)
+				verifiedKeyConfigs = append(verifiedKeyConfigs, key.KeyConfig)
+			} else if vsinfo == nil {
+				vcerr = &common.CheckError{
+					Msg:    fmt.Sprintf("Failed to verify keyless signature in %s", sigFrom),
+					Reason: reasonFail,
+					Error:  nil,
+				}
+			}
+		}
+		if vsinfo == nil && sigstoreErr != nil {
+			return &SigVerifyResult{Error: sigstoreErrResult, Signer: nil}, []string{}, sigstoreErr
+		}
+	}
+	// keyless signature is verified only with sigstore keys
+	if len(self.X509Keys) > 0 && certFound && bundleStr == "" {
+		for _, key := range self.X509Keys {
 			certificate := []byte(certificateStr)
-			certOk, reasonFail, err := x509.VerifyCertificate(certificate, caCertPath)
+			certOk, reasonFail, err := x509.VerifyCertificateWithKeyFiles(certificate, key.Files)
 			if err != nil {
 				vcerr = &common.CheckError{
 					Msg:    fmt.Sprintf("Error occured while verifying certificate in %s", sigFrom),
@@ -190,6 +238,9 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 					Reason: reasonFail,
 					Error:  nil,
 				}
+				if strings.HasPrefix(reasonFail, x509.ReasonCertificateRevoked) || strings.HasPrefix(reasonFail, x509.ReasonCertificateExpired) || strings.HasPrefix(reasonFail, x509.ReasonCRLExpired) {
+					certErr = vcerr
+				}
 				vsinfo = nil
 			} else {
 				cert, err := x509.ParseCertificate(certificate)
@@ -213,7 +264,7 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 				} else if sigOk {
 					vcerr = nil
 					vsinfo = x509.NewSignerInfoFromCert(cert)
-					verifiedKeyPathList = append(verifiedKeyPathList, caCertPath)
+					verifiedKeyConfigs = append(verifiedKeyConfigs, key.KeyConfig)
 				} else {
 					vcerr = &common.CheckError{
 						Msg:    fmt.Sprintf("Failed to verify signature in %s", sigFrom),
@@ -228,9 +279,10 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 
 	// additional pgp verification trial only for detail error message
 	if vsinfo == nil {
-		for _, keyPath := range self.AllMountedKeyPathList {
-			if strings.Contains(keyPath, "/pgp/") {
-				if ok2, _, signer2, fingerprint2, _ := pgp.VerifySignature(keyPath, message, signature); ok2 && signer2 != nil {
+		for _, key := range self.AllKeys {
+			if key.Type == common.SignatureTypePGP {
+				keyRing, _ := pgp.ReadKeyRings(key.Files)
+				if ok2, _, signer2, fingerprint2, _ := pgp.VerifySignatureWithKeyRing(keyRing, message, signature); ok2 && signer2 != nil {
 					signerAlt := &common.SignerInfo{
 						Email:       signer2.Email,
 						Name:        signer2.Name,
@@ -244,11 +296,19 @@ func (self *ResourceVerifier) Verify(sig *GeneralSignature, reqc *common.ReqCont
 		}
 	}
 
+	// revoked or expired certificate is reported rather than failures with the other CA certs
+	invalidCert := false
+	if vsinfo == nil && certErr != nil {
+		vcerr = certErr
+		invalidCert = true
+	}
+
 	svresult := &SigVerifyResult{
-		Error:  vcerr,
-		Signer: vsinfo,
+		Error:       vcerr,
+		Signer:      vsinfo,
+		InvalidCert: invalidCert,
 	}
-	return svresult, verifiedKeyPathList, retErr
+	return svresult, verifiedKeyConfigs, retErr
 }
 
 func (self *ResourceVerifier) MatchMessage(message, reqObj []byte, protectAttrs, ignoreAttrs []*common.AttrsPattern, allowDiffPatterns []*mapnode.DiffPattern, resScope, resKind string, signType SignedResourceType, excludeDiffValue bool) (bool, string) {
@@ -288,6 +348,12 @@ func (self *ResourceVerifier) MatchMessage(message, reqObj []byte, protectAttrs,
 		return matched, diffStr
 	}
 
+	if !matched && self.dryRunDisabled && (signType == SignedResourceTypeResource || signType == SignedResourceTypeApplyingResource || signType == SignedResourceTypePatch) {
+		logger.Debug("DryRunCreate() is skipped because it is disabled")
+		self.dryRunSkipped = true
+		return matched, diffStr
+	}
+
 	if !matched && signType == SignedResourceTypeResource {
 
 		nsMaskedOrgBytes := orgNode.Mask([]string{"metadata.namespace"}).ToYaml()
@@ -487,6 +553,11 @@ func makeAllowDiffPatterns(reqc *common.ReqContext, kustomizeList []*common.Kust
 type SigVerifyResult struct {
 	Error  *common.CheckError
 	Signer *common.SignerInfo
+	Diff   string
+	// true if the message did not match with the request without dryrun, and dryrun was skipped
+	DryRunSkipped bool
+	// true if the signer certificate is revoked or expired
+	InvalidCert bool
 }
 
 /**********************************************
@@ -496,8 +567,8 @@ type SigVerifyResult struct {
 ***********************************************/
 
 type HelmVerifier struct {
-	Namespace   string
-	KeyPathList []string
+	Namespace string
+	Keys      []common.VerificationKey
 }
 
 func (self *HelmVerifier) Verify(sig *GeneralSignature, reqc *common.ReqContext, signingProfile rspapi.ResourceSigningProfile) (*SigVerifyResult, []string, error) {
@@ -539,7 +610,13 @@ func (self *HelmVerifier) Verify(sig *GeneralSignature, reqc *common.ReqContext,
 
 		helmChart := hrmObj.Spec.Chart
 		helmProv := hrmObj.Spec.Prov
-		ok, signer, reasonFail, err := helm.VerifyChartAndProv(helmChart, helmProv, self.KeyPathList)
+		keyRings := [][]byte{}
+		for _, key := range self.Keys {
+			for _, name := range key.FileNames() {
+				keyRings = append(keyRings, key.Files[name])
+			}
+		}
+		ok, signer, reasonFail, err := helm.VerifyChartAndProvWithKeyRings(helmChart, helmProv, keyRings)
 		if err != nil {
 			vcerr = &common.CheckError{
 				Msg:    "Error occured in helm chart verification",
@@ -586,6 +663,15 @@ var CommonMessageMask = []string{
 	fmt.Sprintf("metadata.annotations.\"%s\"", common.SignatureTypeAnnotationKey),
 	fmt.Sprintf("metadata.annotations.\"%s\"", common.MessageScopeAnnotationKey),
 	fmt.Sprintf("metadata.annotations.\"%s\"", common.MutableAttrsAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s\"", common.BundleAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s\"", common.NotBeforeAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s\"", common.NotAfterAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s.*\"", common.SignatureAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s.*\"", common.CertificateAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s.*\"", common.BundleAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s\"", common.DeleteSignatureAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s\"", common.DeleteMessageAnnotationKey),
+	fmt.Sprintf("metadata.annotations.\"%s\"", common.DeleteCertificateAnnotationKey),
 	"metadata.annotations.namespace",
 	"metadata.annotations.kubectl.\"kubernetes.io/last-applied-configuration\"",
 	"metadata.managedFields",
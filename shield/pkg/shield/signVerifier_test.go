@@ -0,0 +1,134 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+	"github.com/IBM/integrity-enforcer/shield/pkg/util/sign/sigstore"
+	x509util "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
+)
+
+// newTestKeylessSignature creates a keyless signature with a Fulcio-like root and a Rekor-like key,
+// and returns the signature data and the key files to verify it.
+func newTestKeylessSignature(t *testing.T, message []byte) (map[string]string, map[string][]byte) {
+	rootCertPem, rootPrvKeyPem, _, err := x509util.CreateCertificateWithAlgorithm("fulcio-root", x509util.KeyAlgorithmECDSAP256, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, _ := x509util.ParseCertificate(rootCertPem)
+	rootPrvKey, _ := x509util.ParsePrivateKey(rootPrvKeyPem)
+	signerKey, signerPubKey, err := x509util.GenerateKeyPairWithAlgorithm(x509util.KeyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerKeyPem, _ := x509util.MarshalPrivateKey(signerKey)
+	identity, _ := url.Parse("https://github.com/example/repo/.github/workflows/release.yaml@refs/heads/main")
+	issuer, _ := asn1.Marshal("https://token.actions.githubusercontent.com")
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		NotBefore:       now.Add(-1 * time.Hour),
+		NotAfter:        now.Add(-50 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{identity},
+		ExtraExtensions: []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}, Value: issuer}},
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, tmpl, rootCert, signerPubKey, rootPrvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPem := x509util.PEMEncode(certBytes, x509util.PEMTypeCertificate)
+	signature, err := x509util.GenerateSignature(message, signerKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rekorKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rekorPubKeyBytes, _ := x509.MarshalPKIXPublicKey(rekorKey.Public())
+	msgHash := sha256.Sum256(message)
+	body := fmt.Sprintf(`{"apiVersion":"0.0.1","kind":"hashedrekord","spec":{"data":{"hash":{"algorithm":"sha256","value":"%s"}},"signature":{"content":"%s","publicKey":{"content":"%s"}}}}`,
+		hex.EncodeToString(msgHash[:]), base64.StdEncoding.EncodeToString(signature), base64.StdEncoding.EncodeToString(certPem))
+	payload := sigstore.BundlePayload{Body: base64.StdEncoding.EncodeToString([]byte(body)), IntegratedTime: now.Add(-55 * time.Minute).Unix(), LogIndex: 1, LogID: "test"}
+	// the payload is signed in the canonical form (sorted keys)
+	canonicalPayload := &bytes.Buffer{}
+	enc := json.NewEncoder(canonicalPayload)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(map[string]interface{}{"body": payload.Body, "integratedTime": payload.IntegratedTime, "logIndex": payload.LogIndex, "logID": payload.LogID})
+	digest := sha256.Sum256(bytes.TrimRight(canonicalPayload.Bytes(), "\n"))
+	set, err := rekorKey.Sign(rand.Reader, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundleBytes, _ := json.Marshal(&sigstore.Bundle{SignedEntryTimestamp: set, Payload: payload})
+
+	data := map[string]string{
+		"message":     string(message),
+		"signature":   string(signature),
+		"certificate": string(certPem),
+		"bundle":      string(bundleBytes),
+	}
+	files := map[string][]byte{
+		"fulcio-root.pem":               rootCertPem,
+		sigstore.RekorPublicKeyFilename: x509util.PEMEncode(rekorPubKeyBytes, x509util.PEMTypePublicKey),
+	}
+	return data, files
+}
+
+func TestVerifyWithMalformedSigstoreKey(t *testing.T) {
+	data, files := newTestKeylessSignature(t, []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-cm\n"))
+	badKey := common.VerificationKey{KeyConfig: "bad-keyconfig", Type: common.SignatureTypeSigstore, Source: "configmap/bad", Files: map[string][]byte{"fulcio-root.pem": []byte("not a certificate")}}
+	goodKey := common.VerificationKey{KeyConfig: "sigstore-keyconfig", Type: common.SignatureTypeSigstore, Source: "secret/good", Files: files}
+	sig := &GeneralSignature{SignType: SignedResourceTypeResource, data: data, option: map[string]bool{}}
+	reqc := &common.ReqContext{Kind: "ConfigMap", Namespace: "secure-ns", Name: "test-cm"}
+
+	keys := []common.VerificationKey{badKey, goodKey}
+	verifier := &ResourceVerifier{SigstoreKeys: keys, AllKeys: keys}
+	result, verifiedKeyConfigs, err := verifier.Verify(sig, reqc, rspapi.ResourceSigningProfile{})
+	if err != nil || result.Signer == nil || len(verifiedKeyConfigs) != 1 || verifiedKeyConfigs[0] != goodKey.KeyConfig {
+		t.Errorf("Verify() Failed\nexpected: verified with %s\nactual: %v, %v, %v", goodKey.KeyConfig, result.Error, verifiedKeyConfigs, err)
+		return
+	}
+	if result.Signer.Identity == "" {
+		t.Errorf("Verify() Failed\nexpected: signer with OIDC identity\nactual: %v", result.Signer)
+	}
+
+	// the error is reported if no key verifies the signature
+	verifier = &ResourceVerifier{SigstoreKeys: []common.VerificationKey{badKey}, AllKeys: []common.VerificationKey{badKey}}
+	result, _, err = verifier.Verify(sig, reqc, rspapi.ResourceSigningProfile{})
+	if err == nil || result.Signer != nil {
+		t.Errorf("Verify() Failed\nexpected: error\nactual: %v, %v", result.Signer, err)
+	}
+}
@@ -17,6 +17,7 @@
 package shield
 
 import (
+	"fmt"
 	"strings"
 
 	rspapi "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
@@ -44,6 +45,9 @@ func (self *ConcreteMutationChecker) Eval(reqc *common.ReqContext, signingProfil
 
 	mask := []string{
 		common.ResourceIntegrityLabelKey,
+		fmt.Sprintf("metadata.annotations.\"%s\"", common.DeleteSignatureAnnotationKey),
+		fmt.Sprintf("metadata.annotations.\"%s\"", common.DeleteMessageAnnotationKey),
+		fmt.Sprintf("metadata.annotations.\"%s\"", common.DeleteCertificateAnnotationKey),
 		"metadata.annotations.namespace",
 		"metadata.annotations.kubectl.\"kubernetes.io/last-applied-configuration\"",
 		"metadata.annotations.deprecated.daemonset.template.generation",
@@ -105,7 +109,7 @@ func (self *ConcreteMutationChecker) Eval(reqc *common.ReqContext, signingProfil
 	reqFields := reqc.Map()
 	excludeDiffValue := reqc.ExcludeDiffValue()
 
-	ignoreAttrsList := signingProfile.IgnoreAttrs(reqFields)
+	ignoreAttrsList := signingProfile.IgnoreAttrs(reqFields, reqc.ConditionVars())
 
 	if mr, err := GetMAResult(ma4kInput, ignoreAttrsList, excludeDiffValue); err != nil {
 		maResult.Error = &common.CheckError{
@@ -206,7 +210,7 @@ func GetMAResult(ma4kInput *Ma4kInput, rules []*common.AttrsPattern, excludeDiff
 	// allWhitelist.Rule = policy
 
 	allMaskKeys := generateMaskKeys(rules,
-		namespace, name, kind, username, userGroups)
+		namespace, name, kind, username, userGroups, ma4kInput.Before, ma4kInput.After)
 
 	// diff
 	dr := oldObject.Diff(newObject)
@@ -246,19 +250,40 @@ func GetMAResult(ma4kInput *Ma4kInput, rules []*common.AttrsPattern, excludeDiff
 	return mr, nil
 }
 
-func generateMaskKeys(rules []*common.AttrsPattern, namespace, name, kind, username string, usergroups []string) []string {
+func generateMaskKeys(rules []*common.AttrsPattern, namespace, name, kind, username string, usergroups []string, oldObj, newObj map[string]interface{}) []string {
 	reqFields := map[string]string{}
 	reqFields["Namespace"] = namespace
 	reqFields["Name"] = name
 	reqFields["Kind"] = kind
 	reqFields["UserName"] = username
 	reqFields["UserGroups"] = strings.Join(usergroups, ",")
+	reqFields["Operation"] = "UPDATE"
+	common.SetMetadataFields(reqFields, getObjectMetadata(newObj), getObjectMetadata(oldObj))
 
 	maskKey := []string{}
 	for _, rule := range rules {
-		if rule.MatchWith(reqFields) {
+		if rule.MatchExistingWith(reqFields) {
 			maskKey = append(maskKey, rule.Attrs...)
 		}
 	}
 	return maskKey
 }
+
+func getObjectMetadata(obj map[string]interface{}) *common.ObjectMetadata {
+	getValues := func(key string) map[string]string {
+		values := map[string]string{}
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		if m, ok := metadata[key].(map[string]interface{}); ok {
+			for k, v := range m {
+				if vStr, ok := v.(string); ok {
+					values[k] = vStr
+				}
+			}
+		}
+		return values
+	}
+	return &common.ObjectMetadata{
+		Labels:      common.NewResourceLabel(getValues("labels")),
+		Annotations: common.NewResourceAnnotation(getValues("annotations")),
+	}
+}
@@ -18,20 +18,24 @@ package shield
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	hrm "github.com/IBM/integrity-enforcer/shield/pkg/apis/helmreleasemetadata/v1alpha1"
 	rsig "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesignature/v1alpha1"
 	rsp "github.com/IBM/integrity-enforcer/shield/pkg/apis/resourcesigningprofile/v1alpha1"
 	sconf "github.com/IBM/integrity-enforcer/shield/pkg/apis/shieldconfig/v1alpha1"
+	sigrevoke "github.com/IBM/integrity-enforcer/shield/pkg/apis/signaturerevocation/v1alpha1"
 	sigconf "github.com/IBM/integrity-enforcer/shield/pkg/apis/signerconfig/v1alpha1"
 	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
 )
 
-func ValidateResource(reqc *common.ReqContext, shieldNamespace string) (bool, string) {
+func ValidateResource(reqc *common.ReqContext, shieldNamespace string, keys []common.VerificationKey) (bool, string) {
 	if reqc.IsDeleteRequest() {
 		return true, ""
 	}
@@ -55,7 +59,13 @@ func ValidateResource(reqc *common.ReqContext, shieldNamespace string) (bool, st
 		}
 		return ok, ""
 	} else if reqc.Kind == common.SignerConfigCustomResourceKind {
-		ok, err := ValidateSignerConfig(reqc)
+		ok, err := ValidateSignerConfig(reqc, keys)
+		if err != nil {
+			return false, fmt.Sprintf("Format validation failed; %s", err.Error())
+		}
+		return ok, ""
+	} else if reqc.Kind == common.SignatureRevocationCustomResourceKind {
+		ok, err := ValidateSignatureRevocation(reqc)
 		if err != nil {
 			return false, fmt.Sprintf("Format validation failed; %s", err.Error())
 		}
@@ -81,9 +91,56 @@ func ValidateResourceSigningProfile(reqc *common.ReqContext, shieldNamespace str
 	if reqc.Namespace != shieldNamespace && data.Spec.TargetNamespaceSelector != nil {
 		return false, fmt.Errorf("%s.Spec.TargetNamespaceSelector is allowed only for %s in %s.", common.ProfileCustomResourceKind, common.ProfileCustomResourceKind, shieldNamespace)
 	}
+	switch data.Spec.Mode {
+	case common.UnknownMode, common.EnforceMode, common.DetectMode, common.WarnMode:
+	default:
+		return false, fmt.Errorf("%s.Spec.Mode must be one of \"%s\", \"%s\" or \"%s\", but got \"%s\".", common.ProfileCustomResourceKind, common.EnforceMode, common.DetectMode, common.WarnMode, data.Spec.Mode)
+	}
+	if err := validateProfileRules(data.Spec); err != nil {
+		return false, fmt.Errorf("%s has an invalid rule; %s", common.ProfileCustomResourceKind, err.Error())
+	}
 	return true, nil
 }
 
+func validateProfileRules(spec rsp.ResourceSigningProfileSpec) error {
+	for _, rules := range []struct {
+		field string
+		items []*common.Rule
+	}{{"protectRules", spec.ProtectRules}, {"ignoreRules", spec.IgnoreRules}, {"forceCheckRules", spec.ForceCheckRules}} {
+		for i, rule := range rules.items {
+			if err := rule.Validate(); err != nil {
+				return fmt.Errorf("spec.%s[%s].%s", rules.field, strconv.Itoa(i), err.Error())
+			}
+		}
+	}
+	for _, attrs := range []struct {
+		field string
+		items []*common.AttrsPattern
+	}{{"protectAttrs", spec.ProtectAttrs}, {"unprotectAttrs", spec.UnprotectAttrs}, {"ignoreAttrs", spec.IgnoreAttrs}} {
+		for i, attr := range attrs.items {
+			if err := attr.Validate(); err != nil {
+				return fmt.Errorf("spec.%s[%s].%s", attrs.field, strconv.Itoa(i), err.Error())
+			}
+		}
+	}
+	for i, kustPattern := range spec.KustomizePatterns {
+		for j, reqPattern := range kustPattern.Match {
+			if err := reqPattern.Validate(); err != nil {
+				return fmt.Errorf("spec.kustomizePatterns[%s].match[%s].%s", strconv.Itoa(i), strconv.Itoa(j), err.Error())
+			}
+		}
+	}
+	for i, saPattern := range spec.ServiceAccountPatterns {
+		if err := saPattern.Validate(); err != nil {
+			return fmt.Errorf("spec.serviceAccountPatterns[%s].%s", strconv.Itoa(i), err.Error())
+		}
+	}
+	if err := spec.OwnerCheck.Validate(); err != nil {
+		return fmt.Errorf("spec.ownerCheck.%s", err.Error())
+	}
+	return nil
+}
+
 func ValidateResourceSignature(reqc *common.ReqContext) (bool, error) {
 	var data *rsig.ResourceSignature
 	dec := json.NewDecoder(bytes.NewReader(reqc.RawObject))
@@ -124,7 +181,9 @@ func ValidateShieldConfig(reqc *common.ReqContext) (bool, error) {
 	return true, nil
 }
 
-func ValidateSignerConfig(reqc *common.ReqContext) (bool, error) {
+// ValidateSignerConfig checks the format of SignerConfig. `keys` are used to find keyConfigs of keyless signature,
+// because a signer with such keyConfig must be identified by both `identity` and `issuer`.
+func ValidateSignerConfig(reqc *common.ReqContext, keys []common.VerificationKey) (bool, error) {
 	var data *sigconf.SignerConfig
 	dec := json.NewDecoder(bytes.NewReader(reqc.RawObject))
 	dec.DisallowUnknownFields() // Force errors if data has undefined fields
@@ -143,10 +202,81 @@ func ValidateSignerConfig(reqc *common.ReqContext) (bool, error) {
 			return false, fmt.Errorf("`spec.config.signers[%s].subjects` in SignerConfig is empty.", strconv.Itoa(i))
 		}
 	}
+	signerNames := []string{}
+	for _, signer := range data.Spec.Config.Signers {
+		signerNames = append(signerNames, signer.Name)
+	}
+	for i, policy := range data.Spec.Config.Policies {
+		if err := common.ValidatePatternArray(policy.Namespaces); err != nil {
+			return false, fmt.Errorf("`spec.config.policies[%s].namespaces` in SignerConfig has an invalid pattern; %s", strconv.Itoa(i), err.Error())
+		}
+		if err := common.ValidatePatternArray(policy.ExcludeNamespaces); err != nil {
+			return false, fmt.Errorf("`spec.config.policies[%s].excludeNamespaces` in SignerConfig has an invalid pattern; %s", strconv.Itoa(i), err.Error())
+		}
+		if policy.MinSigners < 0 {
+			return false, fmt.Errorf("`spec.config.policies[%s].minSigners` in SignerConfig must not be negative.", strconv.Itoa(i))
+		}
+		for _, name := range policy.RequiredSigners {
+			if !common.ExactMatchWithPatternArray(name, signerNames) {
+				return false, fmt.Errorf("`spec.config.policies[%s].requiredSigners` in SignerConfig has undefined signer `%s`.", strconv.Itoa(i), name)
+			}
+		}
+		if numOfSigners := len(common.GetUnionOfArrays(policy.RequiredSigners, policy.Signers)); policy.MinSigners > numOfSigners {
+			return false, fmt.Errorf("`spec.config.policies[%s].minSigners` in SignerConfig is larger than the number of signers.", strconv.Itoa(i))
+		}
+		if policy.MaxSignatureAge != "" {
+			if maxAge, err := time.ParseDuration(policy.MaxSignatureAge); err != nil {
+				return false, fmt.Errorf("`spec.config.policies[%s].maxSignatureAge` in SignerConfig must be duration format like `720h`; %s", strconv.Itoa(i), err.Error())
+			} else if maxAge <= 0 {
+				return false, fmt.Errorf("`spec.config.policies[%s].maxSignatureAge` in SignerConfig must be positive.", strconv.Itoa(i))
+			}
+		}
+	}
+	sigstoreKeyConfigs := map[string]bool{}
+	for _, key := range keys {
+		if key.Type == common.SignatureTypeSigstore {
+			sigstoreKeyConfigs[key.KeyConfig] = true
+		}
+	}
+	for i, signer := range data.Spec.Config.Signers {
+		for j, subject := range signer.Subjects {
+			if err := subject.Validate(); err != nil {
+				return false, fmt.Errorf("`spec.config.signers[%s].subjects[%s]` in SignerConfig has an invalid pattern; %s", strconv.Itoa(i), strconv.Itoa(j), err.Error())
+			}
+			// an empty pattern matches any certificate issued by Fulcio
+			if sigstoreKeyConfigs[signer.KeyConfig] && (subject.Identity == "" || subject.Issuer == "") {
+				return false, fmt.Errorf("`spec.config.signers[%s].subjects[%s]` in SignerConfig must have both `identity` and `issuer` for keyless signature.", strconv.Itoa(i), strconv.Itoa(j))
+			}
+		}
+	}
+	for i, bg := range data.Spec.Config.BreakGlass {
+		if bg.ExpiresAt == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, bg.ExpiresAt); err != nil {
+			return false, fmt.Errorf("`spec.config.breakGlass[%s].expiresAt` in SignerConfig must be RFC3339 format; %s", strconv.Itoa(i), err.Error())
+		}
+	}
 
 	return true, nil
 }
 
+func ValidateSignatureRevocation(reqc *common.ReqContext) (bool, error) {
+	var data *sigrevoke.SignatureRevocation
+	dec := json.NewDecoder(bytes.NewReader(reqc.RawObject))
+	dec.DisallowUnknownFields() // Force errors if data has undefined fields
+
+	if err := dec.Decode(&data); err != nil {
+		return false, err
+	}
+	for i, digest := range data.Spec.SignatureDigests {
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+			return false, fmt.Errorf("`spec.signatureDigests[%s]` in SignatureRevocation must be SHA-256 digest in hex.", strconv.Itoa(i))
+		}
+	}
+	return true, nil
+}
+
 func ValidateHelmReleaseMetadata(reqc *common.ReqContext) (bool, error) {
 	var data *hrm.HelmReleaseMetadata
 	dec := json.NewDecoder(bytes.NewReader(reqc.RawObject))
@@ -0,0 +1,77 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+	"testing"
+
+	common "github.com/IBM/integrity-enforcer/shield/pkg/common"
+)
+
+func TestValidateResourceSigningProfilePatterns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     string
+		expected string
+	}{
+		{"valid", `{"protectRules":[{"match":[{"kind":"ConfigMap","name":"*-config,!test-*"}],"exclude":[{"name":"re:tmp-[0-9]+"}]}]}`, ""},
+		{"invalidRule", `{"protectRules":[{"match":[{"kind":"ConfigMap"}]},{"match":[{"name":"re:app-("}]}]}`, "spec.protectRules[1].match[0].name"},
+		{"invalidAttrs", `{"ignoreAttrs":[{"match":[{"kind":"ConfigMap,"}],"attrs":["data.key"]}]}`, "spec.ignoreAttrs[0].match[0].kind"},
+		{"invalidServiceAccount", `{"serviceAccountPatterns":[{"serviceAccountNames":["re:system:serviceaccount:[a-"]}]}`, "spec.serviceAccountPatterns[0].serviceAccountNames"},
+		{"invalidOwnerCheck", `{"ownerCheck":{"controllerServiceAccounts":["!"]}}`, "spec.ownerCheck.controllerServiceAccounts"},
+	}
+	for _, tc := range testCases {
+		raw := `{"apiVersion":"apis.integrityshield.io/v1alpha1","kind":"ResourceSigningProfile","metadata":{"name":"sample-rsp","namespace":"secure-ns"},"spec":` + tc.spec + `}`
+		reqc := &common.ReqContext{Kind: common.ProfileCustomResourceKind, Namespace: "secure-ns", RawObject: []byte(raw)}
+		ok, err := ValidateResourceSigningProfile(reqc, "integrity-shield-operator-system")
+		if tc.expected == "" {
+			if !ok || err != nil {
+				t.Errorf("TestValidateResourceSigningProfilePatterns() Failed (%s)\nexpected: valid\nactual: %v", tc.name, err)
+			}
+			continue
+		}
+		if ok || err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("TestValidateResourceSigningProfilePatterns() Failed (%s)\nexpected: error about %s\nactual: %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestValidateSignerConfigKeyless(t *testing.T) {
+	keys := []common.VerificationKey{
+		{KeyConfig: "sample-signer-keyconfig", Type: common.SignatureTypePGP},
+		{KeyConfig: "sigstore-keyconfig", Type: common.SignatureTypeSigstore},
+	}
+	testCases := []struct {
+		name     string
+		signer   string
+		expected bool
+	}{
+		{"keyless", `{"name":"ci","keyConfig":"sigstore-keyconfig","subjects":[{"identity":"https://github.com/example/repo/*","issuer":"https://token.actions.githubusercontent.com"}]}`, true},
+		{"keylessWithoutIssuer", `{"name":"ci","keyConfig":"sigstore-keyconfig","subjects":[{"identity":"https://github.com/example/repo/*"}]}`, false},
+		{"keylessWithoutIdentity", `{"name":"ci","keyConfig":"sigstore-keyconfig","subjects":[{"issuer":"https://token.actions.githubusercontent.com"}]}`, false},
+		{"pgp", `{"name":"signer-a","keyConfig":"sample-signer-keyconfig","subjects":[{"email":"signer@enterprise.com"}]}`, true},
+	}
+	for _, tc := range testCases {
+		raw := `{"apiVersion":"apis.integrityshield.io/v1alpha1","kind":"SignerConfig","metadata":{"name":"signer-config","namespace":"integrity-shield-operator-system"},"spec":{"config":{"signers":[` + tc.signer + `]}}}`
+		reqc := &common.ReqContext{Kind: common.SignerConfigCustomResourceKind, RawObject: []byte(raw)}
+		ok, err := ValidateSignerConfig(reqc, keys)
+		if ok != tc.expected {
+			t.Errorf("TestValidateSignerConfigKeyless() Failed (%s)\nexpected: %v\nactual: %v (%v)", tc.name, tc.expected, ok, err)
+		}
+	}
+}
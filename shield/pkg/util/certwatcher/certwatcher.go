@@ -0,0 +1,109 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certwatcher
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"sync"
+
+	logger "github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// CertWatcher keeps the webhook key pair up to date.
+// A mounted secret is updated by swapping a symlink in its directory,
+// so the directory is watched instead of the files themselves.
+type CertWatcher struct {
+	certPath, keyPath string
+	cert              *tls.Certificate
+	watcher           *fsnotify.Watcher
+	lock              sync.RWMutex
+}
+
+func NewCertWatcher(certPath, keyPath string) (*CertWatcher, error) {
+	cw := &CertWatcher{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+	if err := cw.load(); err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(certPath)); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	if keyDir := filepath.Dir(keyPath); keyDir != filepath.Dir(certPath) {
+		if err := watcher.Add(keyDir); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+	cw.watcher = watcher
+	return cw, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate so that every handshake uses the latest key pair.
+func (cw *CertWatcher) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cw.lock.RLock()
+	defer cw.lock.RUnlock()
+	return cw.cert, nil
+}
+
+// Start reloads the key pair on every change in the watched directories until stopCh is closed.
+// If the new key pair cannot be loaded (e.g. only one of the files is updated yet), the current one is kept.
+func (cw *CertWatcher) Start(stopCh <-chan struct{}) {
+	defer cw.watcher.Close()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case event, ok := <-cw.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			if err := cw.load(); err != nil {
+				logger.Warn("Failed to reload TLS certificate, keep using the current one; ", err.Error())
+				continue
+			}
+			logger.Info("TLS certificate has been reloaded.")
+		case err, ok := <-cw.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Error("Error while watching TLS certificate; ", err.Error())
+		}
+	}
+}
+
+func (cw *CertWatcher) load() error {
+	pair, err := tls.LoadX509KeyPair(cw.certPath, cw.keyPath)
+	if err != nil {
+		return err
+	}
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+	cw.cert = &pair
+	return nil
+}
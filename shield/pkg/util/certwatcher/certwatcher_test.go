@@ -0,0 +1,106 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package certwatcher
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	x509util "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
+)
+
+// writeKeyPair writes a new key pair to `<dir>/<dataDir>` and points `<dir>/..data` to it,
+// in the same way as kubelet updates a mounted secret.
+func writeKeyPair(t *testing.T, dir, dataDir string) []byte {
+	certPem, keyPem, _, err := x509util.CreateCertificate("ishield-server", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Mkdir(filepath.Join(dir, dataDir), 0755)
+	_ = ioutil.WriteFile(filepath.Join(dir, dataDir, "tls.crt"), certPem, 0644)
+	_ = ioutil.WriteFile(filepath.Join(dir, dataDir, "tls.key"), keyPem, 0600)
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(dataDir, tmpLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	return x509util.PEMDecode(certPem, x509util.PEMTypeCertificate)
+}
+
+func TestCertWatcherRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ishield-certwatcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldCert := writeKeyPair(t, dir, "..data_1")
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		_ = os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name))
+	}
+	cw, err := NewCertWatcher(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go cw.Start(stopCh)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: cw.GetCertificate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+	servedCert := func() []byte {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+
+	if !bytes.Equal(servedCert(), oldCert) {
+		t.Fatal("CertWatcher Failed\nexpected: the initial certificate is served")
+	}
+
+	newCert := writeKeyPair(t, dir, "..data_2")
+	deadline := time.Now().Add(5 * time.Second)
+	for !bytes.Equal(servedCert(), newCert) {
+		if time.Now().After(deadline) {
+			t.Fatal("CertWatcher Failed\nexpected: the rotated certificate is served\nactual: the old certificate is served")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -23,6 +23,9 @@ import (
 	// "context"
 	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/IBM/integrity-enforcer/shield/pkg/util/metrics"
 
 	"github.com/ghodss/yaml"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -44,7 +47,12 @@ var (
 	warningNoLastAppliedConfigAnnotation = "Warning: %[1]s apply should be used on resource created by either %[1]s create --save-config or %[1]s apply\n"
 )
 
-func DryRunCreate(objBytes []byte, namespace string) ([]byte, error) {
+func DryRunCreate(objBytes []byte, namespace string) (simObjBytes []byte, err error) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveDryRunCreate(err == nil, start)
+	}()
+
 	config, err := GetKubeConfig()
 	if err != nil {
 		return nil, fmt.Errorf("Error in getting k8s config; %s", err.Error())
@@ -81,7 +89,7 @@ func DryRunCreate(objBytes []byte, namespace string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Error in creating resource; %s, gvk: %s", err.Error(), gvk)
 	}
-	simObjBytes, err := yaml.Marshal(simObj)
+	simObjBytes, err = yaml.Marshal(simObj)
 	if err != nil {
 		return nil, fmt.Errorf("Error in converting ojb to yaml; %s", err.Error())
 	}
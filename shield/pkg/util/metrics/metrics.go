@@ -0,0 +1,102 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "ishield"
+
+var (
+	// number of admission requests handled by IShield server
+	admissionRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "admission_requests_total",
+			Help:      "Number of admission requests processed by Integrity Shield, by decision and reason code.",
+		},
+		[]string{"decision", "reason", "operation", "kind", "namespace", "rsp"},
+	)
+
+	// latency of Handler.Run()
+	handlerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_run_duration_seconds",
+			Help:      "Latency of processing a single admission request.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"decision", "operation"},
+	)
+
+	// latency of signature verification
+	signatureVerificationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "signature_verification_duration_seconds",
+			Help:      "Latency of verifying a signature for a single request.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"signType", "result"},
+	)
+
+	// latency of kubeutil.DryRunCreate()
+	dryRunDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "dryrun_create_duration_seconds",
+			Help:      "Latency of dry-run create calls to the API server.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		admissionRequests,
+		handlerDuration,
+		signatureVerificationDuration,
+		dryRunDuration,
+	)
+}
+
+func resultLabel(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "failure"
+}
+
+func RecordAdmissionRequest(decision, reason, operation, kind, namespace, rsp string) {
+	admissionRequests.WithLabelValues(decision, reason, operation, kind, namespace, rsp).Inc()
+}
+
+func ObserveHandlerRun(decision, operation string, start time.Time) {
+	handlerDuration.WithLabelValues(decision, operation).Observe(time.Since(start).Seconds())
+}
+
+func ObserveSignatureVerification(signType string, ok bool, start time.Time) {
+	signatureVerificationDuration.WithLabelValues(signType, resultLabel(ok)).Observe(time.Since(start).Seconds())
+}
+
+func ObserveDryRunCreate(ok bool, start time.Time) {
+	dryRunDuration.WithLabelValues(resultLabel(ok)).Observe(time.Since(start).Seconds())
+}
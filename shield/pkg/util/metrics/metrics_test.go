@@ -0,0 +1,55 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsFunctions(t *testing.T) {
+	RecordAdmissionRequest("deny", "no-sig", "CREATE", "ConfigMap", "test-ns", "test-ns/test-rsp")
+	RecordAdmissionRequest("deny", "no-sig", "CREATE", "ConfigMap", "test-ns", "test-ns/test-rsp")
+	count := testutil.ToFloat64(admissionRequests.WithLabelValues("deny", "no-sig", "CREATE", "ConfigMap", "test-ns", "test-ns/test-rsp"))
+	if count != 2 {
+		t.Errorf("\nexpected: %v\nactual: %v", 2, count)
+	}
+
+	start := time.Now()
+	ObserveHandlerRun("deny", "CREATE", start)
+	ObserveSignatureVerification("pgp", true, start)
+	ObserveDryRunCreate(false, start)
+	if n := len(collect(dryRunDuration)); n != 1 {
+		t.Errorf("dryrun histogram should have 1 series; actual: %d", n)
+	}
+}
+
+func collect(c prometheus.Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+	ms := []prometheus.Metric{}
+	for m := range ch {
+		ms = append(ms, m)
+	}
+	return ms
+}
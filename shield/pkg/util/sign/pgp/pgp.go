@@ -17,9 +17,11 @@
 package pgp
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/IBM/integrity-enforcer/shield/pkg/util/logger"
@@ -67,6 +69,21 @@ func GetFirstIdentity(signer *openpgp.Entity) *openpgp.Identity {
 }
 
 func VerifySignature(keyPath string, msg, sig string) (bool, string, *Signer, []byte, error) {
+	if msg == "" {
+		return false, "Message to be verified is empty", nil, nil, nil
+	}
+	if sig == "" {
+		return false, "Signature to be verified is empty", nil, nil, nil
+	}
+	keyRing, err := LoadKeyRing(keyPath)
+	if err != nil {
+		return false, "Error when loading key ring", nil, nil, err
+	}
+	return VerifySignatureWithKeyRing(keyRing, msg, sig)
+}
+
+// VerifySignatureWithKeyRing verifies the signature with a keyring which is already loaded
+func VerifySignatureWithKeyRing(keyRing openpgp.EntityList, msg, sig string) (bool, string, *Signer, []byte, error) {
 	if msg == "" {
 		return false, "Message to be verified is empty", nil, nil, nil
 	}
@@ -76,9 +93,7 @@ func VerifySignature(keyPath string, msg, sig string) (bool, string, *Signer, []
 	cfgReader := strings.NewReader(msg)
 	sigReader := strings.NewReader(sig)
 
-	if keyRing, err := LoadKeyRing(keyPath); err != nil {
-		return false, "Error when loading key ring", nil, nil, err
-	} else if signer, err := openpgp.CheckArmoredDetachedSignature(keyRing, cfgReader, sigReader); signer == nil {
+	if signer, err := openpgp.CheckArmoredDetachedSignature(keyRing, cfgReader, sigReader); signer == nil {
 		logger.Debug("msg:", msg)
 		logger.Debug("sig:", sig)
 		if err != nil {
@@ -139,36 +154,76 @@ func EntityListToSlice(keyring openpgp.EntityList) []*openpgp.Entity {
 	return entSlice
 }
 
-// func DetachSign(keyPathList []string, msg string, signer string) (string, string, error) {
-// 	if msg == "" {
-// 		return "", "Message to be signed is empty", nil
-// 	}
-// 	sig := ""
-// 	msgReader := strings.NewReader(msg)
-// 	sigWriter := bytes.NewBufferString(sig)
-
-// 	keyRing, err := LoadKeyRing(keyPathList)
-// 	if err != nil {
-// 		return "", "Error when loading key ring", err
-// 	}
-// 	var signerKey *openpgp.Entity
-// 	if signer == "" {
-// 		signerKey = keyRing[0]
-// 	} else {
-// 		signerKey = FindSignerKey(keyRing, signer)
-// 	}
-// 	if signerKey == nil {
-// 		reasonFail := fmt.Sprintf("No signer match with the specified signer expression: %s", signer)
-// 		return "", reasonFail, errors.New(reasonFail)
-// 	}
-
-// 	err = openpgp.ArmoredDetachSignText(sigWriter, signerKey, msgReader, nil)
-// 	if err != nil {
-// 		return "", "Error when signing", err
-// 	}
-
-// 	return sigWriter.String(), "", nil
-// }
+// DetachSign generates an armored detached signature of the message with the private key of the signer in the keyring.
+// The signer is matched with the identity in the same way as MatchIdentity, and the first private key is used if it is empty.
+// An encrypted private key is decrypted with the passphrase.
+func DetachSign(keyRing openpgp.EntityList, msg []byte, signer string, passphrase []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, fmt.Errorf("message to be signed is empty")
+	}
+	var signerKey *openpgp.Entity
+	for _, ent := range keyRing {
+		if ent.PrivateKey == nil {
+			continue
+		}
+		if idt := GetFirstIdentity(ent); signer == "" || (idt != nil && MatchIdentity(idt, signer)) {
+			signerKey = ent
+			break
+		}
+	}
+	if signerKey == nil {
+		return nil, fmt.Errorf("no private key matches with the specified signer expression: %s", signer)
+	}
+	if signerKey.PrivateKey.Encrypted {
+		if err := signerKey.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("failed to decrypt the private key; %s", err.Error())
+		}
+	}
+
+	sig := bytes.NewBuffer(nil)
+	if err := openpgp.ArmoredDetachSign(sig, signerKey, bytes.NewReader(msg), nil); err != nil {
+		return nil, err
+	}
+	return sig.Bytes(), nil
+}
+
+// ReadKeyRing reads a keyring in binary format (e.g. pubring.gpg) or in armored format
+func ReadKeyRing(keyRingBytes []byte) (openpgp.EntityList, error) {
+	keyRing, err := openpgp.ReadKeyRing(bytes.NewReader(keyRingBytes))
+	if err != nil {
+		if armored, armoredErr := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyRingBytes)); armoredErr == nil {
+			return armored, nil
+		}
+	}
+	return keyRing, err
+}
+
+// ReadKeyRings reads all keyrings in the given key files. Files which cannot be read as a keyring are skipped,
+// and an error is returned only when no keyring is found.
+func ReadKeyRings(files map[string][]byte) (openpgp.EntityList, error) {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entities := openpgp.EntityList{}
+	var retErr error
+	for _, name := range names {
+		keyRing, err := ReadKeyRing(files[name])
+		if err != nil {
+			retErr = fmt.Errorf("failed to read keyring \"%s\"; %s", name, err.Error())
+			continue
+		}
+		entities = append(entities, keyRing...)
+	}
+	if len(entities) > 0 {
+		return entities, nil
+	}
+	if retErr == nil {
+		retErr = fmt.Errorf("no keyring is found")
+	}
+	return entities, retErr
+}
 
 func LoadKeyRing(keyPath string) (openpgp.EntityList, error) {
 	entities := []*openpgp.Entity{}
@@ -17,10 +17,13 @@
 package pgp
 
 import (
+	"bytes"
 	"encoding/base64"
 	"io/ioutil"
 	"os"
 	"testing"
+
+	"golang.org/x/crypto/openpgp"
 )
 
 // const testDefaultPublicRingPath = "~/.gnupg/pubring.gpg"
@@ -87,3 +90,31 @@ func TestVerify(t *testing.T) {
 	}
 	_ = os.Remove(testPubringPath)
 }
+
+func TestDetachSign(t *testing.T) {
+	entity, err := openpgp.NewEntity("TestSigner", "", "signer@enterprise.com", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pubring := bytes.NewBuffer(nil)
+	if err := entity.Serialize(pubring); err != nil {
+		t.Error(err)
+		return
+	}
+	keyRing, _ := ReadKeyRing(pubring.Bytes())
+
+	msg := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-configmap\n"
+	if _, err := DetachSign(openpgp.EntityList{entity}, []byte(msg), "other@enterprise.com", nil); err == nil {
+		t.Errorf("DetachSign Failed\nexpected: error for unknown signer\nactual: no error")
+	}
+	sig, err := DetachSign(openpgp.EntityList{entity}, []byte(msg), "signer@enterprise.com", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	verified, reasonFail, signer, _, err := VerifySignatureWithKeyRing(keyRing, msg, string(sig))
+	if !verified || signer.Email != "signer@enterprise.com" {
+		t.Errorf("DetachSign Failed\nexpected: verified by signer@enterprise.com\nactual: verified: %t, reasonFail: %s, err: %v", verified, reasonFail, err)
+	}
+}
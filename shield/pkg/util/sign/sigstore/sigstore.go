@@ -0,0 +1,279 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package sigstore
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	x509util "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
+)
+
+// RekorPublicKeyFilename is the file name of the transparency log public key in the key directory.
+// The other `.crt` or `.pem` files in the directory are regarded as Fulcio root (and intermediate) certificates.
+const RekorPublicKeyFilename = "rekor.pub"
+
+// OIDC issuer extensions in Fulcio certificates
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Bundle is a transparency log entry attached to a cosign signature.
+type Bundle struct {
+	SignedEntryTimestamp []byte        `json:"SignedEntryTimestamp"`
+	Payload              BundlePayload `json:"Payload"`
+}
+
+type BundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogIndex       int64  `json:"logIndex"`
+	LogID          string `json:"logID"`
+}
+
+// hashedRekord is the body of transparency log entry for a signature
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// KeyDir is a set of Fulcio certificates and Rekor public key loaded from a key directory.
+type KeyDir struct {
+	Roots         *x509.CertPool
+	Intermediates []*x509.Certificate
+	RekorPubKey   crypto.PublicKey
+}
+
+func LoadKeyDir(keyDir string) (*KeyDir, error) {
+	files, err := x509util.ReadKeyDir(keyDir)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := NewKeyDir(files)
+	if err != nil {
+		return nil, fmt.Errorf("%s in %s", err.Error(), keyDir)
+	}
+	return keys, nil
+}
+
+// NewKeyDir loads Fulcio certificates and Rekor public key from the given key files.
+func NewKeyDir(files map[string][]byte) (*KeyDir, error) {
+	certs, err := x509util.ParseCertFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no root certificate is found")
+	}
+	roots := x509.NewCertPool()
+	intermediates := []*x509.Certificate{}
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+			roots.AddCert(cert)
+		} else {
+			intermediates = append(intermediates, cert)
+		}
+	}
+
+	keyPemBytes, ok := files[RekorPublicKeyFilename]
+	if !ok {
+		return nil, fmt.Errorf("failed to read transparency log public key; %s is not found", RekorPublicKeyFilename)
+	}
+	keyBytes := x509util.PEMDecode(keyPemBytes, x509util.PEMTypePublicKey)
+	rekorPubKey, err := x509.ParsePKIXPublicKey(keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transparency log public key; %s", err.Error())
+	}
+	return &KeyDir{Roots: roots, Intermediates: intermediates, RekorPubKey: rekorPubKey}, nil
+}
+
+// VerifySignature verifies a cosign signature with a short-lived certificate.
+// The transparency log entry in the bundle must be signed by Rekor and must record this signature and certificate,
+// and the certificate must be valid at the time when the entry is integrated into the log.
+// It returns the verified certificate; invalid signature is reported by reasonFail with nil error.
+func VerifySignature(message, signature, certPemBytes, bundleBytes []byte, keyDir string) (bool, string, *x509.Certificate, error) {
+	keys, err := LoadKeyDir(keyDir)
+	if err != nil {
+		reasonFail := fmt.Sprintf("failed to load sigstore keys: %s", err.Error())
+		return false, reasonFail, nil, fmt.Errorf(reasonFail)
+	}
+	return VerifySignatureWithKeys(message, signature, certPemBytes, bundleBytes, keys)
+}
+
+// VerifySignatureWithKeys verifies a cosign signature in the same way as VerifySignature with keys which are already loaded.
+func VerifySignatureWithKeys(message, signature, certPemBytes, bundleBytes []byte, keys *KeyDir) (bool, string, *x509.Certificate, error) {
+
+	var bundle Bundle
+	if err := json.Unmarshal(bundleBytes, &bundle); err != nil {
+		return false, fmt.Sprintf("failed to parse bundle: %s", err.Error()), nil, nil
+	}
+	if reasonFail := verifyBundle(&bundle, keys.RekorPubKey); reasonFail != "" {
+		return false, reasonFail, nil, nil
+	}
+
+	chain, err := x509util.ParseCertificateChain(certPemBytes)
+	if err != nil {
+		return false, fmt.Sprintf("failed to parse certificate: %s", err.Error()), nil, nil
+	}
+	cert := chain[0]
+	if reasonFail := verifyEntryBody(bundle.Payload.Body, message, signature, cert); reasonFail != "" {
+		return false, reasonFail, nil, nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range append(chain[1:], keys.Intermediates...) {
+		intermediates.AddCert(c)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         keys.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Unix(bundle.Payload.IntegratedTime, 0),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	if _, err := cert.Verify(opts); err != nil {
+		return false, fmt.Sprintf("failed to verify certificate: %s", err.Error()), nil, nil
+	}
+
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return false, fmt.Sprintf("failed to get public key from certificate: %s", err.Error()), nil, nil
+	}
+	sigOk, reasonFail, err := x509util.VerifySignature(message, signature, pubKeyBytes)
+	if err != nil || !sigOk {
+		return false, reasonFail, nil, nil
+	}
+	return true, "", cert, nil
+}
+
+// verifyBundle verifies the signed entry timestamp (SET) of the bundle with the transparency log public key.
+func verifyBundle(bundle *Bundle, rekorPubKey crypto.PublicKey) string {
+	ecdsaKey, ok := rekorPubKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Sprintf("unsupported transparency log public key type %T", rekorPubKey)
+	}
+	canonicalPayload, err := canonicalizePayload(bundle.Payload)
+	if err != nil {
+		return fmt.Sprintf("failed to canonicalize bundle payload: %s", err.Error())
+	}
+	digest := sha256.Sum256(canonicalPayload)
+	var esig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(bundle.SignedEntryTimestamp, &esig); err != nil || esig.R == nil || esig.S == nil {
+		return "signed entry timestamp in bundle is malformed"
+	}
+	if !ecdsa.Verify(ecdsaKey, digest[:], esig.R, esig.S) {
+		return "signed entry timestamp in bundle is not signed by the transparency log"
+	}
+	return ""
+}
+
+// canonicalizePayload returns JSON of the payload with sorted keys and no HTML escape, which is the form signed by Rekor.
+func canonicalizePayload(payload BundlePayload) ([]byte, error) {
+	m := map[string]interface{}{
+		"body":           payload.Body,
+		"integratedTime": payload.IntegratedTime,
+		"logIndex":       payload.LogIndex,
+		"logID":          payload.LogID,
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// verifyEntryBody checks if the transparency log entry records the message hash, the signature and the certificate.
+func verifyEntryBody(body string, message, signature []byte, cert *x509.Certificate) string {
+	bodyBytes, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "transparency log entry body is not base64 encoded"
+	}
+	var rekord hashedRekord
+	if err := json.Unmarshal(bodyBytes, &rekord); err != nil {
+		return fmt.Sprintf("failed to parse transparency log entry body: %s", err.Error())
+	}
+	if rekord.Kind != "hashedrekord" {
+		return fmt.Sprintf("unsupported transparency log entry kind `%s`", rekord.Kind)
+	}
+	msgHash := sha256.Sum256(message)
+	if rekord.Spec.Data.Hash.Algorithm != "sha256" || rekord.Spec.Data.Hash.Value != hex.EncodeToString(msgHash[:]) {
+		return "message hash does not match with transparency log entry"
+	}
+	entrySig, err := base64.StdEncoding.DecodeString(rekord.Spec.Signature.Content)
+	if err != nil || !bytes.Equal(entrySig, signature) {
+		return "signature does not match with transparency log entry"
+	}
+	entryCertPem, err := base64.StdEncoding.DecodeString(rekord.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return "certificate does not match with transparency log entry"
+	}
+	entryCert, err := x509util.ParseCertificate(entryCertPem)
+	if err != nil || !entryCert.Equal(cert) {
+		return "certificate does not match with transparency log entry"
+	}
+	return ""
+}
+
+// GetIdentity returns OIDC identity (email or URI in SAN) and issuer of a Fulcio certificate.
+func GetIdentity(cert *x509.Certificate) (string, string) {
+	identity := ""
+	if len(cert.EmailAddresses) > 0 {
+		identity = cert.EmailAddresses[0]
+	} else if len(cert.URIs) > 0 {
+		identity = cert.URIs[0].String()
+	}
+	issuer := ""
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				issuer = s
+				break
+			}
+		} else if ext.Id.Equal(oidIssuerV1) {
+			issuer = string(ext.Value)
+		}
+	}
+	return identity, issuer
+}
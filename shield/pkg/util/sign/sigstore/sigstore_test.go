@@ -0,0 +1,178 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package sigstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	x509util "github.com/IBM/integrity-enforcer/shield/pkg/util/sign/x509"
+)
+
+const testIdentity = "https://github.com/example/repo/.github/workflows/release.yaml@refs/heads/main"
+const testIssuer = "https://token.actions.githubusercontent.com"
+
+type testSignature struct {
+	keyDir    string
+	message   []byte
+	signature []byte
+	cert      []byte
+	bundle    []byte
+}
+
+// newTestSignature creates a Fulcio-like root, a short-lived certificate which is already expired,
+// a signature and a bundle signed by a Rekor-like key.
+func newTestSignature(t *testing.T) *testSignature {
+	rootCertPem, rootPrvKeyPem, _, err := x509util.CreateCertificateWithAlgorithm("fulcio-root", x509util.KeyAlgorithmECDSAP256, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, _ := x509util.ParseCertificate(rootCertPem)
+	rootPrvKey, _ := x509util.ParsePrivateKey(rootPrvKeyPem)
+
+	signerKey, signerPubKey, err := x509util.GenerateKeyPairWithAlgorithm(x509util.KeyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerKeyPem, _ := x509util.MarshalPrivateKey(signerKey)
+	identityURI, _ := url.Parse(testIdentity)
+	issuerValue, _ := asn1.Marshal(testIssuer)
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(1),
+		Subject:         pkix.Name{},
+		NotBefore:       now.Add(-1 * time.Hour),
+		NotAfter:        now.Add(-50 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{identityURI},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuerValue}},
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, tmpl, rootCert, signerPubKey, rootPrvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPem := x509util.PEMEncode(certBytes, x509util.PEMTypeCertificate)
+
+	message := []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-cm\n")
+	signature, err := x509util.GenerateSignature(message, signerKeyPem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rekorPubKeyBytes, _ := x509.MarshalPKIXPublicKey(rekorKey.Public())
+
+	bundle := newTestBundle(t, rekorKey, message, signature, certPem, now.Add(-55*time.Minute))
+	bundleBytes, _ := json.Marshal(bundle)
+
+	keyDir, err := ioutil.TempDir("", "ishield-sigstore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(keyDir, "fulcio-root.pem"), rootCertPem, 0644)
+	_ = ioutil.WriteFile(filepath.Join(keyDir, RekorPublicKeyFilename), x509util.PEMEncode(rekorPubKeyBytes, x509util.PEMTypePublicKey), 0644)
+
+	return &testSignature{keyDir: keyDir, message: message, signature: signature, cert: certPem, bundle: bundleBytes}
+}
+
+func newTestBundle(t *testing.T, rekorKey *ecdsa.PrivateKey, message, signature, certPem []byte, integratedTime time.Time) *Bundle {
+	msgHash := sha256.Sum256(message)
+	body := fmt.Sprintf(`{"apiVersion":"0.0.1","kind":"hashedrekord","spec":{"data":{"hash":{"algorithm":"sha256","value":"%s"}},"signature":{"content":"%s","publicKey":{"content":"%s"}}}}`,
+		hex.EncodeToString(msgHash[:]), base64.StdEncoding.EncodeToString(signature), base64.StdEncoding.EncodeToString(certPem))
+	payload := BundlePayload{
+		Body:           base64.StdEncoding.EncodeToString([]byte(body)),
+		IntegratedTime: integratedTime.Unix(),
+		LogIndex:       1,
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+	}
+	canonicalPayload, err := canonicalizePayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(canonicalPayload)
+	set, err := rekorKey.Sign(rand.Reader, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Bundle{SignedEntryTimestamp: set, Payload: payload}
+}
+
+func TestVerifySignature(t *testing.T) {
+	ts := newTestSignature(t)
+	defer os.RemoveAll(ts.keyDir)
+
+	ok, reasonFail, cert, err := VerifySignature(ts.message, ts.signature, ts.cert, ts.bundle, ts.keyDir)
+	if err != nil || !ok {
+		t.Fatalf("\nexpected: true\nactual: %t, %s, %v", ok, reasonFail, err)
+	}
+	identity, issuer := GetIdentity(cert)
+	if identity != testIdentity || issuer != testIssuer {
+		t.Errorf("\nexpected: %s, %s\nactual: %s, %s", testIdentity, testIssuer, identity, issuer)
+	}
+
+	// message is not the one recorded in transparency log
+	ok, reasonFail, _, err = VerifySignature([]byte("tampered"), ts.signature, ts.cert, ts.bundle, ts.keyDir)
+	if err != nil || ok {
+		t.Errorf("tampered message should not be verified; %t, %s, %v", ok, reasonFail, err)
+	}
+
+	// bundle is not signed by the transparency log key
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherBundle := newTestBundle(t, otherKey, ts.message, ts.signature, ts.cert, time.Now().Add(-55*time.Minute))
+	otherBundleBytes, _ := json.Marshal(otherBundle)
+	ok, reasonFail, _, err = VerifySignature(ts.message, ts.signature, ts.cert, otherBundleBytes, ts.keyDir)
+	if err != nil || ok {
+		t.Errorf("bundle signed by another key should not be verified; %t, %s, %v", ok, reasonFail, err)
+	}
+}
+
+func TestVerifySignatureOutsideCertValidity(t *testing.T) {
+	ts := newTestSignature(t)
+	defer os.RemoveAll(ts.keyDir)
+
+	// the entry integrated after the certificate expired must be rejected
+	rekorKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rekorPubKeyBytes, _ := x509.MarshalPKIXPublicKey(rekorKey.Public())
+	_ = ioutil.WriteFile(filepath.Join(ts.keyDir, RekorPublicKeyFilename), x509util.PEMEncode(rekorPubKeyBytes, x509util.PEMTypePublicKey), 0644)
+	lateBundle := newTestBundle(t, rekorKey, ts.message, ts.signature, ts.cert, time.Now())
+	lateBundleBytes, _ := json.Marshal(lateBundle)
+
+	ok, reasonFail, _, err := VerifySignature(ts.message, ts.signature, ts.cert, lateBundleBytes, ts.keyDir)
+	if err != nil || ok {
+		t.Errorf("entry integrated after certificate expiry should not be verified; %t, %s, %v", ok, reasonFail, err)
+	}
+}
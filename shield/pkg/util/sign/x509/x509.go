@@ -19,16 +19,24 @@ package x509
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"math/big"
+	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/IBM/integrity-enforcer/shield/pkg/common"
@@ -37,9 +45,28 @@ import (
 var startTimeInt int64
 
 const (
-	PEMTypePrivateKey  string = "RSA PRIVATE KEY"
-	PEMTypePublicKey   string = "PUBLIC KEY"
-	PEMTypeCertificate string = "CERTIFICATE"
+	PEMTypePrivateKey      string = "RSA PRIVATE KEY"
+	PEMTypeECPrivateKey    string = "EC PRIVATE KEY"
+	PEMTypePKCS8PrivateKey string = "PRIVATE KEY"
+	PEMTypePublicKey       string = "PUBLIC KEY"
+	PEMTypeCertificate     string = "CERTIFICATE"
+)
+
+// reasonFail of VerifyCertificate starts with these when the certificate itself is not valid anymore
+const (
+	ReasonCertificateRevoked = "certificate is revoked"
+	ReasonCertificateExpired = "certificate is expired or not yet valid"
+	ReasonCRLExpired         = "crl is expired"
+)
+
+type KeyAlgorithm string
+
+const (
+	KeyAlgorithmRSA       KeyAlgorithm = "RSA"
+	KeyAlgorithmRSAPSS    KeyAlgorithm = "RSA-PSS"
+	KeyAlgorithmECDSAP256 KeyAlgorithm = "ECDSA-P256"
+	KeyAlgorithmECDSAP384 KeyAlgorithm = "ECDSA-P384"
+	KeyAlgorithmEd25519   KeyAlgorithm = "Ed25519"
 )
 
 func init() {
@@ -55,12 +82,83 @@ func GenerateKeyPair() (*rsa.PrivateKey, crypto.PublicKey, error) {
 	return privateCaKey, publicCaKey, nil
 }
 
+// GenerateKeyPairWithAlgorithm generates a key pair of the given algorithm.
+// RSA and RSA-PSS share the same RSA key, and only differ in signature scheme.
+func GenerateKeyPairWithAlgorithm(keyAlg KeyAlgorithm) (crypto.Signer, crypto.PublicKey, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch keyAlg {
+	case KeyAlgorithmRSA, KeyAlgorithmRSAPSS:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case KeyAlgorithmECDSAP256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmECDSAP384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyAlgorithmEd25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("unsupported key algorithm `%s`", keyAlg)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, privateKey.Public(), nil
+}
+
+// MarshalPrivateKey encodes a private key into PEM. RSA key is encoded in PKCS#1 for compatibility,
+// and the others are encoded in PKCS#8.
+func MarshalPrivateKey(privateKey crypto.Signer) ([]byte, error) {
+	if rsaKey, ok := privateKey.(*rsa.PrivateKey); ok {
+		return PEMEncode(x509.MarshalPKCS1PrivateKey(rsaKey), PEMTypePrivateKey), nil
+	}
+	prvKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return PEMEncode(prvKeyBytes, PEMTypePKCS8PrivateKey), nil
+}
+
+// ParsePrivateKey decodes a PEM private key in PKCS#1, SEC 1 (EC) or PKCS#8 format.
+func ParsePrivateKey(prvKeyPemBytes []byte) (crypto.Signer, error) {
+	p, _ := pem.Decode(prvKeyPemBytes)
+	if p == nil {
+		return nil, fmt.Errorf("failed to decode PEM private key")
+	}
+	switch p.Type {
+	case PEMTypePrivateKey:
+		return x509.ParsePKCS1PrivateKey(p.Bytes)
+	case PEMTypeECPrivateKey:
+		return x509.ParseECPrivateKey(p.Bytes)
+	case PEMTypePKCS8PrivateKey:
+		key, err := x509.ParsePKCS8PrivateKey(p.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM type `%s` for private key", p.Type)
+	}
+}
+
 func CreateCertificate(caName string, parentCertPemBytes, parentPrivateKeyPemBytes []byte) ([]byte, []byte, []byte, error) {
-	privateKey, publicCaKey, err := GenerateKeyPair()
+	return CreateCertificateWithAlgorithm(caName, KeyAlgorithmRSA, parentCertPemBytes, parentPrivateKeyPemBytes)
+}
+
+// CreateCertificateWithAlgorithm is the same as CreateCertificate, but the key of the new certificate is generated with the given algorithm.
+// The parent private key can be any of supported algorithms.
+func CreateCertificateWithAlgorithm(caName string, keyAlg KeyAlgorithm, parentCertPemBytes, parentPrivateKeyPemBytes []byte) ([]byte, []byte, []byte, error) {
+	privateKey, publicCaKey, err := GenerateKeyPairWithAlgorithm(keyAlg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	prvKeyPem, err := MarshalPrivateKey(privateKey)
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	prvKeyBytes := x509.MarshalPKCS1PrivateKey(privateKey)
 	pubKeyBytes, err := x509.MarshalPKIXPublicKey(publicCaKey)
 	if err != nil {
 		return nil, nil, nil, err
@@ -80,7 +178,7 @@ func CreateCertificate(caName string, parentCertPemBytes, parentPrivateKeyPemByt
 	}
 
 	var parentCa *x509.Certificate
-	var parentPrivateKey *rsa.PrivateKey
+	var parentPrivateKey crypto.Signer
 
 	// if parent data is given, create new cert using it.
 	// otherwise, create self-signed cert
@@ -90,8 +188,7 @@ func CreateCertificate(caName string, parentCertPemBytes, parentPrivateKeyPemByt
 		if err != nil {
 			return nil, nil, nil, err
 		}
-		parentPrivateKeyBytes := PEMDecode(parentPrivateKeyPemBytes, PEMTypePrivateKey)
-		parentPrivateKey, err = x509.ParsePKCS1PrivateKey(parentPrivateKeyBytes)
+		parentPrivateKey, err = ParsePrivateKey(parentPrivateKeyPemBytes)
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -105,20 +202,27 @@ func CreateCertificate(caName string, parentCertPemBytes, parentPrivateKeyPemByt
 		return nil, nil, nil, err
 	}
 	certPem := PEMEncode(caCertificate, PEMTypeCertificate)
-	prvKeyPem := PEMEncode(prvKeyBytes, PEMTypePrivateKey)
 	pubKeyPem := PEMEncode(pubKeyBytes, PEMTypePublicKey)
 	return certPem, prvKeyPem, pubKeyPem, nil
 }
 
+func isSupportedPEMType(mode string) bool {
+	switch mode {
+	case PEMTypePrivateKey, PEMTypeECPrivateKey, PEMTypePKCS8PrivateKey, PEMTypePublicKey, PEMTypeCertificate:
+		return true
+	}
+	return false
+}
+
 func PEMEncode(content []byte, mode string) []byte {
-	if mode != PEMTypePrivateKey && mode != PEMTypePublicKey && mode != PEMTypeCertificate {
+	if !isSupportedPEMType(mode) {
 		return nil
 	}
 	return pem.EncodeToMemory(&pem.Block{Type: mode, Bytes: content})
 }
 
 func PEMDecode(pemBytes []byte, mode string) []byte {
-	if mode != PEMTypePrivateKey && mode != PEMTypePublicKey && mode != PEMTypeCertificate {
+	if !isSupportedPEMType(mode) {
 		return nil
 	}
 	p, _ := pem.Decode(pemBytes)
@@ -128,21 +232,16 @@ func PEMDecode(pemBytes []byte, mode string) []byte {
 	return p.Bytes
 }
 
-func loadPrivateKey(fpath string) (*rsa.PrivateKey, error) {
+func loadPrivateKey(fpath string) (crypto.Signer, error) {
 	kpath := filepath.Clean(fpath)
 	keyPemBytes, err := ioutil.ReadFile(kpath)
 	if err != nil {
 		return nil, err
 	}
-	keyBytes := PEMDecode(keyPemBytes, PEMTypePrivateKey)
-	private, err := x509.ParsePKCS1PrivateKey(keyBytes)
-	if err != nil {
-		return nil, err
-	}
-	return private, nil
+	return ParsePrivateKey(keyPemBytes)
 }
 
-func loadPublicKey(fpath string) (*rsa.PublicKey, error) {
+func loadPublicKey(fpath string) (crypto.PublicKey, error) {
 	kpath := filepath.Clean(fpath)
 	keyPemBytes, err := ioutil.ReadFile(kpath)
 	if err != nil {
@@ -153,7 +252,7 @@ func loadPublicKey(fpath string) (*rsa.PublicKey, error) {
 	if err != nil {
 		return nil, err
 	}
-	return public.(*rsa.PublicKey), nil
+	return public, nil
 }
 
 func loadCertificate(fpath string) (*x509.Certificate, error) {
@@ -192,6 +291,32 @@ func GetSubjectFromCertificate(certPemBytes []byte) (pkix.Name, error) {
 	return cert.Subject, nil
 }
 
+// ParseCertificateChain parses all certificates in PEM bytes. The first one is the leaf certificate
+// and the rest are intermediate certificates.
+func ParseCertificateChain(certPemBytes []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := certPemBytes
+	for {
+		var p *pem.Block
+		p, rest = pem.Decode(rest)
+		if p == nil {
+			break
+		}
+		if p.Type != PEMTypeCertificate {
+			continue
+		}
+		cert, err := x509.ParseCertificate(p.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate is found in PEM bytes")
+	}
+	return certs, nil
+}
+
 func ParseCertificate(certPemBytes []byte) (*x509.Certificate, error) {
 	certBytes := PEMDecode(certPemBytes, PEMTypeCertificate)
 	cert, err := x509.ParseCertificate(certBytes)
@@ -201,24 +326,83 @@ func ParseCertificate(certPemBytes []byte) (*x509.Certificate, error) {
 	return cert, nil
 }
 
+// GenerateSignature signs a message with the scheme chosen by the key type,
+// i.e. RSA PKCS#1 v1.5, ECDSA or Ed25519.
 func GenerateSignature(msg, prvKeyPemBytes []byte) ([]byte, error) {
-	prvKeyBytes := PEMDecode(prvKeyPemBytes, PEMTypePrivateKey)
-	prvKey, err := x509.ParsePKCS1PrivateKey(prvKeyBytes)
+	return GenerateSignatureWithAlgorithm(msg, prvKeyPemBytes, "")
+}
+
+// GenerateSignatureWithAlgorithm signs a message with the given algorithm.
+// If the algorithm is empty, it is chosen by the key type.
+func GenerateSignatureWithAlgorithm(msg, prvKeyPemBytes []byte, keyAlg KeyAlgorithm) ([]byte, error) {
+	prvKey, err := ParsePrivateKey(prvKeyPemBytes)
 	if err != nil {
 		return nil, err
 	}
-
-	h := crypto.Hash.New(crypto.SHA256)
-	_, _ = h.Write([]byte(msg))
-	msgHash := h.Sum(nil)
-
-	sig, err := rsa.SignPKCS1v15(rand.Reader, prvKey, crypto.SHA256, msgHash)
+	keyTypeAlg, err := getKeyAlgorithm(prvKey.Public())
 	if err != nil {
 		return nil, err
 	}
-	return sig, nil
+	if keyAlg == "" {
+		keyAlg = keyTypeAlg
+	}
+	if keyTypeAlg != keyAlg && !(keyTypeAlg == KeyAlgorithmRSA && keyAlg == KeyAlgorithmRSAPSS) {
+		return nil, fmt.Errorf("key algorithm `%s` cannot be used with %s key", keyAlg, keyTypeAlg)
+	}
+
+	switch keyAlg {
+	case KeyAlgorithmEd25519:
+		// Ed25519 signs a message itself, not a digest
+		return prvKey.Sign(rand.Reader, msg, crypto.Hash(0))
+	case KeyAlgorithmRSAPSS:
+		msgHash := hashMessage(msg, crypto.SHA256)
+		return prvKey.Sign(rand.Reader, msgHash, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	default:
+		hash := getHashFunc(prvKey.Public())
+		msgHash := hashMessage(msg, hash)
+		return prvKey.Sign(rand.Reader, msgHash, hash)
+	}
+}
+
+// getKeyAlgorithm returns the default algorithm for the public key type.
+func getKeyAlgorithm(pubKey crypto.PublicKey) (KeyAlgorithm, error) {
+	switch key := pubKey.(type) {
+	case *rsa.PublicKey:
+		return KeyAlgorithmRSA, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return KeyAlgorithmECDSAP256, nil
+		case elliptic.P384():
+			return KeyAlgorithmECDSAP384, nil
+		}
+		return "", fmt.Errorf("unsupported elliptic curve `%s`", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return KeyAlgorithmEd25519, nil
+	}
+	return "", fmt.Errorf("unsupported public key type %T", pubKey)
 }
 
+// getHashFunc returns the hash function for the public key. SHA-384 is used for P-384 to match its strength.
+func getHashFunc(pubKey crypto.PublicKey) crypto.Hash {
+	if key, ok := pubKey.(*ecdsa.PublicKey); ok && key.Curve == elliptic.P384() {
+		return crypto.SHA384
+	}
+	return crypto.SHA256
+}
+
+func hashMessage(msg []byte, hash crypto.Hash) []byte {
+	h := hash.New()
+	_, _ = h.Write(msg)
+	return h.Sum(nil)
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// VerifySignature verifies a signature with the scheme chosen by the public key type.
+// For RSA key, both PKCS#1 v1.5 and PSS signatures are accepted.
 func VerifySignature(msg, sig, pubKeyBytes []byte) (bool, string, error) {
 	var reasonFail string
 	var err error
@@ -231,15 +415,38 @@ func VerifySignature(msg, sig, pubKeyBytes []byte) (bool, string, error) {
 		return false, reasonFail, fmt.Errorf(reasonFail)
 	}
 
-	h := crypto.Hash.New(crypto.SHA256)
-	_, _ = h.Write([]byte(msg))
-	msgHash := h.Sum(nil)
 	pubKey, err := x509.ParsePKIXPublicKey(pubKeyBytes)
 	if err != nil {
 		reasonFail := fmt.Sprintf("Error when loading public key; %s", err.Error())
 		return false, reasonFail, fmt.Errorf(reasonFail)
 	}
-	err = rsa.VerifyPKCS1v15(pubKey.(*rsa.PublicKey), crypto.SHA256, msgHash, sig)
+	hash := getHashFunc(pubKey)
+	switch key := pubKey.(type) {
+	case *rsa.PublicKey:
+		msgHash := hashMessage(msg, hash)
+		err = rsa.VerifyPKCS1v15(key, hash, msgHash, sig)
+		if err != nil {
+			pssErr := rsa.VerifyPSS(key, hash, msgHash, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: hash})
+			if pssErr == nil {
+				err = nil
+			}
+		}
+	case *ecdsa.PublicKey:
+		var esig ecdsaSignature
+		rest, asn1Err := asn1.Unmarshal(sig, &esig)
+		if asn1Err != nil || len(rest) > 0 || esig.R == nil || esig.S == nil {
+			err = fmt.Errorf("malformed ECDSA signature")
+		} else if !ecdsa.Verify(key, hashMessage(msg, hash), esig.R, esig.S) {
+			err = fmt.Errorf("ECDSA verification error")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, msg, sig) {
+			err = fmt.Errorf("Ed25519 verification error")
+		}
+	default:
+		reasonFail := fmt.Sprintf("Unsupported public key type %T", pubKey)
+		return false, reasonFail, fmt.Errorf(reasonFail)
+	}
 	if err != nil {
 		reasonFail := fmt.Sprintf("Signature is invalid; %s", err.Error())
 		return false, reasonFail, nil
@@ -247,37 +454,123 @@ func VerifySignature(msg, sig, pubKeyBytes []byte) (bool, string, error) {
 	return true, "", nil
 }
 
-func LoadCertDir(certDir string) ([]*x509.Certificate, error) {
-	var certs []*x509.Certificate
-	files, err := ioutil.ReadDir(certDir)
+// ReadKeyDir reads key files in the directory. Hidden files such as `..data` of a mounted Secret are skipped.
+func ReadKeyDir(dir string) (map[string][]byte, error) {
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get files from cert dir; %s", err.Error())
 	}
+	keyFiles := map[string][]byte{}
 	for _, f := range files {
-		if !f.IsDir() && (path.Ext(f.Name()) == ".crt" || path.Ext(f.Name()) == ".pem") {
-			fpath := path.Join(certDir, f.Name())
-			cert, err := loadCertificate(fpath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to load cert file \"%s\" ; %s", fpath, err.Error())
-			}
-			certs = append(certs, cert)
+		if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		fpath := filepath.Clean(path.Join(dir, f.Name()))
+		if fi, err := os.Stat(fpath); err != nil || fi.IsDir() {
+			continue
+		}
+		data, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file \"%s\" ; %s", fpath, err.Error())
+		}
+		keyFiles[f.Name()] = data
+	}
+	return keyFiles, nil
+}
+
+func LoadCertDir(certDir string) ([]*x509.Certificate, error) {
+	files, err := ReadKeyDir(certDir)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertFiles(files)
+}
+
+// LoadCRLDir loads CRL files (`.crl`, PEM or DER) in the directory.
+func LoadCRLDir(certDir string) ([]*pkix.CertificateList, error) {
+	files, err := ReadKeyDir(certDir)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCRLFiles(files)
+}
+
+// ParseCertFiles parses certificate files (`.crt` or `.pem`) in the given files.
+func ParseCertFiles(files map[string][]byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, name := range sortedFileNames(files) {
+		if path.Ext(name) != ".crt" && path.Ext(name) != ".pem" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(PEMDecode(files[name], PEMTypeCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cert file \"%s\" ; %s", name, err.Error())
 		}
+		certs = append(certs, cert)
 	}
 	return certs, nil
 }
 
+// ParseCRLFiles parses CRL files (`.crl`, PEM or DER) in the given files.
+func ParseCRLFiles(files map[string][]byte) ([]*pkix.CertificateList, error) {
+	var crls []*pkix.CertificateList
+	for _, name := range sortedFileNames(files) {
+		if path.Ext(name) != ".crl" {
+			continue
+		}
+		crl, err := x509.ParseCRL(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load crl file \"%s\" ; %s", name, err.Error())
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
+
+func sortedFileNames(files map[string][]byte) []string {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VerifyCertificate verifies the certificate with CA certificates in `caCertPath` directory.
+// `certPemBytes` can be a PEM chain which has intermediate certificates after the leaf one.
+// The certificates in the verified chain are checked against CRL files in the same directory.
 func VerifyCertificate(certPemBytes []byte, caCertPath string) (bool, string, error) {
+	files, err := ReadKeyDir(caCertPath)
+	if err != nil {
+		reasonFail := fmt.Sprintf("failed to load certificate pool: %s", err.Error())
+		return false, reasonFail, fmt.Errorf(reasonFail)
+	}
+	return VerifyCertificateWithKeyFiles(certPemBytes, files)
+}
+
+// VerifyCertificateWithKeyFiles verifies the certificate with CA certificates and CRLs in the given key files.
+func VerifyCertificateWithKeyFiles(certPemBytes []byte, files map[string][]byte) (bool, string, error) {
 	var reasonFail string
 	var err error
-	certBytes := PEMDecode(certPemBytes, PEMTypeCertificate)
-	cert, err := x509.ParseCertificate(certBytes)
+	chain, err := ParseCertificateChain(certPemBytes)
 	if err != nil {
 		reasonFail = fmt.Sprintf("failed to parse certificate: %s", err.Error())
 		return false, reasonFail, fmt.Errorf(reasonFail)
 	}
+	cert := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, interCert := range chain[1:] {
+		intermediates.AddCert(interCert)
+	}
+
+	now := time.Now()
+	if now.After(cert.NotAfter) || now.Before(cert.NotBefore) {
+		reasonFail = fmt.Sprintf("%s: valid from %s to %s", ReasonCertificateExpired, cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+		return false, reasonFail, nil
+	}
 
 	roots := x509.NewCertPool()
-	poolCerts, err := LoadCertDir(caCertPath)
+	poolCerts, err := ParseCertFiles(files)
 	if err != nil {
 		reasonFail = fmt.Sprintf("failed to load certificate pool: %s", err.Error())
 		return false, reasonFail, fmt.Errorf(reasonFail)
@@ -288,18 +581,88 @@ func VerifyCertificate(certPemBytes []byte, caCertPath string) (bool, string, er
 		}
 	}
 	opts := x509.VerifyOptions{
-		Roots:     roots,
-		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
 	}
-	_, err = cert.Verify(opts)
+	verifiedChains, err := cert.Verify(opts)
 	if err != nil {
+		if invalidErr, ok := err.(x509.CertificateInvalidError); ok && invalidErr.Reason == x509.Expired {
+			reasonFail = fmt.Sprintf("%s: %s", ReasonCertificateExpired, err.Error())
+			return false, reasonFail, nil
+		}
 		reasonFail = fmt.Sprintf("failed to verify certificate: %s", err.Error())
 		return false, reasonFail, nil
 	}
 
+	crls, err := ParseCRLFiles(files)
+	if err != nil {
+		reasonFail = fmt.Sprintf("failed to load CRLs: %s", err.Error())
+		return false, reasonFail, fmt.Errorf(reasonFail)
+	}
+	for _, verifiedChain := range verifiedChains {
+		if revoked := findRevokedCertificate(verifiedChain, crls); revoked != nil {
+			reasonFail = fmt.Sprintf("%s: serial number %s, subject `%s`", ReasonCertificateRevoked, revoked.SerialNumber.String(), revoked.Subject.String())
+			return false, reasonFail, nil
+		}
+		// a stale CRL may not list certificates revoked after it was issued
+		if expired := findExpiredCRL(verifiedChain, crls, now); expired != nil {
+			var crlIssuer pkix.Name
+			crlIssuer.FillFromRDNSequence(&expired.TBSCertList.Issuer)
+			reasonFail = fmt.Sprintf("%s: issuer `%s`, next update %s", ReasonCRLExpired, crlIssuer.String(), expired.TBSCertList.NextUpdate.UTC().Format(time.RFC3339))
+			return false, reasonFail, nil
+		}
+	}
+
 	return true, "", nil
 }
 
+// findRevokedCertificate returns a certificate in the chain which is listed in any CRL issued by its parent.
+// CRLs which are not signed by the issuer are ignored.
+func findRevokedCertificate(chain []*x509.Certificate, crls []*pkix.CertificateList) *x509.Certificate {
+	for i := 0; i+1 < len(chain); i++ {
+		cert := chain[i]
+		for _, crl := range issuerCRLs(cert, chain[i+1], crls) {
+			for _, revoked := range crl.TBSCertList.RevokedCertificates {
+				if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return cert
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findExpiredCRL returns a CRL issued by a parent in the chain which is past its `nextUpdate` at `now`.
+func findExpiredCRL(chain []*x509.Certificate, crls []*pkix.CertificateList, now time.Time) *pkix.CertificateList {
+	for i := 0; i+1 < len(chain); i++ {
+		for _, crl := range issuerCRLs(chain[i], chain[i+1], crls) {
+			if crl.HasExpired(now) {
+				return crl
+			}
+		}
+	}
+	return nil
+}
+
+// issuerCRLs returns CRLs for the certificate which are signed by the issuer.
+func issuerCRLs(cert, issuer *x509.Certificate, crls []*pkix.CertificateList) []*pkix.CertificateList {
+	issued := []*pkix.CertificateList{}
+	for _, crl := range crls {
+		var crlIssuer pkix.Name
+		crlIssuer.FillFromRDNSequence(&crl.TBSCertList.Issuer)
+		if crlIssuer.String() != cert.Issuer.String() {
+			continue
+		}
+		if err := issuer.CheckCRLSignature(crl); err != nil {
+			continue
+		}
+		issued = append(issued, crl)
+	}
+	return issued
+}
+
 func isSelfSignedCert(cert *x509.Certificate) bool {
 	return bytes.Equal(cert.RawSubject, cert.RawIssuer)
 }
@@ -307,9 +670,15 @@ func isSelfSignedCert(cert *x509.Certificate) bool {
 func NewSignerInfoFromCert(cert *x509.Certificate) *common.SignerInfo {
 	si := NewSignerInfoFromPKIXName(cert.Subject)
 	si.SerialNumber = cert.SerialNumber
+	si.Fingerprint = []byte(GetFingerprint(cert))
 	return si
 }
 
+// GetFingerprint returns SHA-256 fingerprint of the certificate in upper case hex like PGP key fingerprint
+func GetFingerprint(cert *x509.Certificate) string {
+	return fmt.Sprintf("%X", sha256.Sum256(cert.Raw))
+}
+
 func NewSignerInfoFromPKIXName(dn pkix.Name) *common.SignerInfo {
 	si := &common.SignerInfo{}
 
@@ -17,9 +17,16 @@
 package x509
 
 import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEndToEndCAVerification(t *testing.T) {
@@ -77,3 +84,164 @@ func TestEndToEndCAVerification(t *testing.T) {
 	os.Remove(testInterCert)
 	os.Remove(testServiceCert)
 }
+
+func TestSignatureAlgorithms(t *testing.T) {
+	msg := []byte("abc")
+	for _, keyAlg := range []KeyAlgorithm{KeyAlgorithmRSA, KeyAlgorithmRSAPSS, KeyAlgorithmECDSAP256, KeyAlgorithmECDSAP384, KeyAlgorithmEd25519} {
+		rootCert, rootPrvKeyBytes, _, err := CreateCertificateWithAlgorithm("RootCA", keyAlg, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", keyAlg, err.Error())
+		}
+		cert, prvKeyBytes, _, err := CreateCertificateWithAlgorithm("ServiceTeamAdminA", keyAlg, rootCert, rootPrvKeyBytes)
+		if err != nil {
+			t.Fatalf("%s: %s", keyAlg, err.Error())
+		}
+		pubKeyBytes, err := GetPublicKeyFromCertificate(cert)
+		if err != nil {
+			t.Fatalf("%s: %s", keyAlg, err.Error())
+		}
+
+		sig, err := GenerateSignatureWithAlgorithm(msg, prvKeyBytes, keyAlg)
+		if err != nil {
+			t.Fatalf("%s: %s", keyAlg, err.Error())
+		}
+		sigOk, reasonFail, err := VerifySignature(msg, sig, pubKeyBytes)
+		if err != nil || !sigOk {
+			t.Errorf("%s: \nexpected: true\nactual: %t, %s", keyAlg, sigOk, reasonFail)
+		}
+		sigOk, _, err = VerifySignature([]byte("abd"), sig, pubKeyBytes)
+		if err != nil || sigOk {
+			t.Errorf("%s: signature for a different message should be invalid; %t, %v", keyAlg, sigOk, err)
+		}
+	}
+}
+
+func TestSignatureAlgorithmMismatch(t *testing.T) {
+	_, prvKeyBytes, _, err := CreateCertificateWithAlgorithm("ServiceTeamAdminA", KeyAlgorithmECDSAP256, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = GenerateSignatureWithAlgorithm([]byte("abc"), prvKeyBytes, KeyAlgorithmRSAPSS)
+	if err == nil {
+		t.Errorf("RSA-PSS signature should not be generated with ECDSA key")
+	}
+
+	_, rsaPrvKeyBytes, _, err := CreateCertificate("ServiceTeamAdminB", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := GenerateSignature([]byte("abc"), rsaPrvKeyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecCert, _, _, err := CreateCertificateWithAlgorithm("ServiceTeamAdminC", KeyAlgorithmECDSAP256, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPubKeyBytes, err := GetPublicKeyFromCertificate(ecCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RSA signature must fail cleanly with ECDSA public key
+	sigOk, _, err := VerifySignature([]byte("abc"), sig, ecPubKeyBytes)
+	if err != nil || sigOk {
+		t.Errorf("\nexpected: false, nil\nactual: %t, %v", sigOk, err)
+	}
+}
+
+func TestCertificateChainAndRevocation(t *testing.T) {
+	rootCert, rootPrvKeyBytes, _, err := CreateCertificate("RootCA", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interCert, interPrvKeyBytes, _, err := CreateCertificateWithAlgorithm("IntermediateCA", KeyAlgorithmECDSAP256, rootCert, rootPrvKeyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceCert, _, _, err := CreateCertificateWithAlgorithm("ServiceTeamAdminA", KeyAlgorithmECDSAP256, interCert, interPrvKeyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only root CA is in the cert dir, so intermediate CA must be given in the chain
+	certDir, err := ioutil.TempDir("", "ishield-x509-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(certDir)
+	_ = ioutil.WriteFile(filepath.Join(certDir, "root.crt"), rootCert, 0644)
+
+	certOk, reasonFail, err := VerifyCertificate(serviceCert, certDir)
+	if err != nil || certOk {
+		t.Errorf("leaf certificate without intermediate should not be verified; %t, %s, %v", certOk, reasonFail, err)
+	}
+	chain := append(append([]byte{}, serviceCert...), interCert...)
+	certOk, reasonFail, err = VerifyCertificate(chain, certDir)
+	if err != nil || !certOk {
+		t.Errorf("\nexpected: true\nactual: %t, %s, %v", certOk, reasonFail, err)
+	}
+
+	// revoke the leaf certificate by CRL issued by intermediate CA
+	inter, _ := ParseCertificate(interCert)
+	interPrvKey, _ := ParsePrivateKey(interPrvKeyBytes)
+	service, _ := ParseCertificate(serviceCert)
+	now := time.Now()
+	crlBytes, err := inter.CreateCRL(rand.Reader, interPrvKey, []pkix.RevokedCertificate{{SerialNumber: service.SerialNumber, RevocationTime: now}}, now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(certDir, "inter.crl"), crlBytes, 0644)
+
+	certOk, reasonFail, err = VerifyCertificate(chain, certDir)
+	if err != nil || certOk || !strings.HasPrefix(reasonFail, ReasonCertificateRevoked) {
+		t.Errorf("\nexpected: false, %s\nactual: %t, %s, %v", ReasonCertificateRevoked, certOk, reasonFail, err)
+	}
+
+	// a stale CRL of intermediate CA may not list certificates revoked after it was issued
+	staleCrlBytes, err := inter.CreateCRL(rand.Reader, interPrvKey, []pkix.RevokedCertificate{}, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ioutil.WriteFile(filepath.Join(certDir, "inter.crl"), staleCrlBytes, 0644)
+
+	certOk, reasonFail, err = VerifyCertificate(chain, certDir)
+	if err != nil || certOk || !strings.HasPrefix(reasonFail, ReasonCRLExpired) {
+		t.Errorf("\nexpected: false, %s\nactual: %t, %s, %v", ReasonCRLExpired, certOk, reasonFail, err)
+	}
+}
+
+func TestExpiredCertificate(t *testing.T) {
+	rootCert, rootPrvKeyBytes, _, err := CreateCertificate("RootCA", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := ParseCertificate(rootCert)
+	rootPrvKey, _ := ParsePrivateKey(rootPrvKeyBytes)
+	_, pubKey, err := GenerateKeyPairWithAlgorithm(KeyAlgorithmEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(100),
+		Subject:      pkix.Name{CommonName: "ExpiredSigner"},
+		NotBefore:    time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, tmpl, root, pubKey, rootPrvKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certDir, err := ioutil.TempDir("", "ishield-x509-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(certDir)
+	_ = ioutil.WriteFile(filepath.Join(certDir, "root.crt"), rootCert, 0644)
+
+	certOk, reasonFail, err := VerifyCertificate(PEMEncode(certBytes, PEMTypeCertificate), certDir)
+	if err != nil || certOk || !strings.HasPrefix(reasonFail, ReasonCertificateExpired) {
+		t.Errorf("\nexpected: false, %s\nactual: %t, %s, %v", ReasonCertificateExpired, certOk, reasonFail, err)
+	}
+}
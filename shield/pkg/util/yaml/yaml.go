@@ -33,6 +33,11 @@ type ResourceInfo struct {
 	raw                []byte
 }
 
+// Raw returns the YAML of the single resource
+func (self ResourceInfo) Raw() []byte {
+	return self.raw
+}
+
 func FindSingleYaml(message []byte, apiVersion, kind, name, namespace string) (bool, []byte) {
 	for _, ri := range ParseMessage(message) {
 		if common.MatchPattern(apiVersion, ri.ApiVersion) &&
@@ -105,3 +110,16 @@ func Decompress(str string) string {
 	s := string(output.Bytes())
 	return s
 }
+
+// Compress returns the gzip compressed data, which can be read by Decompress
+func Compress(str string) string {
+	output := bytes.Buffer{}
+	writer := gzip.NewWriter(&output)
+	_, _ = writer.Write([]byte(str))
+	_ = writer.Close()
+	return output.String()
+}
+
+func Base64encode(str string) string {
+	return base64.StdEncoding.EncodeToString([]byte(str))
+}